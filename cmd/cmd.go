@@ -4,12 +4,18 @@ import (
 	"flag"
 	"fmt"
 
+	"github.com/theyudiriski/billing-service/cmd/migrate"
 	http "github.com/theyudiriski/billing-service/cmd/server"
+	"github.com/theyudiriski/billing-service/cmd/worker"
 )
 
 func main() {
+	var migrateCommand string
+
 	runnerMap := map[string]func() Runner{
-		"api": func() Runner { return http.NewServer() },
+		"api":      func() Runner { return http.NewServer() },
+		"late-fee": func() Runner { return worker.NewLateFeeWorker() },
+		"migrate":  func() Runner { return migrate.NewMigrator(migrateCommand) },
 	}
 
 	var serverType string
@@ -25,6 +31,12 @@ func main() {
 			return keys
 		}()),
 	)
+	flag.StringVar(
+		&migrateCommand,
+		"migrate",
+		migrate.CommandUp,
+		fmt.Sprintf("migration command to run with -type=migrate. one of %v", migrate.Commands),
+	)
 	flag.Parse()
 
 	getRunner, ok := runnerMap[serverType]
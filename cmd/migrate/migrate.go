@@ -0,0 +1,107 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/theyudiriski/billing-service/config"
+	"github.com/theyudiriski/billing-service/internal/postgres"
+	billing "github.com/theyudiriski/billing-service/internal/service"
+)
+
+const (
+	CommandUp     = "up"
+	CommandDown   = "down"
+	CommandStatus = "status"
+)
+
+var Commands = []string{CommandUp, CommandDown, CommandStatus}
+
+// Migrator runs a single migration command against the leader and exits.
+type Migrator struct {
+	logger   billing.Logger
+	command  string
+	migrator *postgres.Migrator
+	db       *postgres.Client
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	err    error
+}
+
+func NewMigrator(command string) *Migrator {
+	conf := config.LoadMigrate()
+	logger := billing.NewLogger()
+
+	db, err := postgres.NewClient(conf.Database)
+	if err != nil {
+		panic(err)
+	}
+
+	migrator, err := postgres.NewMigrator(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Migrator{
+		logger:   logger,
+		command:  command,
+		migrator: migrator,
+		db:       db,
+
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+func (m *Migrator) Run() error {
+	m.err = m.run()
+	return m.err
+}
+
+func (m *Migrator) run() error {
+	switch m.command {
+	case CommandUp:
+		applied, err := m.migrator.Up(m.ctx)
+		for _, migration := range applied {
+			m.logger.Info(fmt.Sprintf("applied migration %d_%s", migration.Version, migration.Name))
+		}
+		if err == nil && len(applied) == 0 {
+			m.logger.Info("no pending migration")
+		}
+		return err
+
+	case CommandDown:
+		reverted, err := m.migrator.Down(m.ctx, 1)
+		for _, migration := range reverted {
+			m.logger.Info(fmt.Sprintf("reverted migration %d_%s", migration.Version, migration.Name))
+		}
+		if err == nil && len(reverted) == 0 {
+			m.logger.Info("no applied migration")
+		}
+		return err
+
+	case CommandStatus:
+		statuses, err := m.migrator.Status(m.ctx)
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = "applied at " + billing.LocalTime(*status.AppliedAt).Format("2006-01-02 15:04:05")
+			}
+			m.logger.Info(fmt.Sprintf("%d_%s: %s", status.Version, status.Name, appliedAt))
+		}
+		return err
+
+	default:
+		return fmt.Errorf("migrate command should be one of %v", Commands)
+	}
+}
+
+// Stop closes the database and reports the outcome of Run, the runner has
+// nothing else to stop once Run returns.
+func (m *Migrator) Stop() error {
+	m.cancel()
+	return errors.Join(m.err, m.db.Close())
+}
@@ -0,0 +1,416 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/theyudiriski/billing-service/cmd/server/util"
+	billing "github.com/theyudiriski/billing-service/internal/service"
+)
+
+// CreateAdjustment
+type CreateAdjustmentRequest struct {
+	ScheduleID string
+	Type       billing.AdjustmentType
+	Amount     billing.Amount
+	ReasonCode billing.AdjustmentReasonCode
+	OperatorID string
+}
+
+func (r *CreateAdjustmentRequest) UnmarshalJSON(b []byte) error {
+	temp := struct {
+		ScheduleID *string                       `json:"schedule_id"`
+		Type       *billing.AdjustmentType       `json:"type"`
+		Amount     *json.Number                  `json:"amount"`
+		Currency   *string                       `json:"currency"`
+		ReasonCode *billing.AdjustmentReasonCode `json:"reason_code"`
+		OperatorID *string                       `json:"operator_id"`
+	}{}
+
+	if err := json.Unmarshal(b, &temp); err != nil {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			err.Error(),
+			http.StatusBadRequest,
+		)
+	}
+
+	if temp.ScheduleID == nil || *temp.ScheduleID == "" {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			"schedule_id is required",
+			http.StatusBadRequest,
+		)
+	}
+
+	if _, err := uuid.Parse(*temp.ScheduleID); err != nil {
+		return billing.ErrInvalidUUID
+	}
+
+	if temp.Type == nil {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			"type is required",
+			http.StatusBadRequest,
+		)
+	}
+
+	amount, err := parsePositiveAmount(temp.Amount, temp.Currency, "amount")
+	if err != nil {
+		return err
+	}
+
+	if temp.ReasonCode == nil {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			"reason_code is required",
+			http.StatusBadRequest,
+		)
+	}
+
+	if temp.OperatorID == nil || *temp.OperatorID == "" {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			"operator_id is required",
+			http.StatusBadRequest,
+		)
+	}
+
+	*r = CreateAdjustmentRequest{
+		ScheduleID: *temp.ScheduleID,
+		Type:       *temp.Type,
+		Amount:     amount,
+		ReasonCode: *temp.ReasonCode,
+		OperatorID: *temp.OperatorID,
+	}
+
+	return nil
+}
+
+// DecideAdjustmentRequest is the body to approve or reject an adjustment.
+type DecideAdjustmentRequest struct {
+	OperatorID string
+}
+
+func (r *DecideAdjustmentRequest) UnmarshalJSON(b []byte) error {
+	temp := struct {
+		OperatorID *string `json:"operator_id"`
+	}{}
+
+	if err := json.Unmarshal(b, &temp); err != nil {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			err.Error(),
+			http.StatusBadRequest,
+		)
+	}
+
+	if temp.OperatorID == nil || *temp.OperatorID == "" {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			"operator_id is required",
+			http.StatusBadRequest,
+		)
+	}
+
+	*r = DecideAdjustmentRequest{
+		OperatorID: *temp.OperatorID,
+	}
+
+	return nil
+}
+
+// ReverseAdjustment
+type ReverseAdjustmentRequest struct {
+	ReasonCode billing.AdjustmentReasonCode
+	OperatorID string
+}
+
+func (r *ReverseAdjustmentRequest) UnmarshalJSON(b []byte) error {
+	temp := struct {
+		ReasonCode *billing.AdjustmentReasonCode `json:"reason_code"`
+		OperatorID *string                       `json:"operator_id"`
+	}{}
+
+	if err := json.Unmarshal(b, &temp); err != nil {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			err.Error(),
+			http.StatusBadRequest,
+		)
+	}
+
+	if temp.ReasonCode == nil {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			"reason_code is required",
+			http.StatusBadRequest,
+		)
+	}
+
+	if temp.OperatorID == nil || *temp.OperatorID == "" {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			"operator_id is required",
+			http.StatusBadRequest,
+		)
+	}
+
+	*r = ReverseAdjustmentRequest{
+		ReasonCode: *temp.ReasonCode,
+		OperatorID: *temp.OperatorID,
+	}
+
+	return nil
+}
+
+type AdjustmentResponse struct {
+	*billing.Adjustment
+}
+
+func (r AdjustmentResponse) MarshalJSON() ([]byte, error) {
+	var decidedAt *string
+	if r.DecidedAt != nil {
+		formatted := billing.LocalTime(*r.DecidedAt).Format(time.RFC3339)
+		decidedAt = &formatted
+	}
+
+	return json.Marshal(&struct {
+		ID          string      `json:"id"`
+		LoanID      string      `json:"loan_id"`
+		ScheduleID  string      `json:"schedule_id"`
+		Type        string      `json:"type"`
+		Amount      json.Number `json:"amount"`
+		Currency    string      `json:"currency"`
+		ReasonCode  string      `json:"reason_code"`
+		Status      string      `json:"status"`
+		ReversesID  *string     `json:"reverses_id"`
+		RequestedBy string      `json:"requested_by"`
+		RequestedAt string      `json:"requested_at"`
+		DecidedBy   *string     `json:"decided_by"`
+		DecidedAt   *string     `json:"decided_at"`
+	}{
+		ID:          r.ID,
+		LoanID:      r.LoanID,
+		ScheduleID:  r.ScheduleID,
+		Type:        string(r.Type),
+		Amount:      json.Number(r.Amount.String()),
+		Currency:    r.Amount.Currency,
+		ReasonCode:  string(r.ReasonCode),
+		Status:      string(r.Status),
+		ReversesID:  r.ReversesID,
+		RequestedBy: r.RequestedBy,
+		RequestedAt: billing.LocalTime(r.RequestedAt).Format(time.RFC3339),
+		DecidedBy:   r.DecidedBy,
+		DecidedAt:   decidedAt,
+	})
+}
+
+// unmarshalRequestBody reads the request body into v, reporting malformed json
+// as unprocessable content.
+func unmarshalRequestBody(r *http.Request, v any) error {
+	reqBody, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+
+	if err != nil {
+		return err
+	}
+
+	if err = json.Unmarshal(reqBody, v); err != nil {
+		var syntaxError *json.SyntaxError
+		if errors.As(err, &syntaxError) {
+			return billing.NewError(
+				billing.ErrUnprocessableContentError.Error(),
+				"Invalid json.",
+				http.StatusUnprocessableEntity,
+			)
+		}
+		return err
+	}
+
+	return nil
+}
+
+func CreateAdjustment(
+	logger billing.Logger,
+	adjustmentService billing.AdjustmentService,
+	id string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		if _, errParse := uuid.Parse(id); errParse != nil {
+			util.MarshalJSONError(w, billing.ErrInvalidUUID)
+			return
+		}
+
+		var in CreateAdjustmentRequest
+		if err := unmarshalRequestBody(r, &in); err != nil {
+			logger.WarnContext(ctx, "failed to unmarshal request body", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		adjustment, err := adjustmentService.CreateAdjustment(
+			ctx,
+			id,
+			in.ScheduleID,
+			in.Type,
+			in.Amount,
+			in.ReasonCode,
+			in.OperatorID,
+		)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to create adjustment", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		util.MarshalJSONResponse(w, http.StatusCreated, AdjustmentResponse{adjustment})
+	}
+}
+
+func ApproveAdjustment(
+	logger billing.Logger,
+	adjustmentService billing.AdjustmentService,
+	id string,
+	adjustmentID string,
+) http.HandlerFunc {
+	return decideAdjustment(logger, id, adjustmentID, adjustmentService.ApproveAdjustment)
+}
+
+func RejectAdjustment(
+	logger billing.Logger,
+	adjustmentService billing.AdjustmentService,
+	id string,
+	adjustmentID string,
+) http.HandlerFunc {
+	return decideAdjustment(logger, id, adjustmentID, adjustmentService.RejectAdjustment)
+}
+
+func decideAdjustment(
+	logger billing.Logger,
+	id string,
+	adjustmentID string,
+	decide func(ctx context.Context, loanID, adjustmentID, operatorID string) (*billing.Adjustment, error),
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		if _, errParse := uuid.Parse(id); errParse != nil {
+			util.MarshalJSONError(w, billing.ErrInvalidUUID)
+			return
+		}
+
+		if _, errParse := uuid.Parse(adjustmentID); errParse != nil {
+			util.MarshalJSONError(w, billing.ErrInvalidUUID)
+			return
+		}
+
+		var in DecideAdjustmentRequest
+		if err := unmarshalRequestBody(r, &in); err != nil {
+			logger.WarnContext(ctx, "failed to unmarshal request body", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		adjustment, err := decide(ctx, id, adjustmentID, in.OperatorID)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to decide on adjustment", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		util.MarshalJSONResponse(w, http.StatusOK, AdjustmentResponse{adjustment})
+	}
+}
+
+func ReverseAdjustment(
+	logger billing.Logger,
+	adjustmentService billing.AdjustmentService,
+	id string,
+	adjustmentID string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		if _, errParse := uuid.Parse(id); errParse != nil {
+			util.MarshalJSONError(w, billing.ErrInvalidUUID)
+			return
+		}
+
+		if _, errParse := uuid.Parse(adjustmentID); errParse != nil {
+			util.MarshalJSONError(w, billing.ErrInvalidUUID)
+			return
+		}
+
+		var in ReverseAdjustmentRequest
+		if err := unmarshalRequestBody(r, &in); err != nil {
+			logger.WarnContext(ctx, "failed to unmarshal request body", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		adjustment, err := adjustmentService.ReverseAdjustment(
+			ctx,
+			id,
+			adjustmentID,
+			in.ReasonCode,
+			in.OperatorID,
+		)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to reverse adjustment", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		util.MarshalJSONResponse(w, http.StatusCreated, AdjustmentResponse{adjustment})
+	}
+}
+
+// GetAdjustments
+type GetAdjustmentsResponse struct {
+	LoanID      string               `json:"loan_id"`
+	Adjustments []AdjustmentResponse `json:"adjustments"`
+}
+
+func GetAdjustments(
+	logger billing.Logger,
+	adjustmentService billing.AdjustmentService,
+	id string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		if _, errParse := uuid.Parse(id); errParse != nil {
+			util.MarshalJSONError(w, billing.ErrInvalidUUID)
+			return
+		}
+
+		adjustments, err := adjustmentService.GetAdjustments(ctx, id)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to get adjustments", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		response := GetAdjustmentsResponse{
+			LoanID:      id,
+			Adjustments: make([]AdjustmentResponse, 0, len(adjustments)),
+		}
+		for i := range adjustments {
+			response.Adjustments = append(response.Adjustments, AdjustmentResponse{&adjustments[i]})
+		}
+
+		util.MarshalJSONResponse(w, http.StatusOK, response)
+	}
+}
@@ -0,0 +1,441 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/theyudiriski/billing-service/cmd/server/util"
+	billing "github.com/theyudiriski/billing-service/internal/service"
+)
+
+// CreateBorrower
+type CreateBorrowerRequest struct {
+	FullName       string
+	IdentityType   billing.IdentityType
+	IdentityNumber string
+	DateOfBirth    time.Time
+	Email          string
+	PhoneNumber    string
+	Address        string
+}
+
+func (r *CreateBorrowerRequest) UnmarshalJSON(b []byte) error {
+	temp := struct {
+		FullName       *string               `json:"full_name"`
+		IdentityType   *billing.IdentityType `json:"identity_type"`
+		IdentityNumber *string               `json:"identity_number"`
+		DateOfBirth    *string               `json:"date_of_birth"`
+		Email          *string               `json:"email"`
+		PhoneNumber    *string               `json:"phone_number"`
+		Address        *string               `json:"address"`
+	}{}
+
+	if err := json.Unmarshal(b, &temp); err != nil {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			err.Error(),
+			http.StatusBadRequest,
+		)
+	}
+
+	if temp.FullName == nil || strings.TrimSpace(*temp.FullName) == "" {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			"full_name is required",
+			http.StatusBadRequest,
+		)
+	}
+
+	if temp.IdentityType == nil {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			"identity_type is required",
+			http.StatusBadRequest,
+		)
+	}
+
+	if temp.IdentityNumber == nil || strings.TrimSpace(*temp.IdentityNumber) == "" {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			"identity_number is required",
+			http.StatusBadRequest,
+		)
+	}
+
+	if temp.DateOfBirth == nil {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			"date_of_birth is required",
+			http.StatusBadRequest,
+		)
+	}
+
+	dateOfBirth, err := time.ParseInLocation("2006-01-02", *temp.DateOfBirth, billing.CurrentLocalTime().Location())
+	if err != nil {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			"date_of_birth must be a date, ex 1990-01-31",
+			http.StatusBadRequest,
+		)
+	}
+
+	if temp.Email == nil {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			"email is required",
+			http.StatusBadRequest,
+		)
+	}
+
+	// a bare address, no display name
+	email, err := mail.ParseAddress(*temp.Email)
+	if err != nil || email.Address != strings.TrimSpace(*temp.Email) {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			"email must be a valid email address",
+			http.StatusBadRequest,
+		)
+	}
+
+	if temp.PhoneNumber == nil || strings.TrimSpace(*temp.PhoneNumber) == "" {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			"phone_number is required",
+			http.StatusBadRequest,
+		)
+	}
+
+	// the address is optional
+	var address string
+	if temp.Address != nil {
+		address = strings.TrimSpace(*temp.Address)
+	}
+
+	*r = CreateBorrowerRequest{
+		FullName:       strings.TrimSpace(*temp.FullName),
+		IdentityType:   *temp.IdentityType,
+		IdentityNumber: strings.TrimSpace(*temp.IdentityNumber),
+		DateOfBirth:    dateOfBirth,
+		Email:          email.Address,
+		PhoneNumber:    strings.TrimSpace(*temp.PhoneNumber),
+		Address:        address,
+	}
+
+	return nil
+}
+
+// UpdateKYCStatus
+type UpdateKYCStatusRequest struct {
+	Status billing.KYCStatus
+}
+
+func (r *UpdateKYCStatusRequest) UnmarshalJSON(b []byte) error {
+	temp := struct {
+		Status *billing.KYCStatus `json:"status"`
+	}{}
+
+	if err := json.Unmarshal(b, &temp); err != nil {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			err.Error(),
+			http.StatusBadRequest,
+		)
+	}
+
+	if temp.Status == nil {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			"status is required",
+			http.StatusBadRequest,
+		)
+	}
+
+	*r = UpdateKYCStatusRequest{
+		Status: *temp.Status,
+	}
+
+	return nil
+}
+
+type BorrowerResponse struct {
+	*billing.Borrower
+}
+
+func (r BorrowerResponse) MarshalJSON() ([]byte, error) {
+	var kycUpdatedAt *string
+	if r.KYCUpdatedAt != nil {
+		formatted := billing.LocalTime(*r.KYCUpdatedAt).Format(time.RFC3339)
+		kycUpdatedAt = &formatted
+	}
+
+	return json.Marshal(&struct {
+		ID             string  `json:"id"`
+		FullName       string  `json:"full_name"`
+		IdentityType   string  `json:"identity_type"`
+		IdentityNumber string  `json:"identity_number"`
+		DateOfBirth    string  `json:"date_of_birth"`
+		Email          string  `json:"email"`
+		PhoneNumber    string  `json:"phone_number"`
+		Address        string  `json:"address"`
+		KYCStatus      string  `json:"kyc_status"`
+		KYCUpdatedAt   *string `json:"kyc_updated_at"`
+		CreatedAt      string  `json:"created_at"`
+	}{
+		ID:             r.ID,
+		FullName:       r.FullName,
+		IdentityType:   string(r.IdentityType),
+		IdentityNumber: r.IdentityNumber,
+		DateOfBirth:    r.DateOfBirth.Format("2006-01-02"),
+		Email:          r.Email,
+		PhoneNumber:    r.PhoneNumber,
+		Address:        r.Address,
+		KYCStatus:      string(r.KYCStatus),
+		KYCUpdatedAt:   kycUpdatedAt,
+		CreatedAt:      billing.LocalTime(r.CreatedAt).Format(time.RFC3339),
+	})
+}
+
+func CreateBorrower(
+	logger billing.Logger,
+	borrowerService billing.BorrowerService,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		var in CreateBorrowerRequest
+		if err := unmarshalRequestBody(r, &in); err != nil {
+			logger.WarnContext(ctx, "failed to unmarshal request body", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		borrower, err := borrowerService.CreateBorrower(
+			ctx,
+			in.FullName,
+			in.IdentityType,
+			in.IdentityNumber,
+			in.DateOfBirth,
+			in.Email,
+			in.PhoneNumber,
+			in.Address,
+		)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to create borrower", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		util.MarshalJSONResponse(w, http.StatusCreated, BorrowerResponse{borrower})
+	}
+}
+
+func GetBorrower(
+	logger billing.Logger,
+	borrowerService billing.BorrowerService,
+	id string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		if _, errParse := uuid.Parse(id); errParse != nil {
+			util.MarshalJSONError(w, billing.ErrInvalidUUID)
+			return
+		}
+
+		borrower, err := borrowerService.GetBorrower(ctx, id)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to get borrower", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		util.MarshalJSONResponse(w, http.StatusOK, BorrowerResponse{borrower})
+	}
+}
+
+func UpdateKYCStatus(
+	logger billing.Logger,
+	borrowerService billing.BorrowerService,
+	id string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		if _, errParse := uuid.Parse(id); errParse != nil {
+			util.MarshalJSONError(w, billing.ErrInvalidUUID)
+			return
+		}
+
+		var in UpdateKYCStatusRequest
+		if err := unmarshalRequestBody(r, &in); err != nil {
+			logger.WarnContext(ctx, "failed to unmarshal request body", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		borrower, err := borrowerService.UpdateKYCStatus(ctx, id, in.Status)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to update kyc status", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		util.MarshalJSONResponse(w, http.StatusOK, BorrowerResponse{borrower})
+	}
+}
+
+type BorrowerLoansResponse struct {
+	*billing.BorrowerLoans
+}
+
+func (r BorrowerLoansResponse) MarshalJSON() ([]byte, error) {
+	type currencyExposure struct {
+		Currency             string      `json:"currency"`
+		PrincipalOutstanding json.Number `json:"principal_outstanding"`
+		Overdue              json.Number `json:"overdue_amount"`
+	}
+
+	type worstDelinquency struct {
+		LoanID             string `json:"loan_id"`
+		Status             string `json:"status"`
+		OldestDueDate      string `json:"oldest_due_date"`
+		MissedInstallments int    `json:"missed_installments"`
+		DaysPastDue        int    `json:"days_past_due"`
+	}
+
+	type summary struct {
+		ActiveLoans      int                `json:"active_loans"`
+		DelinquentLoans  int                `json:"delinquent_loans"`
+		Currencies       []currencyExposure `json:"currencies"`
+		WorstDelinquency *worstDelinquency  `json:"worst_delinquency"`
+	}
+
+	loans := make([]LoanResponse, 0, len(r.Loans))
+	for i := range r.Loans {
+		loans = append(loans, LoanResponse{&r.Loans[i]})
+	}
+
+	var nextCursor *string
+	if r.NextCursor != nil {
+		cursor := r.NextCursor.String()
+		nextCursor = &cursor
+	}
+
+	currencies := make([]currencyExposure, 0, len(r.Exposure.Currencies))
+	for _, exposure := range r.Exposure.Currencies {
+		currencies = append(currencies, currencyExposure{
+			Currency:             exposure.PrincipalOutstanding.Currency,
+			PrincipalOutstanding: json.Number(exposure.PrincipalOutstanding.String()),
+			Overdue:              json.Number(exposure.Overdue.String()),
+		})
+	}
+
+	var worst *worstDelinquency
+	if d := r.Exposure.WorstDelinquency; d != nil {
+		worst = &worstDelinquency{
+			LoanID:             d.LoanID,
+			Status:             string(d.Status),
+			OldestDueDate:      billing.LocalTime(d.OldestDueDate).Format("2006-01-02"),
+			MissedInstallments: d.MissedInstallments,
+			DaysPastDue:        d.DaysPastDue,
+		}
+	}
+
+	return json.Marshal(&struct {
+		BorrowerID string         `json:"borrower_id"`
+		Loans      []LoanResponse `json:"loans"`
+		NextCursor *string        `json:"next_cursor"`
+		Summary    summary        `json:"summary"`
+	}{
+		BorrowerID: r.BorrowerID,
+		Loans:      loans,
+		NextCursor: nextCursor,
+		Summary: summary{
+			ActiveLoans:      r.Exposure.ActiveLoans,
+			DelinquentLoans:  r.Exposure.DelinquentLoans,
+			Currencies:       currencies,
+			WorstDelinquency: worst,
+		},
+	})
+}
+
+// parseBorrowerLoanFilter reads the loan filter from the query string: status
+// takes a comma separated list, cursor is the next_cursor of a previous page and
+// limit the page size.
+func parseBorrowerLoanFilter(r *http.Request) (billing.BorrowerLoanFilter, error) {
+	var filter billing.BorrowerLoanFilter
+	query := r.URL.Query()
+
+	if statuses := query.Get("status"); statuses != "" {
+		for _, s := range strings.Split(statuses, ",") {
+			var status billing.LoanStatus
+			if err := status.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+				return filter, err
+			}
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+
+	if cursor := query.Get("cursor"); cursor != "" {
+		var after billing.LoanCursor
+		if err := after.UnmarshalText([]byte(cursor)); err != nil {
+			return filter, err
+		}
+		filter.After = &after
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return filter, billing.NewError(
+				billing.ErrValidationError.Error(),
+				"limit should be a number",
+				http.StatusBadRequest,
+			)
+		}
+		filter.Limit = n
+	}
+
+	return filter, nil
+}
+
+func GetBorrowerLoans(
+	logger billing.Logger,
+	loanService billing.LoanService,
+	id string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		if _, errParse := uuid.Parse(id); errParse != nil {
+			util.MarshalJSONError(w, billing.ErrInvalidUUID)
+			return
+		}
+
+		filter, err := parseBorrowerLoanFilter(r)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to parse loan filter", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		loans, err := loanService.ListBorrowerLoans(ctx, id, filter)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to list borrower loans", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		util.MarshalJSONResponse(w, http.StatusOK, BorrowerLoansResponse{loans})
+	}
+}
@@ -0,0 +1,109 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/theyudiriski/billing-service/cmd/server/util"
+	billing "github.com/theyudiriski/billing-service/internal/service"
+)
+
+const maxCancellationReasonLength = 500
+
+// CancelLoan
+type CancelLoanRequest struct {
+	Reason string
+}
+
+func (r *CancelLoanRequest) UnmarshalJSON(b []byte) error {
+	temp := struct {
+		Reason *string `json:"reason"`
+	}{}
+
+	if err := json.Unmarshal(b, &temp); err != nil {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			err.Error(),
+			http.StatusBadRequest,
+		)
+	}
+
+	if temp.Reason == nil || strings.TrimSpace(*temp.Reason) == "" {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			"reason is required",
+			http.StatusBadRequest,
+		)
+	}
+
+	if len(*temp.Reason) > maxCancellationReasonLength {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			fmt.Sprintf("reason must be at most %d characters", maxCancellationReasonLength),
+			http.StatusBadRequest,
+		)
+	}
+
+	*r = CancelLoanRequest{
+		Reason: strings.TrimSpace(*temp.Reason),
+	}
+
+	return nil
+}
+
+type CancellationResponse struct {
+	*billing.LoanCancellation
+}
+
+func (r CancellationResponse) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		Reason            string      `json:"reason"`
+		CancelledAt       string      `json:"cancelled_at"`
+		PrincipalToReturn json.Number `json:"principal_to_return"`
+		RefundAmount      json.Number `json:"refund_amount"`
+		Currency          string      `json:"currency"`
+	}{
+		Reason:            r.Reason,
+		CancelledAt:       billing.LocalTime(r.CancelledAt).Format(time.RFC3339),
+		PrincipalToReturn: json.Number(r.PrincipalToReturn.String()),
+		RefundAmount:      json.Number(r.RefundAmount.String()),
+		Currency:          r.PrincipalToReturn.Currency,
+	})
+}
+
+func CancelLoan(
+	logger billing.Logger,
+	loanService billing.LoanService,
+	id string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		if _, errParse := uuid.Parse(id); errParse != nil {
+			util.MarshalJSONError(w, billing.ErrInvalidUUID)
+			return
+		}
+
+		var in CancelLoanRequest
+		if err := unmarshalRequestBody(r, &in); err != nil {
+			logger.WarnContext(ctx, "failed to unmarshal request body", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		loan, err := loanService.CancelLoan(ctx, id, in.Reason)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to cancel loan", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		util.MarshalJSONResponse(w, http.StatusOK, LoanResponse{loan})
+	}
+}
@@ -0,0 +1,173 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/theyudiriski/billing-service/cmd/server/util"
+	billing "github.com/theyudiriski/billing-service/internal/service"
+)
+
+const maxDeferralReasonLength = 500
+
+// DeferInstallments
+type DeferInstallmentsRequest struct {
+	Terms billing.LoanDeferralTerms
+}
+
+func (r *DeferInstallmentsRequest) UnmarshalJSON(b []byte) error {
+	temp := struct {
+		Installments *int     `json:"installments"`
+		InterestRate *float64 `json:"interest_rate"`
+		Reason       *string  `json:"reason"`
+		RequestedBy  *string  `json:"requested_by"`
+	}{}
+
+	if err := json.Unmarshal(b, &temp); err != nil {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			err.Error(),
+			http.StatusBadRequest,
+		)
+	}
+
+	if temp.Installments == nil || *temp.Installments <= 0 {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			"installments must be a positive number",
+			http.StatusBadRequest,
+		)
+	}
+
+	// optional, deferring is free by default
+	var interestRate float64
+	if temp.InterestRate != nil {
+		if *temp.InterestRate < 0 {
+			return billing.NewError(
+				billing.ErrValidationError.Error(),
+				"interest_rate must not be negative",
+				http.StatusBadRequest,
+			)
+		}
+		interestRate = *temp.InterestRate
+	}
+
+	if temp.Reason == nil || strings.TrimSpace(*temp.Reason) == "" {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			"reason is required",
+			http.StatusBadRequest,
+		)
+	}
+
+	if len(*temp.Reason) > maxDeferralReasonLength {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			fmt.Sprintf("reason must be at most %d characters", maxDeferralReasonLength),
+			http.StatusBadRequest,
+		)
+	}
+
+	if temp.RequestedBy == nil || *temp.RequestedBy == "" {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			"requested_by is required",
+			http.StatusBadRequest,
+		)
+	}
+
+	if len(*temp.RequestedBy) > maxOperatorIDLength {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			fmt.Sprintf("requested_by must be at most %d characters", maxOperatorIDLength),
+			http.StatusBadRequest,
+		)
+	}
+
+	*r = DeferInstallmentsRequest{
+		Terms: billing.LoanDeferralTerms{
+			Installments: *temp.Installments,
+			InterestRate: interestRate,
+			Reason:       strings.TrimSpace(*temp.Reason),
+			RequestedBy:  *temp.RequestedBy,
+		},
+	}
+
+	return nil
+}
+
+type DeferralResponse struct {
+	*billing.LoanDeferral
+}
+
+func (r DeferralResponse) MarshalJSON() ([]byte, error) {
+	schedules := make([]ScheduleResponse, 0, len(r.Schedules))
+	for _, schedule := range r.Schedules {
+		schedules = append(schedules, ScheduleResponse{schedule})
+	}
+
+	return json.Marshal(&struct {
+		ID            string             `json:"id"`
+		LoanID        string             `json:"loan_id"`
+		Installments  int                `json:"installments"`
+		InterestRate  float64            `json:"interest_rate"`
+		Interest      json.Number        `json:"interest"`
+		Currency      string             `json:"currency"`
+		Reason        string             `json:"reason"`
+		RequestedBy   string             `json:"requested_by"`
+		DeferredFrom  string             `json:"deferred_from"`
+		DeferredUntil string             `json:"deferred_until"`
+		CreatedAt     string             `json:"created_at"`
+		Schedules     []ScheduleResponse `json:"schedules"`
+	}{
+		ID:            r.ID,
+		LoanID:        r.LoanID,
+		Installments:  r.Installments,
+		InterestRate:  r.InterestRate,
+		Interest:      json.Number(r.Interest.String()),
+		Currency:      r.Interest.Currency,
+		Reason:        r.Reason,
+		RequestedBy:   r.RequestedBy,
+		DeferredFrom:  billing.LocalTime(r.DeferredFrom).Format(time.RFC3339),
+		DeferredUntil: billing.LocalTime(r.DeferredUntil).Format(time.RFC3339),
+		CreatedAt:     billing.LocalTime(r.CreatedAt).Format(time.RFC3339),
+		Schedules:     schedules,
+	})
+}
+
+func DeferInstallments(
+	logger billing.Logger,
+	loanService billing.LoanService,
+	id string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		if _, errParse := uuid.Parse(id); errParse != nil {
+			util.MarshalJSONError(w, billing.ErrInvalidUUID)
+			return
+		}
+
+		var in DeferInstallmentsRequest
+		if err := unmarshalRequestBody(r, &in); err != nil {
+			logger.WarnContext(ctx, "failed to unmarshal request body", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		deferral, err := loanService.DeferInstallments(ctx, id, in.Terms)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to defer installments", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		util.MarshalJSONResponse(w, http.StatusOK, DeferralResponse{deferral})
+	}
+}
@@ -0,0 +1,251 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/theyudiriski/billing-service/cmd/server/util"
+	billing "github.com/theyudiriski/billing-service/internal/service"
+)
+
+const maxDisbursementReferenceLength = 100
+
+// DisburseLoan
+type DisburseLoanRequest struct {
+	Disbursement billing.LoanDisbursement
+}
+
+func (r *DisburseLoanRequest) UnmarshalJSON(b []byte) error {
+	temp := struct {
+		Amount      *json.Number `json:"amount"`
+		Currency    *string      `json:"currency"`
+		Reference   *string      `json:"reference"`
+		DisbursedAt *string      `json:"disbursed_at"`
+	}{}
+
+	if err := json.Unmarshal(b, &temp); err != nil {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			err.Error(),
+			http.StatusBadRequest,
+		)
+	}
+
+	disbursement, err := parseDisbursement(temp.Amount, temp.Currency, temp.Reference, temp.DisbursedAt, "disbursed_at")
+	if err != nil {
+		return err
+	}
+
+	*r = DisburseLoanRequest{
+		Disbursement: disbursement,
+	}
+
+	return nil
+}
+
+// parseDisbursement builds a disbursement from request fields, the date is
+// optional and defaults to now.
+func parseDisbursement(
+	amount *json.Number,
+	currency *string,
+	reference *string,
+	disbursedAt *string,
+	disbursedAtField string,
+) (billing.LoanDisbursement, error) {
+	disbursedAmount, err := parsePositiveAmount(amount, currency, "amount")
+	if err != nil {
+		return billing.LoanDisbursement{}, err
+	}
+
+	if reference == nil || strings.TrimSpace(*reference) == "" {
+		return billing.LoanDisbursement{}, billing.NewError(
+			billing.ErrValidationError.Error(),
+			"reference is required",
+			http.StatusBadRequest,
+		)
+	}
+
+	if len(*reference) > maxDisbursementReferenceLength {
+		return billing.LoanDisbursement{}, billing.NewError(
+			billing.ErrValidationError.Error(),
+			fmt.Sprintf("reference must be at most %d characters", maxDisbursementReferenceLength),
+			http.StatusBadRequest,
+		)
+	}
+
+	disbursement := billing.LoanDisbursement{
+		Amount:    disbursedAmount,
+		Reference: strings.TrimSpace(*reference),
+	}
+
+	if disbursedAt != nil {
+		disbursement.DisbursedAt, err = time.Parse(time.RFC3339, *disbursedAt)
+		if err != nil {
+			return billing.LoanDisbursement{}, billing.NewError(
+				billing.ErrValidationError.Error(),
+				fmt.Sprintf("%s must be an RFC 3339 timestamp", disbursedAtField),
+				http.StatusBadRequest,
+			)
+		}
+	}
+
+	return disbursement, nil
+}
+
+type DisbursementResponse struct {
+	*billing.LoanDisbursement
+}
+
+func (r DisbursementResponse) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		Amount      json.Number `json:"amount"`
+		Currency    string      `json:"currency"`
+		Reference   string      `json:"reference"`
+		DisbursedAt string      `json:"disbursed_at"`
+	}{
+		Amount:      json.Number(r.Amount.String()),
+		Currency:    r.Amount.Currency,
+		Reference:   r.Reference,
+		DisbursedAt: billing.LocalTime(r.DisbursedAt).Format(time.RFC3339),
+	})
+}
+
+func DisburseLoan(
+	logger billing.Logger,
+	loanService billing.LoanService,
+	id string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		if _, errParse := uuid.Parse(id); errParse != nil {
+			util.MarshalJSONError(w, billing.ErrInvalidUUID)
+			return
+		}
+
+		var in DisburseLoanRequest
+		if err := unmarshalRequestBody(r, &in); err != nil {
+			logger.WarnContext(ctx, "failed to unmarshal request body", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		loan, err := loanService.DisburseLoan(ctx, id, in.Disbursement)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to disburse loan", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		util.MarshalJSONResponse(w, http.StatusOK, LoanResponse{loan})
+	}
+}
+
+// PayoutWebhook stands in for the notification a payout provider sends once a
+// transfer to the borrower settles.
+type PayoutWebhookRequest struct {
+	LoanID       string
+	Status       string
+	Disbursement billing.LoanDisbursement
+}
+
+const (
+	PayoutStatusCompleted = "completed"
+	PayoutStatusFailed    = "failed"
+)
+
+func (r *PayoutWebhookRequest) UnmarshalJSON(b []byte) error {
+	temp := struct {
+		LoanID      *string      `json:"loan_id"`
+		Status      *string      `json:"status"`
+		Amount      *json.Number `json:"amount"`
+		Currency    *string      `json:"currency"`
+		Reference   *string      `json:"reference"`
+		CompletedAt *string      `json:"completed_at"`
+	}{}
+
+	if err := json.Unmarshal(b, &temp); err != nil {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			err.Error(),
+			http.StatusBadRequest,
+		)
+	}
+
+	if temp.LoanID == nil || *temp.LoanID == "" {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			"loan_id is required",
+			http.StatusBadRequest,
+		)
+	}
+
+	if _, err := uuid.Parse(*temp.LoanID); err != nil {
+		return billing.ErrInvalidUUID
+	}
+
+	if temp.Status == nil || (*temp.Status != PayoutStatusCompleted && *temp.Status != PayoutStatusFailed) {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			fmt.Sprintf("status should be one of %v", []string{PayoutStatusCompleted, PayoutStatusFailed}),
+			http.StatusBadRequest,
+		)
+	}
+
+	*r = PayoutWebhookRequest{
+		LoanID: *temp.LoanID,
+		Status: *temp.Status,
+	}
+
+	// a failed payout carries nothing to record
+	if r.Status == PayoutStatusFailed {
+		return nil
+	}
+
+	disbursement, err := parseDisbursement(temp.Amount, temp.Currency, temp.Reference, temp.CompletedAt, "completed_at")
+	if err != nil {
+		return err
+	}
+	r.Disbursement = disbursement
+
+	return nil
+}
+
+// PayoutWebhook disburses the loan of a completed payout. A failed payout
+// leaves the loan pending disbursement, to be paid out again or cancelled.
+func PayoutWebhook(
+	logger billing.Logger,
+	loanService billing.LoanService,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		var in PayoutWebhookRequest
+		if err := unmarshalRequestBody(r, &in); err != nil {
+			logger.WarnContext(ctx, "failed to unmarshal request body", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		if in.Status == PayoutStatusFailed {
+			logger.WarnContext(ctx, "payout failed", "loanID", in.LoanID)
+			util.MarshalJSONSuccess(w, http.StatusOK)
+			return
+		}
+
+		if _, err := loanService.DisburseLoan(ctx, in.LoanID, in.Disbursement); err != nil {
+			logger.WarnContext(ctx, "failed to disburse loan", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		util.MarshalJSONSuccess(w, http.StatusOK)
+	}
+}
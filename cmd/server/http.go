@@ -22,6 +22,7 @@ const (
 type Server struct {
 	logger billing.Logger
 	server *http.Server
+	db     *postgres.Client
 }
 
 func NewServer() *Server {
@@ -33,15 +34,60 @@ func NewServer() *Server {
 		panic(err)
 	}
 
+	if conf.MigrateOnStartup {
+		migrate(logger, db)
+	}
+
+	lateFeeRules, err := billing.NewLateFeeRules(
+		conf.LateFee.GraceDays,
+		conf.LateFee.FlatAmounts,
+		conf.LateFee.DailyRate,
+		conf.LateFee.CapRate,
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	creditPolicy, err := billing.NewCreditPolicy(
+		conf.CreditPolicy.MaxActiveLoans,
+		conf.CreditPolicy.MaxOutstandingPrincipal,
+		conf.CreditPolicy.BlockDelinquent,
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	payoffRules, err := billing.NewPayoffRules(conf.Payoff.PrepaymentFeeRate)
+	if err != nil {
+		panic(err)
+	}
+
+	txManager := postgres.NewTxManager(db)
+	borrowerStore := postgres.NewBorrowerStore(db)
+	productStore := postgres.NewLoanProductStore(db)
 	loanStore := postgres.NewLoanStore(db)
+	paymentStore := postgres.NewPaymentStore(db)
+	lateFeeStore := postgres.NewLateFeeStore(db)
+	adjustmentStore := postgres.NewAdjustmentStore(db)
+	payoffStore := postgres.NewPayoffStore(db)
 
-	loanService := billing.NewLoanService(logger, loanStore)
+	borrowerService := billing.NewBorrowerService(logger, txManager, borrowerStore)
+	productService := billing.NewLoanProductService(logger, txManager, productStore)
+	loanService := billing.NewLoanService(logger, creditPolicy, conf.LoanCoolingOffDays, txManager, borrowerStore, productStore, loanStore, paymentStore)
+	lateFeeService := billing.NewLateFeeService(logger, lateFeeRules, txManager, loanStore, lateFeeStore)
+	adjustmentService := billing.NewAdjustmentService(logger, txManager, loanStore, adjustmentStore)
+	payoffService := billing.NewPayoffService(logger, payoffRules, txManager, loanStore, paymentStore, adjustmentStore, payoffStore)
 
 	router := NewRouter(
 		logger,
 		db,
 
+		borrowerService,
+		productService,
 		loanService,
+		lateFeeService,
+		adjustmentService,
+		payoffService,
 	)
 
 	server := &http.Server{
@@ -53,13 +99,37 @@ func NewServer() *Server {
 	return &Server{
 		logger: logger,
 		server: server,
+		db:     db,
+	}
+}
+
+// migrate applies the pending migrations, servers started together take turns
+// and the later ones find nothing left to apply.
+func migrate(logger billing.Logger, db *postgres.Client) {
+	migrator, err := postgres.NewMigrator(db)
+	if err != nil {
+		panic(err)
+	}
+
+	applied, err := migrator.Up(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	for _, migration := range applied {
+		logger.Info(fmt.Sprintf("applied migration %d_%s", migration.Version, migration.Name))
 	}
 }
 
 func NewRouter(
 	logger billing.Logger,
 	db *postgres.Client,
+	borrowerService billing.BorrowerService,
+	productService billing.LoanProductService,
 	loanService billing.LoanService,
+	lateFeeService billing.LateFeeService,
+	adjustmentService billing.AdjustmentService,
+	payoffService billing.PayoffService,
 ) *chi.Mux {
 	r := chi.NewRouter()
 	h := &routerHandler{
@@ -68,11 +138,17 @@ func NewRouter(
 		logger: logger,
 		db:     db,
 
-		loanService: loanService,
+		borrowerService:   borrowerService,
+		productService:    productService,
+		loanService:       loanService,
+		lateFeeService:    lateFeeService,
+		adjustmentService: adjustmentService,
+		payoffService:     payoffService,
 	}
 
 	h.router.Use(chiMiddleware.Recoverer)
 	h.router.Use(chiMiddleware.Timeout(ServerAPITimeout))
+	h.router.Use(readYourWrites)
 
 	h.router.Mount("/api", h.registerAPIRoutes())
 
@@ -85,7 +161,12 @@ type routerHandler struct {
 	logger billing.Logger
 	db     *postgres.Client
 
-	loanService billing.LoanService
+	borrowerService   billing.BorrowerService
+	productService    billing.LoanProductService
+	loanService       billing.LoanService
+	lateFeeService    billing.LateFeeService
+	adjustmentService billing.AdjustmentService
+	payoffService     billing.PayoffService
 }
 
 func (s *Server) Run() error {
@@ -98,15 +179,87 @@ func (s *Server) Stop() error {
 	defer cancel()
 
 	s.logger.Info("gracefully shutdown HTTP server")
-	return s.server.Shutdown(ctx)
+	if err := s.server.Shutdown(ctx); err != nil {
+		return err
+	}
+	return s.db.Close()
 }
 
 func (h *routerHandler) registerAPIRoutes() http.Handler {
 	r := chi.NewRouter()
 
+	r.Route("/borrowers", func(r chi.Router) {
+		r.Post("/", CreateBorrower(h.logger, h.borrowerService))
+
+		r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+			id := chi.URLParam(r, "id")
+			GetBorrower(h.logger, h.borrowerService, id)(w, r)
+		})
+
+		r.Post("/{id}/kyc-status", func(w http.ResponseWriter, r *http.Request) {
+			id := chi.URLParam(r, "id")
+			UpdateKYCStatus(h.logger, h.borrowerService, id)(w, r)
+		})
+
+		r.Get("/{id}/loans", func(w http.ResponseWriter, r *http.Request) {
+			id := chi.URLParam(r, "id")
+			GetBorrowerLoans(h.logger, h.loanService, id)(w, r)
+		})
+	})
+
+	r.Route("/products", func(r chi.Router) {
+		r.Post("/", CreateLoanProduct(h.logger, h.productService))
+		r.Get("/", ListLoanProducts(h.logger, h.productService))
+
+		r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+			id := chi.URLParam(r, "id")
+			GetLoanProduct(h.logger, h.productService, id)(w, r)
+		})
+
+		r.Post("/{id}/status", func(w http.ResponseWriter, r *http.Request) {
+			id := chi.URLParam(r, "id")
+			UpdateLoanProductStatus(h.logger, h.productService, id)(w, r)
+		})
+	})
+
 	r.Route("/loans", func(r chi.Router) {
 		r.Post("/", CreateLoan(h.logger, h.loanService))
 
+		r.Post("/{id}/disburse", func(w http.ResponseWriter, r *http.Request) {
+			id := chi.URLParam(r, "id")
+			DisburseLoan(h.logger, h.loanService, id)(w, r)
+		})
+
+		r.Post("/{id}/cancel", func(w http.ResponseWriter, r *http.Request) {
+			id := chi.URLParam(r, "id")
+			CancelLoan(h.logger, h.loanService, id)(w, r)
+		})
+
+		r.Post("/{id}/restructure", func(w http.ResponseWriter, r *http.Request) {
+			id := chi.URLParam(r, "id")
+			RestructureLoan(h.logger, h.loanService, id)(w, r)
+		})
+
+		r.Post("/{id}/defer", func(w http.ResponseWriter, r *http.Request) {
+			id := chi.URLParam(r, "id")
+			DeferInstallments(h.logger, h.loanService, id)(w, r)
+		})
+
+		r.Post("/{id}/write-off", func(w http.ResponseWriter, r *http.Request) {
+			id := chi.URLParam(r, "id")
+			WriteOffLoan(h.logger, h.loanService, id)(w, r)
+		})
+
+		r.Get("/{id}/payoff-quote", func(w http.ResponseWriter, r *http.Request) {
+			id := chi.URLParam(r, "id")
+			GetPayoffQuote(h.logger, h.payoffService, id)(w, r)
+		})
+
+		r.Post("/{id}/payoff", func(w http.ResponseWriter, r *http.Request) {
+			id := chi.URLParam(r, "id")
+			PayoffLoan(h.logger, h.payoffService, id)(w, r)
+		})
+
 		r.Get("/{id}/outstanding", func(w http.ResponseWriter, r *http.Request) {
 			id := chi.URLParam(r, "id")
 			GetOutstandingLoan(h.logger, h.loanService, id)(w, r)
@@ -122,8 +275,61 @@ func (h *routerHandler) registerAPIRoutes() http.Handler {
 			IsDelinquent(h.logger, h.loanService, id)(w, r)
 		})
 
+		r.Get("/{id}/payments", func(w http.ResponseWriter, r *http.Request) {
+			id := chi.URLParam(r, "id")
+			GetPayments(h.logger, h.loanService, id)(w, r)
+		})
+
+		r.Get("/{id}/schedules", func(w http.ResponseWriter, r *http.Request) {
+			id := chi.URLParam(r, "id")
+			GetSchedules(h.logger, h.loanService, id)(w, r)
+		})
+
+		r.Get("/{id}/late-fees", func(w http.ResponseWriter, r *http.Request) {
+			id := chi.URLParam(r, "id")
+			GetLateFees(h.logger, h.lateFeeService, id)(w, r)
+		})
+
+		r.Post("/{id}/late-fees/{lateFeeID}/waive", func(w http.ResponseWriter, r *http.Request) {
+			id := chi.URLParam(r, "id")
+			lateFeeID := chi.URLParam(r, "lateFeeID")
+			WaiveLateFee(h.logger, h.lateFeeService, id, lateFeeID)(w, r)
+		})
+
+		r.Get("/{id}/adjustments", func(w http.ResponseWriter, r *http.Request) {
+			id := chi.URLParam(r, "id")
+			GetAdjustments(h.logger, h.adjustmentService, id)(w, r)
+		})
+
+		r.Post("/{id}/adjustments", func(w http.ResponseWriter, r *http.Request) {
+			id := chi.URLParam(r, "id")
+			CreateAdjustment(h.logger, h.adjustmentService, id)(w, r)
+		})
+
+		r.Post("/{id}/adjustments/{adjustmentID}/approve", func(w http.ResponseWriter, r *http.Request) {
+			id := chi.URLParam(r, "id")
+			adjustmentID := chi.URLParam(r, "adjustmentID")
+			ApproveAdjustment(h.logger, h.adjustmentService, id, adjustmentID)(w, r)
+		})
+
+		r.Post("/{id}/adjustments/{adjustmentID}/reject", func(w http.ResponseWriter, r *http.Request) {
+			id := chi.URLParam(r, "id")
+			adjustmentID := chi.URLParam(r, "adjustmentID")
+			RejectAdjustment(h.logger, h.adjustmentService, id, adjustmentID)(w, r)
+		})
+
+		r.Post("/{id}/adjustments/{adjustmentID}/reverse", func(w http.ResponseWriter, r *http.Request) {
+			id := chi.URLParam(r, "id")
+			adjustmentID := chi.URLParam(r, "adjustmentID")
+			ReverseAdjustment(h.logger, h.adjustmentService, id, adjustmentID)(w, r)
+		})
+
 		r.Post("/pay", PayLoan(h.logger, h.loanService))
 	})
 
+	r.Route("/webhooks", func(r chi.Router) {
+		r.Post("/payouts", PayoutWebhook(h.logger, h.loanService))
+	})
+
 	return r
 }
@@ -0,0 +1,117 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/theyudiriski/billing-service/cmd/server/util"
+	billing "github.com/theyudiriski/billing-service/internal/service"
+)
+
+type LateFeeResponse struct {
+	*billing.LateFee
+}
+
+func (r LateFeeResponse) MarshalJSON() ([]byte, error) {
+	var waivedAt *string
+	if r.WaivedAt != nil {
+		formatted := billing.LocalTime(*r.WaivedAt).Format(time.RFC3339)
+		waivedAt = &formatted
+	}
+
+	return json.Marshal(&struct {
+		ID         string      `json:"id"`
+		LoanID     string      `json:"loan_id"`
+		ScheduleID string      `json:"schedule_id"`
+		Kind       string      `json:"kind"`
+		Amount     json.Number `json:"amount"`
+		Currency   string      `json:"currency"`
+		AccruedOn  string      `json:"accrued_on"`
+		Status     string      `json:"status"`
+		WaivedAt   *string     `json:"waived_at"`
+	}{
+		ID:         r.ID,
+		LoanID:     r.LoanID,
+		ScheduleID: r.ScheduleID,
+		Kind:       string(r.Kind),
+		Amount:     json.Number(r.Amount.String()),
+		Currency:   r.Amount.Currency,
+		AccruedOn:  billing.LocalDate(r.AccruedOn).Format("2006-01-02"),
+		Status:     string(r.Status),
+		WaivedAt:   waivedAt,
+	})
+}
+
+// GetLateFees
+type GetLateFeesResponse struct {
+	LoanID   string            `json:"loan_id"`
+	LateFees []LateFeeResponse `json:"late_fees"`
+}
+
+func GetLateFees(
+	logger billing.Logger,
+	lateFeeService billing.LateFeeService,
+	id string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		if _, errParse := uuid.Parse(id); errParse != nil {
+			util.MarshalJSONError(w, billing.ErrInvalidUUID)
+			return
+		}
+
+		fees, err := lateFeeService.GetLateFees(ctx, id)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to get late fees", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		response := GetLateFeesResponse{
+			LoanID:   id,
+			LateFees: make([]LateFeeResponse, 0, len(fees)),
+		}
+		for i := range fees {
+			response.LateFees = append(response.LateFees, LateFeeResponse{&fees[i]})
+		}
+
+		util.MarshalJSONResponse(w, http.StatusOK, response)
+	}
+}
+
+// WaiveLateFee
+func WaiveLateFee(
+	logger billing.Logger,
+	lateFeeService billing.LateFeeService,
+	id string,
+	lateFeeID string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		if _, errParse := uuid.Parse(id); errParse != nil {
+			util.MarshalJSONError(w, billing.ErrInvalidUUID)
+			return
+		}
+
+		if _, errParse := uuid.Parse(lateFeeID); errParse != nil {
+			util.MarshalJSONError(w, billing.ErrInvalidUUID)
+			return
+		}
+
+		fee, err := lateFeeService.WaiveLateFee(ctx, id, lateFeeID)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to waive late fee", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		util.MarshalJSONResponse(w, http.StatusOK, LateFeeResponse{fee})
+	}
+}
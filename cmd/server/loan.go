@@ -4,8 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/theyudiriski/billing-service/cmd/server/util"
@@ -14,20 +17,22 @@ import (
 
 // CreateLoan
 type CreateLoanRequest struct {
-	BorrowerID       string
-	PrincipalAmount  billing.Amount
-	InterestRate     float64
-	PaymentFrequency billing.LoanFrequency
-	TotalPayments    int
+	BorrowerID  string
+	ProductID   string
+	Application billing.LoanApplication
 }
 
 func (r *CreateLoanRequest) UnmarshalJSON(b []byte) error {
 	temp := struct {
 		BorrowerID       *string                `json:"borrower_id"`
-		PrincipalAmount  *float64               `json:"principal_amount"`
+		ProductID        *string                `json:"product_id"`
+		PrincipalAmount  *json.Number           `json:"principal_amount"`
+		Currency         *string                `json:"currency"`
 		InterestRate     *float64               `json:"interest_rate"`
+		InterestModel    *billing.InterestModel `json:"interest_model"`
 		PaymentFrequency *billing.LoanFrequency `json:"payment_frequency"`
 		TotalPayments    *int                   `json:"total_payments"`
+		FeeAmount        *json.Number           `json:"fee_amount"`
 	}{}
 
 	if err := json.Unmarshal(b, &temp); err != nil {
@@ -46,72 +51,162 @@ func (r *CreateLoanRequest) UnmarshalJSON(b []byte) error {
 		)
 	}
 
-	if temp.PrincipalAmount == nil || *temp.PrincipalAmount <= 0 {
-		return billing.NewError(
-			billing.ErrValidationError.Error(),
-			"principal_amount is required and must be greater than 0",
-			http.StatusBadRequest,
-		)
+	if _, err := uuid.Parse(*temp.BorrowerID); err != nil {
+		return billing.ErrInvalidUUID
 	}
 
-	if temp.InterestRate == nil || *temp.InterestRate <= 0 {
+	if temp.ProductID == nil || *temp.ProductID == "" {
 		return billing.NewError(
 			billing.ErrValidationError.Error(),
-			"interest_rate is required and must be greater than 0",
+			"product_id is required",
 			http.StatusBadRequest,
 		)
 	}
 
-	if temp.PaymentFrequency == nil {
-		return billing.NewError(
-			billing.ErrValidationError.Error(),
-			"payment_frequency is required",
-			http.StatusBadRequest,
-		)
+	if _, err := uuid.Parse(*temp.ProductID); err != nil {
+		return billing.ErrInvalidUUID
 	}
 
-	if temp.TotalPayments == nil || *temp.TotalPayments <= 0 {
+	// the interest model and fees are set by the product
+	if temp.InterestModel != nil || temp.FeeAmount != nil {
 		return billing.NewError(
 			billing.ErrValidationError.Error(),
-			"total_payments is required and must be greater than 0",
+			"interest_model and fee_amount are set by the product",
 			http.StatusBadRequest,
 		)
 	}
 
+	principalAmount, err := parsePositiveAmount(temp.PrincipalAmount, temp.Currency, "principal_amount")
+	if err != nil {
+		return err
+	}
+
+	// the rest is optional, the product fills in what it allows a single value for
+	application := billing.LoanApplication{
+		PrincipalAmount: principalAmount,
+	}
+
+	if temp.InterestRate != nil {
+		if *temp.InterestRate <= 0 {
+			return billing.NewError(
+				billing.ErrValidationError.Error(),
+				"interest_rate must be greater than 0",
+				http.StatusBadRequest,
+			)
+		}
+		application.InterestRate = *temp.InterestRate
+	}
+
+	if temp.PaymentFrequency != nil {
+		application.PaymentFrequency = *temp.PaymentFrequency
+	}
+
+	if temp.TotalPayments != nil {
+		if *temp.TotalPayments <= 0 {
+			return billing.NewError(
+				billing.ErrValidationError.Error(),
+				"total_payments must be greater than 0",
+				http.StatusBadRequest,
+			)
+		}
+		application.TotalPayments = *temp.TotalPayments
+	}
+
 	*r = CreateLoanRequest{
-		BorrowerID:       *temp.BorrowerID,
-		PrincipalAmount:  billing.NewAmount(*temp.PrincipalAmount),
-		InterestRate:     *temp.InterestRate,
-		PaymentFrequency: *temp.PaymentFrequency,
-		TotalPayments:    *temp.TotalPayments,
+		BorrowerID:  *temp.BorrowerID,
+		ProductID:   *temp.ProductID,
+		Application: application,
 	}
 
 	return nil
 }
 
+// parsePositiveAmount parses a required JSON number field into an amount greater
+// than 0, in the given currency or the default one when it is not provided.
+func parsePositiveAmount(n *json.Number, currency *string, field string) (billing.Amount, error) {
+	errInvalid := billing.NewError(
+		billing.ErrValidationError.Error(),
+		fmt.Sprintf("%s is required and must be greater than 0", field),
+		http.StatusBadRequest,
+	)
+
+	if n == nil {
+		return billing.Amount{}, errInvalid
+	}
+
+	currencyCode := billing.DefaultCurrency
+	if currency != nil && *currency != "" {
+		currencyCode = *currency
+	}
+
+	if _, err := billing.LookupCurrency(currencyCode); err != nil {
+		return billing.Amount{}, err
+	}
+
+	amount, err := billing.ParseAmount(n.String(), currencyCode)
+	if err != nil || amount.Cmp(amount.ZeroLike()) <= 0 {
+		return billing.Amount{}, errInvalid
+	}
+
+	return amount, nil
+}
+
 type LoanResponse struct {
 	*billing.Loan
 }
 
 func (r LoanResponse) MarshalJSON() ([]byte, error) {
+	var disbursement *DisbursementResponse
+	if r.Disbursement != nil {
+		disbursement = &DisbursementResponse{r.Disbursement}
+	}
+
+	var cancellation *CancellationResponse
+	if r.Cancellation != nil {
+		cancellation = &CancellationResponse{r.Cancellation}
+	}
+
+	var writeOff *WriteOffResponse
+	if r.WriteOff != nil {
+		writeOff = &WriteOffResponse{r.WriteOff}
+	}
+
 	return json.Marshal(&struct {
-		ID               string  `json:"id"`
-		BorrowerID       string  `json:"borrower_id"`
-		PrincipalAmount  float64 `json:"principal_amount"`
-		InterestRate     float64 `json:"interest_rate"`
-		StartedAt        string  `json:"started_at"`
-		EndedAt          string  `json:"ended_at"`
-		PaymentFrequency string  `json:"payment_frequency"`
-		TotalPayments    int     `json:"total_payments"`
+		ID               string                `json:"id"`
+		BorrowerID       string                `json:"borrower_id"`
+		ProductID        *string               `json:"product_id"`
+		PrincipalAmount  json.Number           `json:"principal_amount"`
+		Currency         string                `json:"currency"`
+		InterestRate     float64               `json:"interest_rate"`
+		InterestModel    string                `json:"interest_model"`
+		FeeAmount        json.Number           `json:"fee_amount"`
+		StartedAt        string                `json:"started_at"`
+		EndedAt          string                `json:"ended_at"`
+		PaymentFrequency string                `json:"payment_frequency"`
+		TotalPayments    int                   `json:"total_payments"`
+		Status           string                `json:"status"`
+		GraceDays        *int                  `json:"grace_days"`
+		Disbursement     *DisbursementResponse `json:"disbursement"`
+		Cancellation     *CancellationResponse `json:"cancellation"`
+		WriteOff         *WriteOffResponse     `json:"write_off"`
 	}{
 		ID:               r.ID,
 		BorrowerID:       r.BorrowerID,
-		PrincipalAmount:  r.PrincipalAmount.ToFloat64(),
+		ProductID:        r.ProductID,
+		PrincipalAmount:  json.Number(r.PrincipalAmount.String()),
+		Currency:         r.PrincipalAmount.Currency,
 		InterestRate:     r.InterestRate,
+		InterestModel:    string(r.InterestModel),
+		FeeAmount:        json.Number(r.FeeAmount.String()),
 		StartedAt:        billing.LocalTime(r.StartedAt).Format("2006-01-02"),
 		EndedAt:          billing.LocalTime(r.EndedAt).Format("2006-01-02"),
 		PaymentFrequency: string(r.PaymentFrequency),
 		TotalPayments:    r.TotalPayments,
+		Status:           string(r.Status),
+		GraceDays:        r.GraceDays,
+		Disbursement:     disbursement,
+		Cancellation:     cancellation,
+		WriteOff:         writeOff,
 	})
 }
 
@@ -152,10 +247,8 @@ func CreateLoan(
 		loan, err := loanService.CreateLoan(
 			ctx,
 			in.BorrowerID,
-			in.PrincipalAmount,
-			in.InterestRate,
-			in.PaymentFrequency,
-			in.TotalPayments,
+			in.ProductID,
+			in.Application,
 		)
 		if err != nil {
 			logger.WarnContext(ctx, "failed to create loan", "error", err)
@@ -256,15 +349,25 @@ func GetPendingLoan(
 }
 
 // PayLoan
+const (
+	IdempotencyKeyHeader    = "Idempotency-Key"
+	maxIdempotencyKeyLength = 100
+)
+
 type PayLoanRequest struct {
-	ID     string
-	Amount billing.Amount
+	ID                string
+	Amount            billing.Amount
+	Channel           string
+	ExternalReference string
 }
 
 func (r *PayLoanRequest) UnmarshalJSON(b []byte) error {
 	temp := struct {
-		ID     *string  `json:"id"`
-		Amount *float64 `json:"amount"`
+		ID                *string      `json:"id"`
+		Amount            *json.Number `json:"amount"`
+		Currency          *string      `json:"currency"`
+		Channel           *string      `json:"channel"`
+		ExternalReference *string      `json:"external_reference"`
 	}{}
 
 	if err := json.Unmarshal(b, &temp); err != nil {
@@ -283,17 +386,33 @@ func (r *PayLoanRequest) UnmarshalJSON(b []byte) error {
 		)
 	}
 
-	if temp.Amount == nil || *temp.Amount <= 0 {
+	if _, err := uuid.Parse(*temp.ID); err != nil {
+		return billing.ErrInvalidUUID
+	}
+
+	amount, err := parsePositiveAmount(temp.Amount, temp.Currency, "amount")
+	if err != nil {
+		return err
+	}
+
+	if temp.Channel == nil || *temp.Channel == "" {
 		return billing.NewError(
 			billing.ErrValidationError.Error(),
-			"amount is required and must be greater than 0",
+			"channel is required",
 			http.StatusBadRequest,
 		)
 	}
 
+	var externalReference string
+	if temp.ExternalReference != nil {
+		externalReference = *temp.ExternalReference
+	}
+
 	*r = PayLoanRequest{
-		ID:     *temp.ID,
-		Amount: billing.NewAmount(*temp.Amount),
+		ID:                *temp.ID,
+		Amount:            amount,
+		Channel:           *temp.Channel,
+		ExternalReference: externalReference,
 	}
 
 	return nil
@@ -307,6 +426,17 @@ func PayLoan(
 		ctx, cancel := context.WithCancel(r.Context())
 		defer cancel()
 
+		// optional, retries sharing a key settle the loan only once
+		idempotencyKey := strings.TrimSpace(r.Header.Get(IdempotencyKeyHeader))
+		if len(idempotencyKey) > maxIdempotencyKeyLength {
+			util.MarshalJSONError(w, billing.NewError(
+				billing.ErrValidationError.Error(),
+				fmt.Sprintf("%s must be at most %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength),
+				http.StatusBadRequest,
+			))
+			return
+		}
+
 		var in PayLoanRequest
 		reqBody, err := io.ReadAll(r.Body)
 		defer r.Body.Close()
@@ -333,10 +463,13 @@ func PayLoan(
 			return
 		}
 
-		err = loanService.PayLoan(
+		payment, err := loanService.PayLoan(
 			ctx,
 			in.ID,
 			in.Amount,
+			in.Channel,
+			in.ExternalReference,
+			idempotencyKey,
 		)
 		if err != nil {
 			logger.WarnContext(ctx, "failed to pay loan", "error", err)
@@ -344,6 +477,238 @@ func PayLoan(
 			return
 		}
 
-		util.MarshalJSONSuccess(w, http.StatusOK)
+		util.MarshalJSONResponse(w, http.StatusOK, PaymentResponse{payment})
+	}
+}
+
+type PaymentResponse struct {
+	*billing.Payment
+}
+
+func (r PaymentResponse) MarshalJSON() ([]byte, error) {
+	type allocation struct {
+		ScheduleID string      `json:"schedule_id"`
+		Seq        int         `json:"seq"`
+		DueDate    string      `json:"due_date"`
+		Amount     json.Number `json:"amount"`
+		Status     string      `json:"schedule_status"`
+	}
+
+	allocations := make([]allocation, 0, len(r.Allocations))
+	for _, a := range r.Allocations {
+		allocations = append(allocations, allocation{
+			ScheduleID: a.ScheduleID,
+			Seq:        a.Seq,
+			DueDate:    billing.LocalTime(a.DueDate).Format("2006-01-02"),
+			Amount:     json.Number(a.Amount.String()),
+			Status:     string(a.ScheduleStatus),
+		})
+	}
+
+	return json.Marshal(&struct {
+		ID                string       `json:"id"`
+		LoanID            string       `json:"loan_id"`
+		Amount            json.Number  `json:"amount"`
+		CreditAmount      json.Number  `json:"credit_amount"`
+		Currency          string       `json:"currency"`
+		Channel           string       `json:"channel"`
+		ExternalReference string       `json:"external_reference"`
+		PaidAt            string       `json:"paid_at"`
+		Allocations       []allocation `json:"allocations"`
+	}{
+		ID:                r.ID,
+		LoanID:            r.LoanID,
+		Amount:            json.Number(r.Amount.String()),
+		CreditAmount:      json.Number(r.UnappliedAmount.String()),
+		Currency:          r.Amount.Currency,
+		Channel:           r.Channel,
+		ExternalReference: r.ExternalReference,
+		PaidAt:            billing.LocalTime(r.PaidAt).Format(time.RFC3339),
+		Allocations:       allocations,
+	})
+}
+
+// GetPayments
+type GetPaymentsResponse struct {
+	LoanID   string            `json:"loan_id"`
+	Payments []PaymentResponse `json:"payments"`
+}
+
+func GetPayments(
+	logger billing.Logger,
+	loanService billing.LoanService,
+	id string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		if _, errParse := uuid.Parse(id); errParse != nil {
+			util.MarshalJSONError(w, billing.ErrInvalidUUID)
+			return
+		}
+
+		payments, err := loanService.GetPayments(ctx, id)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to get payments", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		response := GetPaymentsResponse{
+			LoanID:   id,
+			Payments: make([]PaymentResponse, 0, len(payments)),
+		}
+		for i := range payments {
+			response.Payments = append(response.Payments, PaymentResponse{&payments[i]})
+		}
+
+		util.MarshalJSONResponse(w, http.StatusOK, response)
+	}
+}
+
+// GetSchedules
+type ScheduleResponse struct {
+	billing.LoanSchedule
+}
+
+func (r ScheduleResponse) MarshalJSON() ([]byte, error) {
+	var paidAt *string
+	if r.PaidAt != nil {
+		formatted := billing.LocalTime(*r.PaidAt).Format(time.RFC3339)
+		paidAt = &formatted
+	}
+
+	return json.Marshal(&struct {
+		ID           string      `json:"id"`
+		Seq          int         `json:"seq"`
+		Version      int         `json:"version"`
+		DueDate      string      `json:"due_date"`
+		AmountDue    json.Number `json:"amount_due"`
+		PrincipalDue json.Number `json:"principal_due"`
+		InterestDue  json.Number `json:"interest_due"`
+		FeeDue       json.Number `json:"fee_due"`
+		LateFeeDue   json.Number `json:"late_fee_due"`
+		PaidAmount   json.Number `json:"paid_amount"`
+		Currency     string      `json:"currency"`
+		Status       string      `json:"status"`
+		PaidAt       *string     `json:"paid_at"`
+	}{
+		ID:           r.ID,
+		Seq:          r.Seq,
+		Version:      r.Version,
+		DueDate:      billing.LocalTime(r.DueDate).Format("2006-01-02"),
+		AmountDue:    json.Number(r.AmountDue.String()),
+		PrincipalDue: json.Number(r.PrincipalDue.String()),
+		InterestDue:  json.Number(r.InterestDue.String()),
+		FeeDue:       json.Number(r.FeeDue.String()),
+		LateFeeDue:   json.Number(r.LateFeeDue.String()),
+		PaidAmount:   json.Number(r.PaidAmount.String()),
+		Currency:     r.AmountDue.Currency,
+		Status:       string(r.Status),
+		PaidAt:       paidAt,
+	})
+}
+
+type GetSchedulesResponse struct {
+	LoanID    string             `json:"loan_id"`
+	Schedules []ScheduleResponse `json:"schedules"`
+}
+
+// parseScheduleFilter reads the schedule filter from the query string:
+// status takes a comma separated list, from and to are inclusive dates (YYYY-MM-DD).
+func parseScheduleFilter(r *http.Request) (billing.LoanScheduleFilter, error) {
+	var filter billing.LoanScheduleFilter
+	query := r.URL.Query()
+
+	if statuses := query.Get("status"); statuses != "" {
+		for _, s := range strings.Split(statuses, ",") {
+			var status billing.LoanScheduleStatus
+			if err := status.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+				return filter, err
+			}
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+
+	if from := query.Get("from"); from != "" {
+		date, err := parseLocalDate(from, "from")
+		if err != nil {
+			return filter, err
+		}
+		filter.DueFrom = &date
+	}
+
+	if to := query.Get("to"); to != "" {
+		date, err := parseLocalDate(to, "to")
+		if err != nil {
+			return filter, err
+		}
+		// inclusive end date
+		date = date.AddDate(0, 0, 1)
+		filter.DueTo = &date
+	}
+
+	if filter.DueFrom != nil && filter.DueTo != nil && !filter.DueFrom.Before(*filter.DueTo) {
+		return filter, billing.NewError(
+			billing.ErrValidationError.Error(),
+			"from must not be after to",
+			http.StatusBadRequest,
+		)
+	}
+
+	return filter, nil
+}
+
+func parseLocalDate(value string, field string) (time.Time, error) {
+	loc, _ := time.LoadLocation(billing.LocalTimezone)
+	date, err := time.ParseInLocation("2006-01-02", value, loc)
+	if err != nil {
+		return time.Time{}, billing.NewError(
+			billing.ErrValidationError.Error(),
+			fmt.Sprintf("%s should be a date formatted as YYYY-MM-DD", field),
+			http.StatusBadRequest,
+		)
+	}
+	return date, nil
+}
+
+func GetSchedules(
+	logger billing.Logger,
+	loanService billing.LoanService,
+	id string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		if _, errParse := uuid.Parse(id); errParse != nil {
+			util.MarshalJSONError(w, billing.ErrInvalidUUID)
+			return
+		}
+
+		filter, err := parseScheduleFilter(r)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to parse schedule filter", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		schedules, err := loanService.GetSchedules(ctx, id, filter)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to get schedules", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		response := GetSchedulesResponse{
+			LoanID:    id,
+			Schedules: make([]ScheduleResponse, 0, len(schedules)),
+		}
+		for _, schedule := range schedules {
+			response.Schedules = append(response.Schedules, ScheduleResponse{schedule})
+		}
+
+		util.MarshalJSONResponse(w, http.StatusOK, response)
 	}
 }
@@ -0,0 +1,21 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+
+	billing "github.com/theyudiriski/billing-service/internal/service"
+)
+
+// ReadYourWritesHeader set to true has the request read from the leader, for a
+// client that must see a write it just made that a follower may not have yet.
+const ReadYourWritesHeader = "X-Read-Your-Writes"
+
+func readYourWrites(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.EqualFold(strings.TrimSpace(r.Header.Get(ReadYourWritesHeader)), "true") {
+			r = r.WithContext(billing.WithReadYourWrites(r.Context()))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,175 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/theyudiriski/billing-service/cmd/server/util"
+	billing "github.com/theyudiriski/billing-service/internal/service"
+)
+
+// GetPayoffQuote
+type PayoffQuoteResponse struct {
+	*billing.PayoffQuote
+}
+
+func (r PayoffQuoteResponse) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		ID              string      `json:"id"`
+		LoanID          string      `json:"loan_id"`
+		AsOf            string      `json:"as_of"`
+		Principal       json.Number `json:"principal"`
+		AccruedInterest json.Number `json:"accrued_interest"`
+		Fee             json.Number `json:"fee"`
+		LateFee         json.Number `json:"late_fee"`
+		Rebate          json.Number `json:"rebate"`
+		PrepaymentFee   json.Number `json:"prepayment_fee"`
+		Total           json.Number `json:"total"`
+		Currency        string      `json:"currency"`
+		ValidUntil      string      `json:"valid_until"`
+	}{
+		ID:              r.ID,
+		LoanID:          r.LoanID,
+		AsOf:            billing.LocalTime(r.AsOf).Format("2006-01-02"),
+		Principal:       json.Number(r.Principal.String()),
+		AccruedInterest: json.Number(r.AccruedInterest.String()),
+		Fee:             json.Number(r.Fee.String()),
+		LateFee:         json.Number(r.LateFee.String()),
+		Rebate:          json.Number(r.Rebate.String()),
+		PrepaymentFee:   json.Number(r.PrepaymentFee.String()),
+		Total:           json.Number(r.Total.String()),
+		Currency:        r.Total.Currency,
+		ValidUntil:      billing.LocalTime(r.ValidUntil).Format(time.RFC3339),
+	})
+}
+
+func GetPayoffQuote(
+	logger billing.Logger,
+	payoffService billing.PayoffService,
+	id string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		if _, errParse := uuid.Parse(id); errParse != nil {
+			util.MarshalJSONError(w, billing.ErrInvalidUUID)
+			return
+		}
+
+		// optional, today when missing
+		var asOf time.Time
+		if value := r.URL.Query().Get("as_of"); value != "" {
+			date, err := parseLocalDate(value, "as_of")
+			if err != nil {
+				util.MarshalJSONError(w, err)
+				return
+			}
+			asOf = date
+		}
+
+		quote, err := payoffService.QuotePayoff(ctx, id, asOf)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to quote payoff", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		util.MarshalJSONResponse(w, http.StatusOK, PayoffQuoteResponse{quote})
+	}
+}
+
+// PayoffLoan
+type PayoffLoanRequest struct {
+	QuoteID           string
+	Channel           string
+	ExternalReference string
+}
+
+func (r *PayoffLoanRequest) UnmarshalJSON(b []byte) error {
+	temp := struct {
+		QuoteID           *string `json:"quote_id"`
+		Channel           *string `json:"channel"`
+		ExternalReference *string `json:"external_reference"`
+	}{}
+
+	if err := json.Unmarshal(b, &temp); err != nil {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			err.Error(),
+			http.StatusBadRequest,
+		)
+	}
+
+	if temp.QuoteID == nil || *temp.QuoteID == "" {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			"quote_id is required",
+			http.StatusBadRequest,
+		)
+	}
+
+	if _, err := uuid.Parse(*temp.QuoteID); err != nil {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			"quote_id should be a UUID",
+			http.StatusBadRequest,
+		)
+	}
+
+	if temp.Channel == nil || *temp.Channel == "" {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			"channel is required",
+			http.StatusBadRequest,
+		)
+	}
+
+	var externalReference string
+	if temp.ExternalReference != nil {
+		externalReference = *temp.ExternalReference
+	}
+
+	*r = PayoffLoanRequest{
+		QuoteID:           *temp.QuoteID,
+		Channel:           *temp.Channel,
+		ExternalReference: externalReference,
+	}
+
+	return nil
+}
+
+func PayoffLoan(
+	logger billing.Logger,
+	payoffService billing.PayoffService,
+	id string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		if _, errParse := uuid.Parse(id); errParse != nil {
+			util.MarshalJSONError(w, billing.ErrInvalidUUID)
+			return
+		}
+
+		var in PayoffLoanRequest
+		if err := unmarshalRequestBody(r, &in); err != nil {
+			logger.WarnContext(ctx, "failed to unmarshal request body", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		payment, err := payoffService.Payoff(ctx, id, in.QuoteID, in.Channel, in.ExternalReference)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to pay off loan", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		util.MarshalJSONResponse(w, http.StatusOK, PaymentResponse{payment})
+	}
+}
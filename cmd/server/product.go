@@ -0,0 +1,321 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/theyudiriski/billing-service/cmd/server/util"
+	billing "github.com/theyudiriski/billing-service/internal/service"
+)
+
+// CreateLoanProduct
+type CreateLoanProductRequest struct {
+	Name  string
+	Terms billing.LoanProductTerms
+}
+
+func (r *CreateLoanProductRequest) UnmarshalJSON(b []byte) error {
+	temp := struct {
+		Name            *string                 `json:"name"`
+		Currency        *string                 `json:"currency"`
+		MinPrincipal    *json.Number            `json:"min_principal"`
+		MaxPrincipal    *json.Number            `json:"max_principal"`
+		MinInterestRate *float64                `json:"min_interest_rate"`
+		MaxInterestRate *float64                `json:"max_interest_rate"`
+		InterestModel   *billing.InterestModel  `json:"interest_model"`
+		Frequencies     []billing.LoanFrequency `json:"frequencies"`
+		Terms           []int                   `json:"terms"`
+		FeeAmount       *json.Number            `json:"fee_amount"`
+		FeeRate         *float64                `json:"fee_rate"`
+		GraceDays       *int                    `json:"grace_days"`
+	}{}
+
+	if err := json.Unmarshal(b, &temp); err != nil {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			err.Error(),
+			http.StatusBadRequest,
+		)
+	}
+
+	if temp.Name == nil || strings.TrimSpace(*temp.Name) == "" {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			"name is required",
+			http.StatusBadRequest,
+		)
+	}
+
+	minPrincipal, err := parsePositiveAmount(temp.MinPrincipal, temp.Currency, "min_principal")
+	if err != nil {
+		return err
+	}
+
+	maxPrincipal, err := parsePositiveAmount(temp.MaxPrincipal, temp.Currency, "max_principal")
+	if err != nil {
+		return err
+	}
+
+	if temp.MinInterestRate == nil || temp.MaxInterestRate == nil {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			"min_interest_rate and max_interest_rate are required, equal for a fixed rate",
+			http.StatusBadRequest,
+		)
+	}
+
+	if len(temp.Frequencies) == 0 || len(temp.Terms) == 0 {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			"frequencies and terms are required",
+			http.StatusBadRequest,
+		)
+	}
+
+	// loans are flat unless told otherwise
+	interestModel := billing.InterestModelFlat
+	if temp.InterestModel != nil {
+		interestModel = *temp.InterestModel
+	}
+
+	// fees and grace days are optional, charged in the product currency
+	feeAmount := minPrincipal.ZeroLike()
+	if temp.FeeAmount != nil {
+		feeAmount, err = billing.ParseAmount(temp.FeeAmount.String(), minPrincipal.Currency)
+		if err != nil || feeAmount.Cmp(feeAmount.ZeroLike()) < 0 {
+			return billing.NewError(
+				billing.ErrValidationError.Error(),
+				"fee_amount must not be negative",
+				http.StatusBadRequest,
+			)
+		}
+	}
+
+	var feeRate float64
+	if temp.FeeRate != nil {
+		feeRate = *temp.FeeRate
+	}
+
+	var graceDays int
+	if temp.GraceDays != nil {
+		graceDays = *temp.GraceDays
+	}
+
+	*r = CreateLoanProductRequest{
+		Name: strings.TrimSpace(*temp.Name),
+		Terms: billing.LoanProductTerms{
+			MinPrincipal:    minPrincipal,
+			MaxPrincipal:    maxPrincipal,
+			MinInterestRate: *temp.MinInterestRate,
+			MaxInterestRate: *temp.MaxInterestRate,
+			InterestModel:   interestModel,
+			Frequencies:     temp.Frequencies,
+			Terms:           temp.Terms,
+			FeeAmount:       feeAmount,
+			FeeRate:         feeRate,
+			GraceDays:       graceDays,
+		},
+	}
+
+	return nil
+}
+
+// UpdateLoanProductStatus
+type UpdateLoanProductStatusRequest struct {
+	Status billing.LoanProductStatus
+}
+
+func (r *UpdateLoanProductStatusRequest) UnmarshalJSON(b []byte) error {
+	temp := struct {
+		Status *billing.LoanProductStatus `json:"status"`
+	}{}
+
+	if err := json.Unmarshal(b, &temp); err != nil {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			err.Error(),
+			http.StatusBadRequest,
+		)
+	}
+
+	if temp.Status == nil {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			"status is required",
+			http.StatusBadRequest,
+		)
+	}
+
+	*r = UpdateLoanProductStatusRequest{
+		Status: *temp.Status,
+	}
+
+	return nil
+}
+
+type LoanProductResponse struct {
+	*billing.LoanProduct
+}
+
+func (r LoanProductResponse) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		ID              string                  `json:"id"`
+		Name            string                  `json:"name"`
+		Currency        string                  `json:"currency"`
+		MinPrincipal    json.Number             `json:"min_principal"`
+		MaxPrincipal    json.Number             `json:"max_principal"`
+		MinInterestRate float64                 `json:"min_interest_rate"`
+		MaxInterestRate float64                 `json:"max_interest_rate"`
+		InterestModel   string                  `json:"interest_model"`
+		Frequencies     []billing.LoanFrequency `json:"frequencies"`
+		Terms           []int                   `json:"terms"`
+		FeeAmount       json.Number             `json:"fee_amount"`
+		FeeRate         float64                 `json:"fee_rate"`
+		GraceDays       int                     `json:"grace_days"`
+		Status          string                  `json:"status"`
+		CreatedAt       string                  `json:"created_at"`
+	}{
+		ID:              r.ID,
+		Name:            r.Name,
+		Currency:        r.Currency(),
+		MinPrincipal:    json.Number(r.MinPrincipal.String()),
+		MaxPrincipal:    json.Number(r.MaxPrincipal.String()),
+		MinInterestRate: r.MinInterestRate,
+		MaxInterestRate: r.MaxInterestRate,
+		InterestModel:   string(r.InterestModel),
+		Frequencies:     r.Frequencies,
+		Terms:           r.Terms,
+		FeeAmount:       json.Number(r.FeeAmount.String()),
+		FeeRate:         r.FeeRate,
+		GraceDays:       r.GraceDays,
+		Status:          string(r.Status),
+		CreatedAt:       billing.LocalTime(r.CreatedAt).Format(time.RFC3339),
+	})
+}
+
+type ListLoanProductsResponse struct {
+	Products []LoanProductResponse `json:"products"`
+}
+
+func CreateLoanProduct(
+	logger billing.Logger,
+	productService billing.LoanProductService,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		var in CreateLoanProductRequest
+		if err := unmarshalRequestBody(r, &in); err != nil {
+			logger.WarnContext(ctx, "failed to unmarshal request body", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		product, err := productService.CreateProduct(ctx, in.Name, in.Terms)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to create loan product", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		util.MarshalJSONResponse(w, http.StatusCreated, LoanProductResponse{product})
+	}
+}
+
+func GetLoanProduct(
+	logger billing.Logger,
+	productService billing.LoanProductService,
+	id string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		if _, errParse := uuid.Parse(id); errParse != nil {
+			util.MarshalJSONError(w, billing.ErrInvalidUUID)
+			return
+		}
+
+		product, err := productService.GetProduct(ctx, id)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to get loan product", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		util.MarshalJSONResponse(w, http.StatusOK, LoanProductResponse{product})
+	}
+}
+
+// ListLoanProducts lists every product, or the ones in the status given by the
+// status query parameter.
+func ListLoanProducts(
+	logger billing.Logger,
+	productService billing.LoanProductService,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		var status billing.LoanProductStatus
+		if value := r.URL.Query().Get("status"); value != "" {
+			if err := status.UnmarshalText([]byte(value)); err != nil {
+				util.MarshalJSONError(w, err)
+				return
+			}
+		}
+
+		products, err := productService.ListProducts(ctx, status)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to list loan products", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		response := ListLoanProductsResponse{
+			Products: make([]LoanProductResponse, 0, len(products)),
+		}
+		for i := range products {
+			response.Products = append(response.Products, LoanProductResponse{&products[i]})
+		}
+
+		util.MarshalJSONResponse(w, http.StatusOK, response)
+	}
+}
+
+func UpdateLoanProductStatus(
+	logger billing.Logger,
+	productService billing.LoanProductService,
+	id string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		if _, errParse := uuid.Parse(id); errParse != nil {
+			util.MarshalJSONError(w, billing.ErrInvalidUUID)
+			return
+		}
+
+		var in UpdateLoanProductStatusRequest
+		if err := unmarshalRequestBody(r, &in); err != nil {
+			logger.WarnContext(ctx, "failed to unmarshal request body", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		product, err := productService.UpdateProductStatus(ctx, id, in.Status)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to update loan product status", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		util.MarshalJSONResponse(w, http.StatusOK, LoanProductResponse{product})
+	}
+}
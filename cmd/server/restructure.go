@@ -0,0 +1,174 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/theyudiriski/billing-service/cmd/server/util"
+	billing "github.com/theyudiriski/billing-service/internal/service"
+)
+
+const (
+	maxRestructureReasonLength = 500
+	maxOperatorIDLength        = 100
+)
+
+// RestructureLoan
+type RestructureLoanRequest struct {
+	Terms billing.LoanRestructureTerms
+}
+
+func (r *RestructureLoanRequest) UnmarshalJSON(b []byte) error {
+	temp := struct {
+		ExtendPayments    int     `json:"extend_payments"`
+		HolidayPayments   int     `json:"holiday_payments"`
+		CapitaliseArrears bool    `json:"capitalise_arrears"`
+		Reason            *string `json:"reason"`
+		RequestedBy       *string `json:"requested_by"`
+		ApprovedBy        *string `json:"approved_by"`
+	}{}
+
+	if err := json.Unmarshal(b, &temp); err != nil {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			err.Error(),
+			http.StatusBadRequest,
+		)
+	}
+
+	if temp.Reason == nil || strings.TrimSpace(*temp.Reason) == "" {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			"reason is required",
+			http.StatusBadRequest,
+		)
+	}
+
+	if len(*temp.Reason) > maxRestructureReasonLength {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			fmt.Sprintf("reason must be at most %d characters", maxRestructureReasonLength),
+			http.StatusBadRequest,
+		)
+	}
+
+	operators := []struct {
+		field string
+		value *string
+	}{
+		{"requested_by", temp.RequestedBy},
+		{"approved_by", temp.ApprovedBy},
+	}
+	for _, operator := range operators {
+		if operator.value == nil || *operator.value == "" {
+			return billing.NewError(
+				billing.ErrValidationError.Error(),
+				fmt.Sprintf("%s is required", operator.field),
+				http.StatusBadRequest,
+			)
+		}
+
+		if len(*operator.value) > maxOperatorIDLength {
+			return billing.NewError(
+				billing.ErrValidationError.Error(),
+				fmt.Sprintf("%s must be at most %d characters", operator.field, maxOperatorIDLength),
+				http.StatusBadRequest,
+			)
+		}
+	}
+
+	*r = RestructureLoanRequest{
+		Terms: billing.LoanRestructureTerms{
+			ExtendPayments:    temp.ExtendPayments,
+			HolidayPayments:   temp.HolidayPayments,
+			CapitaliseArrears: temp.CapitaliseArrears,
+			Reason:            strings.TrimSpace(*temp.Reason),
+			RequestedBy:       *temp.RequestedBy,
+			ApprovedBy:        *temp.ApprovedBy,
+		},
+	}
+
+	return nil
+}
+
+type RestructureResponse struct {
+	*billing.LoanRestructure
+}
+
+func (r RestructureResponse) MarshalJSON() ([]byte, error) {
+	schedules := make([]ScheduleResponse, 0, len(r.Schedules))
+	for _, schedule := range r.Schedules {
+		schedules = append(schedules, ScheduleResponse{schedule})
+	}
+
+	return json.Marshal(&struct {
+		ID                string             `json:"id"`
+		LoanID            string             `json:"loan_id"`
+		Version           int                `json:"version"`
+		ExtendPayments    int                `json:"extend_payments"`
+		HolidayPayments   int                `json:"holiday_payments"`
+		CapitaliseArrears bool               `json:"capitalise_arrears"`
+		Outstanding       json.Number        `json:"outstanding"`
+		Arrears           json.Number        `json:"arrears"`
+		Capitalised       json.Number        `json:"capitalised"`
+		Currency          string             `json:"currency"`
+		Reason            string             `json:"reason"`
+		RequestedBy       string             `json:"requested_by"`
+		ApprovedBy        string             `json:"approved_by"`
+		CreatedAt         string             `json:"created_at"`
+		Schedules         []ScheduleResponse `json:"schedules"`
+	}{
+		ID:                r.ID,
+		LoanID:            r.LoanID,
+		Version:           r.Version,
+		ExtendPayments:    r.ExtendPayments,
+		HolidayPayments:   r.HolidayPayments,
+		CapitaliseArrears: r.CapitaliseArrears,
+		Outstanding:       json.Number(r.Outstanding.String()),
+		Arrears:           json.Number(r.Arrears.String()),
+		Capitalised:       json.Number(r.Capitalised.String()),
+		Currency:          r.Outstanding.Currency,
+		Reason:            r.Reason,
+		RequestedBy:       r.RequestedBy,
+		ApprovedBy:        r.ApprovedBy,
+		CreatedAt:         billing.LocalTime(r.CreatedAt).Format(time.RFC3339),
+		Schedules:         schedules,
+	})
+}
+
+func RestructureLoan(
+	logger billing.Logger,
+	loanService billing.LoanService,
+	id string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		if _, errParse := uuid.Parse(id); errParse != nil {
+			util.MarshalJSONError(w, billing.ErrInvalidUUID)
+			return
+		}
+
+		var in RestructureLoanRequest
+		if err := unmarshalRequestBody(r, &in); err != nil {
+			logger.WarnContext(ctx, "failed to unmarshal request body", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		restructure, err := loanService.RestructureLoan(ctx, id, in.Terms)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to restructure loan", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		util.MarshalJSONResponse(w, http.StatusOK, RestructureResponse{restructure})
+	}
+}
@@ -22,11 +22,173 @@ var errorMapping = map[error]error{
 		http.StatusBadRequest,
 	),
 
-	billing.ErrPaymentAmountMismatch: billing.NewError(
-		billing.ErrPaymentAmountMismatch.Error(),
-		"Payment amount is not equal to pending amount",
+	billing.ErrLoanProductNotFound: billing.NewError(
+		billing.ErrLoanProductNotFound.Error(),
+		"Loan product not found",
 		http.StatusBadRequest,
 	),
+
+	billing.ErrLoanProductRetired: billing.NewError(
+		billing.ErrLoanProductRetired.Error(),
+		"Loan product is retired and no longer open to new loans",
+		http.StatusUnprocessableEntity,
+	),
+
+	billing.ErrInvalidLoanProductStatusTransition: billing.NewError(
+		billing.ErrInvalidLoanProductStatusTransition.Error(),
+		"Loan product is already in this status",
+		http.StatusUnprocessableEntity,
+	),
+
+	billing.ErrBorrowerNotFound: billing.NewError(
+		billing.ErrBorrowerNotFound.Error(),
+		"Borrower not found",
+		http.StatusBadRequest,
+	),
+
+	billing.ErrBorrowerAlreadyExists: billing.NewError(
+		billing.ErrBorrowerAlreadyExists.Error(),
+		"Borrower with this identity is already registered",
+		http.StatusConflict,
+	),
+
+	billing.ErrBorrowerNotVerified: billing.NewError(
+		billing.ErrBorrowerNotVerified.Error(),
+		"Borrower has not passed KYC verification",
+		http.StatusUnprocessableEntity,
+	),
+
+	billing.ErrInvalidKYCStatusTransition: billing.NewError(
+		billing.ErrInvalidKYCStatusTransition.Error(),
+		"KYC status transition is not allowed",
+		http.StatusUnprocessableEntity,
+	),
+
+	billing.ErrBorrowerDelinquent: billing.NewError(
+		billing.ErrBorrowerDelinquent.Error(),
+		"Borrower has a delinquent loan",
+		http.StatusUnprocessableEntity,
+	),
+
+	billing.ErrActiveLoanLimitExceeded: billing.NewError(
+		billing.ErrActiveLoanLimitExceeded.Error(),
+		"Borrower has reached the limit of active loans",
+		http.StatusUnprocessableEntity,
+	),
+
+	billing.ErrOutstandingLimitExceeded: billing.NewError(
+		billing.ErrOutstandingLimitExceeded.Error(),
+		"Loan would take the borrower over their outstanding principal limit",
+		http.StatusUnprocessableEntity,
+	),
+
+	billing.ErrLoanNotPayable: billing.NewError(
+		billing.ErrLoanNotPayable.Error(),
+		"Loan is not accepting payments in its current status",
+		http.StatusUnprocessableEntity,
+	),
+
+	billing.ErrLoanNotPendingDisbursement: billing.NewError(
+		billing.ErrLoanNotPendingDisbursement.Error(),
+		"Loan is not awaiting disbursement",
+		http.StatusUnprocessableEntity,
+	),
+
+	billing.ErrDisbursementReferenceConflict: billing.NewError(
+		billing.ErrDisbursementReferenceConflict.Error(),
+		"Disbursement reference is already recorded on another loan",
+		http.StatusConflict,
+	),
+
+	billing.ErrLoanNotCancellable: billing.NewError(
+		billing.ErrLoanNotCancellable.Error(),
+		"Loan can only be cancelled before disbursement or within the cooling-off period",
+		http.StatusUnprocessableEntity,
+	),
+
+	billing.ErrLoanNotRestructurable: billing.NewError(
+		billing.ErrLoanNotRestructurable.Error(),
+		"Only active or delinquent loans with installments left can be restructured",
+		http.StatusUnprocessableEntity,
+	),
+
+	billing.ErrLoanNotDeferrable: billing.NewError(
+		billing.ErrLoanNotDeferrable.Error(),
+		"Only active or delinquent loans with upcoming installments can be deferred",
+		http.StatusUnprocessableEntity,
+	),
+
+	billing.ErrPayoffQuoteNotFound: billing.NewError(
+		billing.ErrPayoffQuoteNotFound.Error(),
+		"Payoff quote not found",
+		http.StatusBadRequest,
+	),
+
+	billing.ErrPayoffQuoteNotDue: billing.NewError(
+		billing.ErrPayoffQuoteNotDue.Error(),
+		"Payoff quote is for a later day, pay it on that day",
+		http.StatusUnprocessableEntity,
+	),
+
+	billing.ErrPayoffQuoteExpired: billing.NewError(
+		billing.ErrPayoffQuoteExpired.Error(),
+		"Payoff quote has expired, request a new one",
+		http.StatusUnprocessableEntity,
+	),
+
+	billing.ErrPayoffQuoteStale: billing.NewError(
+		billing.ErrPayoffQuoteStale.Error(),
+		"Loan balance changed since the payoff quote, request a new one",
+		http.StatusUnprocessableEntity,
+	),
+
+	billing.ErrPayoffQuoteUsed: billing.NewError(
+		billing.ErrPayoffQuoteUsed.Error(),
+		"Payoff quote has already been paid",
+		http.StatusConflict,
+	),
+
+	billing.ErrInvalidLoanStatusTransition: billing.NewError(
+		billing.ErrInvalidLoanStatusTransition.Error(),
+		"Loan status transition is not allowed",
+		http.StatusUnprocessableEntity,
+	),
+
+	billing.ErrIdempotencyKeyMismatch: billing.NewError(
+		billing.ErrIdempotencyKeyMismatch.Error(),
+		"Idempotency-Key has already been used with a different request",
+		http.StatusUnprocessableEntity,
+	),
+
+	billing.ErrLateFeeNotFound: billing.NewError(
+		billing.ErrLateFeeNotFound.Error(),
+		"Late fee not found",
+		http.StatusBadRequest,
+	),
+
+	billing.ErrLateFeeNotWaivable: billing.NewError(
+		billing.ErrLateFeeNotWaivable.Error(),
+		"Late fee can no longer be waived",
+		http.StatusUnprocessableEntity,
+	),
+
+	billing.ErrAdjustmentNotFound: billing.NewError(
+		billing.ErrAdjustmentNotFound.Error(),
+		"Adjustment not found",
+		http.StatusBadRequest,
+	),
+
+	billing.ErrAdjustmentNotApplicable: billing.NewError(
+		billing.ErrAdjustmentNotApplicable.Error(),
+		"Adjustment no longer fits what is left on the schedule",
+		http.StatusUnprocessableEntity,
+	),
+
+	billing.ErrAdjustmentNotPending: billing.NewError(
+		billing.ErrAdjustmentNotPending.Error(),
+		"Adjustment has already been decided on",
+		http.StatusUnprocessableEntity,
+	),
 }
 
 func MarshalJSONResponse(w http.ResponseWriter, statusCode int, data any) {
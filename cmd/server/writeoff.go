@@ -0,0 +1,107 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/theyudiriski/billing-service/cmd/server/util"
+	billing "github.com/theyudiriski/billing-service/internal/service"
+)
+
+const maxWriteOffReasonLength = 500
+
+// WriteOffLoan
+type WriteOffLoanRequest struct {
+	Reason string
+}
+
+func (r *WriteOffLoanRequest) UnmarshalJSON(b []byte) error {
+	temp := struct {
+		Reason *string `json:"reason"`
+	}{}
+
+	if err := json.Unmarshal(b, &temp); err != nil {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			err.Error(),
+			http.StatusBadRequest,
+		)
+	}
+
+	if temp.Reason == nil || strings.TrimSpace(*temp.Reason) == "" {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			"reason is required",
+			http.StatusBadRequest,
+		)
+	}
+
+	if len(*temp.Reason) > maxWriteOffReasonLength {
+		return billing.NewError(
+			billing.ErrValidationError.Error(),
+			fmt.Sprintf("reason must be at most %d characters", maxWriteOffReasonLength),
+			http.StatusBadRequest,
+		)
+	}
+
+	*r = WriteOffLoanRequest{
+		Reason: strings.TrimSpace(*temp.Reason),
+	}
+
+	return nil
+}
+
+type WriteOffResponse struct {
+	*billing.LoanWriteOff
+}
+
+func (r WriteOffResponse) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		Reason       string      `json:"reason"`
+		WrittenOffAt string      `json:"written_off_at"`
+		Amount       json.Number `json:"amount"`
+		Currency     string      `json:"currency"`
+	}{
+		Reason:       r.Reason,
+		WrittenOffAt: billing.LocalTime(r.WrittenOffAt).Format(time.RFC3339),
+		Amount:       json.Number(r.Amount.String()),
+		Currency:     r.Amount.Currency,
+	})
+}
+
+func WriteOffLoan(
+	logger billing.Logger,
+	loanService billing.LoanService,
+	id string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		if _, errParse := uuid.Parse(id); errParse != nil {
+			util.MarshalJSONError(w, billing.ErrInvalidUUID)
+			return
+		}
+
+		var in WriteOffLoanRequest
+		if err := unmarshalRequestBody(r, &in); err != nil {
+			logger.WarnContext(ctx, "failed to unmarshal request body", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		loan, err := loanService.WriteOffLoan(ctx, id, in.Reason)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to write off loan", "error", err)
+			util.MarshalJSONError(w, err)
+			return
+		}
+
+		util.MarshalJSONResponse(w, http.StatusOK, LoanResponse{loan})
+	}
+}
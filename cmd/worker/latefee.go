@@ -0,0 +1,86 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/theyudiriski/billing-service/config"
+	"github.com/theyudiriski/billing-service/internal/postgres"
+	billing "github.com/theyudiriski/billing-service/internal/service"
+)
+
+// LateFeeWorker accrues late fees on overdue schedules on a fixed interval.
+type LateFeeWorker struct {
+	logger         billing.Logger
+	interval       time.Duration
+	lateFeeService billing.LateFeeService
+	db             *postgres.Client
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewLateFeeWorker() *LateFeeWorker {
+	conf := config.LoadLateFeeWorker()
+	logger := billing.NewLogger()
+
+	db, err := postgres.NewClient(conf.Database)
+	if err != nil {
+		panic(err)
+	}
+
+	rules, err := billing.NewLateFeeRules(
+		conf.Rules.GraceDays,
+		conf.Rules.FlatAmounts,
+		conf.Rules.DailyRate,
+		conf.Rules.CapRate,
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	txManager := postgres.NewTxManager(db)
+	loanStore := postgres.NewLoanStore(db)
+	lateFeeStore := postgres.NewLateFeeStore(db)
+
+	lateFeeService := billing.NewLateFeeService(logger, rules, txManager, loanStore, lateFeeStore)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &LateFeeWorker{
+		logger:         logger,
+		interval:       conf.Interval,
+		lateFeeService: lateFeeService,
+		db:             db,
+
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+func (w *LateFeeWorker) Run() error {
+	w.logger.Info(fmt.Sprintf("late fee worker running every %v with PID %v", w.interval, os.Getpid()))
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		// a failed run is retried on the next tick, accrual catches up on missed days
+		if err := w.lateFeeService.AccrueLateFees(w.ctx); err != nil {
+			w.logger.WarnContext(w.ctx, "failed to accrue late fees", "error", err)
+		}
+
+		select {
+		case <-w.ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *LateFeeWorker) Stop() error {
+	w.logger.Info("stopping late fee worker")
+	w.cancel()
+	return w.db.Close()
+}
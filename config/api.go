@@ -12,6 +12,11 @@ func LoadAPI() API {
 	config.HTTP.WriteTimeout = RequireEnvToDuration("HTTP_WRITE_TIMEOUT")
 
 	config.Database = LoadPostgres()
+	config.MigrateOnStartup = OptionalEnvToBool("MIGRATE_ON_STARTUP", false)
+	config.LateFee = LoadLateFee()
+	config.CreditPolicy = LoadCreditPolicy()
+	config.LoanCoolingOffDays = OptionalEnvToInt("LOAN_COOLING_OFF_DAYS", 0)
+	config.Payoff = LoadPayoff()
 
 	return config
 }
@@ -23,4 +28,11 @@ type API struct {
 		WriteTimeout time.Duration
 	}
 	Database Database
+	// apply pending migrations before serving
+	MigrateOnStartup bool
+	LateFee          LateFee
+	CreditPolicy     CreditPolicy
+	// days after disbursement a loan may still be cancelled
+	LoanCoolingOffDays int
+	Payoff             Payoff
 }
@@ -0,0 +1,17 @@
+package config
+
+func LoadCreditPolicy() CreditPolicy {
+	return CreditPolicy{
+		MaxActiveLoans:          OptionalEnvToInt("CREDIT_MAX_ACTIVE_LOANS", 0),
+		MaxOutstandingPrincipal: optionalEnvToCurrencyAmounts("CREDIT_MAX_OUTSTANDING_PRINCIPAL"),
+		BlockDelinquent:         OptionalEnvToBool("CREDIT_BLOCK_DELINQUENT", true),
+	}
+}
+
+type CreditPolicy struct {
+	// 0 means unlimited
+	MaxActiveLoans int
+	// decimal amounts keyed by currency code
+	MaxOutstandingPrincipal map[string]string
+	BlockDelinquent         bool
+}
@@ -5,6 +5,11 @@ import "time"
 type Database struct {
 	Leader   DatabaseConfig
 	Follower DatabaseConfig
+
+	// reads fall back to the leader while the follower lags further behind
+	// than MaxFollowerLag, checked every FollowerLagCheckInterval
+	MaxFollowerLag           time.Duration
+	FollowerLagCheckInterval time.Duration
 }
 
 type DatabaseConfig struct {
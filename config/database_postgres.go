@@ -1,6 +1,9 @@
 package config
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 const (
 	engine = "postgres"
@@ -8,6 +11,9 @@ const (
 	defaultMaxIdleConns    = 5
 	defaultMaxOpenConns    = 7
 	defaultConnMaxLifetime = 30 * time.Minute
+
+	defaultMaxFollowerLag           = 5 * time.Second
+	defaultFollowerLagCheckInterval = 5 * time.Second
 )
 
 func LoadPostgres() Database {
@@ -34,6 +40,12 @@ func LoadPostgres() Database {
 			MaxOpenConns:    OptionalEnvToInt("POSTGRES_FOLLOWER_MAX_OPEN_CONNECTIONS", defaultMaxOpenConns),
 			ConnMaxLifetime: OptionalEnvToDuration("POSTGRES_FOLLOWER_CONNECTION_MAX_LIFETIME", defaultConnMaxLifetime),
 		},
+		MaxFollowerLag:           OptionalEnvToDuration("POSTGRES_FOLLOWER_MAX_LAG", defaultMaxFollowerLag),
+		FollowerLagCheckInterval: OptionalEnvToDuration("POSTGRES_FOLLOWER_LAG_CHECK_INTERVAL", defaultFollowerLagCheckInterval),
+	}
+
+	if cfg.FollowerLagCheckInterval <= 0 {
+		panic(fmt.Errorf("POSTGRES_FOLLOWER_LAG_CHECK_INTERVAL should be a positive time.Duration"))
 	}
 
 	return cfg
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	defaultLateFeeAccrualInterval = 24 * time.Hour
+)
+
+func LoadLateFeeWorker() LateFeeWorker {
+	config := LateFeeWorker{}
+
+	config.Interval = OptionalEnvToDuration("LATE_FEE_ACCRUAL_INTERVAL", defaultLateFeeAccrualInterval)
+	config.Rules = LoadLateFee()
+	config.Database = LoadPostgres()
+
+	return config
+}
+
+type LateFeeWorker struct {
+	Interval time.Duration
+	Rules    LateFee
+	Database Database
+}
+
+func LoadLateFee() LateFee {
+	return LateFee{
+		GraceDays:   OptionalEnvToInt("LATE_FEE_GRACE_DAYS", 0),
+		FlatAmounts: optionalEnvToCurrencyAmounts("LATE_FEE_FLAT_AMOUNTS"),
+		DailyRate:   OptionalEnvToFloat("LATE_FEE_DAILY_RATE", 0),
+		CapRate:     OptionalEnvToFloat("LATE_FEE_CAP_RATE", 0),
+	}
+}
+
+type LateFee struct {
+	GraceDays int
+	// decimal amounts keyed by currency code
+	FlatAmounts map[string]string
+	DailyRate   float64
+	CapRate     float64
+}
+
+// optionalEnvToCurrencyAmounts reads a comma separated list of CURRENCY:AMOUNT
+// pairs, ex IDR:50000,SGD:5.
+func optionalEnvToCurrencyAmounts(key string) map[string]string {
+	value := map[string]string{}
+	for _, pair := range OptionalEnvToStringSlice(key, nil) {
+		currency, amount, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || currency == "" || amount == "" {
+			panic(fmt.Errorf("%s should be a list of CURRENCY:AMOUNT, ex IDR:50000,SGD:5", key))
+		}
+		value[currency] = amount
+	}
+
+	return value
+}
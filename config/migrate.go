@@ -0,0 +1,11 @@
+package config
+
+func LoadMigrate() Migrate {
+	return Migrate{
+		Database: LoadPostgres(),
+	}
+}
+
+type Migrate struct {
+	Database Database
+}
@@ -0,0 +1,12 @@
+package config
+
+func LoadPayoff() Payoff {
+	return Payoff{
+		PrepaymentFeeRate: OptionalEnvToFloat("PAYOFF_PREPAYMENT_FEE_RATE", 0),
+	}
+}
+
+type Payoff struct {
+	// charged on the principal paid ahead of its due date
+	PrepaymentFeeRate float64
+}
@@ -0,0 +1,265 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	billing "github.com/theyudiriski/billing-service/internal/service"
+)
+
+// adjustedColumns maps an adjustment type to the schedule component it corrects.
+var adjustedColumns = map[billing.AdjustmentType]string{
+	billing.AdjustmentTypeWaiveFee:          "fee_due",
+	billing.AdjustmentTypeReduceInstallment: "principal_due",
+	billing.AdjustmentTypeWriteOffInterest:  "interest_due",
+}
+
+// approvedAdjustments sums in minor units the approved adjustments of the given
+// types on the schedule of the given id column, a reversal counting against the
+// adjustment it undoes. No types sums them all.
+func approvedAdjustments(scheduleIDColumn string, types ...billing.AdjustmentType) string {
+	typeFilter := ""
+	if len(types) > 0 {
+		quoted := make([]string, 0, len(types))
+		for _, adjustmentType := range types {
+			quoted = append(quoted, "'"+string(adjustmentType)+"'")
+		}
+		typeFilter = "AND a.type IN (" + strings.Join(quoted, ", ") + ")"
+	}
+
+	return `(
+		SELECT
+			COALESCE(SUM(CASE WHEN a.reverses_id IS NULL THEN 1 ELSE -1 END * CAST(a.amount->>'value' AS BIGINT)), 0)
+		FROM
+			loan_adjustments a
+		WHERE
+			a.schedule_id = ` + scheduleIDColumn + `
+			AND a.status = 'approved'
+			` + typeFilter + `
+	)`
+}
+
+// adjustedValue is what is left in minor units of a schedule amount column once
+// the approved adjustments of the given types are taken off. Schedules keep the
+// amounts they were made with, adjustments only ever apply on top of them.
+func adjustedValue(table, column string, types ...billing.AdjustmentType) string {
+	return `(CAST(` + table + `.` + column + `->>'value' AS BIGINT) - ` +
+		approvedAdjustments(table+".id", types...) + `)`
+}
+
+// adjustedAmount is adjustedValue as an amount in the currency and precision of
+// the column.
+func adjustedAmount(table, column string, types ...billing.AdjustmentType) string {
+	return `jsonb_set(` + table + `.` + column + `, '{value}', to_jsonb` +
+		adjustedValue(table, column, types...) + `)`
+}
+
+func NewAdjustmentStore(db *Client) billing.AdjustmentStore {
+	return &adjustmentStore{db}
+}
+
+type adjustmentStore struct {
+	db *Client
+}
+
+func (s *adjustmentStore) CreateAdjustment(
+	ctx context.Context,
+	adjustment *billing.Adjustment,
+) error {
+	_, err := s.db.leader(ctx).ExecContext(ctx, `
+INSERT INTO loan_adjustments(
+	id,
+	loan_id,
+	schedule_id,
+	type,
+	amount,
+	reason_code,
+	status,
+	reverses_id,
+	requested_by,
+	requested_at
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		adjustment.ID,
+		adjustment.LoanID,
+		adjustment.ScheduleID,
+		adjustment.Type,
+		adjustment.Amount,
+		adjustment.ReasonCode,
+		adjustment.Status,
+		adjustment.ReversesID,
+		adjustment.RequestedBy,
+		adjustment.RequestedAt,
+	)
+	return err
+}
+
+// ListAdjustmentsByLoanID returns every adjustment of a loan, oldest first.
+func (s *adjustmentStore) ListAdjustmentsByLoanID(
+	ctx context.Context,
+	loanID string,
+) ([]billing.Adjustment, error) {
+	rows, err := s.db.follower(ctx).QueryContext(ctx, `
+SELECT
+	id,
+	loan_id,
+	schedule_id,
+	type,
+	amount,
+	reason_code,
+	status,
+	reverses_id,
+	requested_by,
+	requested_at,
+	decided_by,
+	decided_at
+FROM
+	loan_adjustments
+WHERE
+	loan_id = $1
+ORDER BY
+	requested_at`,
+		loanID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	adjustments := []billing.Adjustment{}
+	for rows.Next() {
+		var a billing.Adjustment
+		if err := rows.Scan(
+			&a.ID,
+			&a.LoanID,
+			&a.ScheduleID,
+			&a.Type,
+			&a.Amount,
+			&a.ReasonCode,
+			&a.Status,
+			&a.ReversesID,
+			&a.RequestedBy,
+			&a.RequestedAt,
+			&a.DecidedBy,
+			&a.DecidedAt,
+		); err != nil {
+			return nil, err
+		}
+		adjustments = append(adjustments, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return adjustments, nil
+}
+
+// ApproveAdjustment marks a pending adjustment as approved in a single
+// transaction with the status of its schedule, which follows what is left to
+// pay once the adjustment is taken off, or added back for a reversal. The
+// schedule amounts themselves are left as they were made. A schedule voided or
+// superseded since the adjustment was requested is no longer adjustable.
+func (s *adjustmentStore) ApproveAdjustment(
+	ctx context.Context,
+	adjustment *billing.Adjustment,
+) error {
+	return runInTx(ctx, s.db.Leader, nil, func(tx *sql.Tx) error {
+		return approveAdjustment(ctx, tx, adjustment)
+	})
+}
+
+func approveAdjustment(
+	ctx context.Context,
+	tx *sql.Tx,
+	adjustment *billing.Adjustment,
+) error {
+	if err := decideAdjustment(ctx, tx, adjustment); err != nil {
+		return err
+	}
+
+	// the adjustment is approved by now, so it is part of what is taken off
+	column := adjustedColumns[adjustment.Type]
+	amountDue := adjustedValue("loan_schedules", "amount_due")
+	result, err := tx.ExecContext(ctx, `
+UPDATE
+	loan_schedules
+SET
+	status = CASE
+		WHEN CAST(paid_amount->>'value' AS BIGINT) >= `+amountDue+` THEN 'paid'
+		WHEN CAST(paid_amount->>'value' AS BIGINT) > 0 THEN 'partially_paid'
+		ELSE 'unpaid'
+	END,
+	paid_at = CASE
+		WHEN CAST(paid_amount->>'value' AS BIGINT) >= `+amountDue+` THEN COALESCE(paid_at, $2)
+		ELSE NULL
+	END
+WHERE
+	id = $1
+	AND status NOT IN ('void', 'superseded')
+	AND `+adjustedValue("loan_schedules", column, adjustment.Type)+` >= 0
+	AND `+amountDue+` >= CAST(paid_amount->>'value' AS BIGINT)`,
+		adjustment.ScheduleID,
+		adjustment.DecidedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return billing.ErrAdjustmentNotApplicable
+	}
+
+	return nil
+}
+
+// RejectAdjustment marks a pending adjustment as rejected.
+func (s *adjustmentStore) RejectAdjustment(
+	ctx context.Context,
+	adjustment *billing.Adjustment,
+) error {
+	return runInTx(ctx, s.db.Leader, nil, func(tx *sql.Tx) error {
+		return decideAdjustment(ctx, tx, adjustment)
+	})
+}
+
+// decideAdjustment records the approval decision, failing when the adjustment
+// has been decided on in the meantime.
+func decideAdjustment(
+	ctx context.Context,
+	tx *sql.Tx,
+	adjustment *billing.Adjustment,
+) error {
+	result, err := tx.ExecContext(ctx, `
+UPDATE
+	loan_adjustments
+SET
+	status = $2,
+	decided_by = $3,
+	decided_at = $4
+WHERE
+	id = $1
+	AND status = 'pending_approval'`,
+		adjustment.ID,
+		adjustment.Status,
+		adjustment.DecidedBy,
+		adjustment.DecidedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return billing.ErrAdjustmentNotPending
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	billing "github.com/theyudiriski/billing-service/internal/service"
+)
+
+func NewBorrowerStore(db *Client) billing.BorrowerStore {
+	return &borrowerStore{db}
+}
+
+type borrowerStore struct {
+	db *Client
+}
+
+func (s *borrowerStore) CreateBorrower(
+	ctx context.Context,
+	borrower *billing.Borrower,
+) error {
+	result, err := s.db.leader(ctx).ExecContext(ctx, `
+INSERT INTO borrowers(
+	id,
+	full_name,
+	identity_type,
+	identity_number,
+	date_of_birth,
+	email,
+	phone_number,
+	address,
+	kyc_status,
+	created_at
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+ON CONFLICT (identity_type, identity_number) DO NOTHING`,
+		borrower.ID,
+		borrower.FullName,
+		borrower.IdentityType,
+		borrower.IdentityNumber,
+		borrower.DateOfBirth,
+		borrower.Email,
+		borrower.PhoneNumber,
+		borrower.Address,
+		borrower.KYCStatus,
+		borrower.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return billing.ErrBorrowerAlreadyExists
+	}
+
+	return nil
+}
+
+func (s *borrowerStore) GetBorrowerByID(
+	ctx context.Context,
+	borrowerID string,
+) (*billing.Borrower, error) {
+	row := s.db.follower(ctx).QueryRowContext(ctx, `
+SELECT
+	`+borrowerColumns+`
+FROM
+	borrowers
+WHERE
+	id = $1`,
+		borrowerID,
+	)
+
+	borrower, err := scanBorrower(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, billing.ErrBorrowerNotFound
+		}
+		return nil, err
+	}
+
+	return borrower, nil
+}
+
+// GetBorrowerByIDForUpdate returns the borrower and locks it until the
+// transaction in ctx ends, so loans for the same borrower are originated one
+// at a time.
+func (s *borrowerStore) GetBorrowerByIDForUpdate(
+	ctx context.Context,
+	borrowerID string,
+) (*billing.Borrower, error) {
+	row := s.db.leader(ctx).QueryRowContext(ctx, `
+SELECT
+	`+borrowerColumns+`
+FROM
+	borrowers
+WHERE
+	id = $1
+FOR UPDATE`,
+		borrowerID,
+	)
+
+	borrower, err := scanBorrower(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, billing.ErrBorrowerNotFound
+		}
+		return nil, err
+	}
+
+	return borrower, nil
+}
+
+// UpdateKYCStatus moves the borrower from one KYC status to another, failing
+// when the borrower is no longer in the expected status.
+func (s *borrowerStore) UpdateKYCStatus(
+	ctx context.Context,
+	borrower *billing.Borrower,
+	from billing.KYCStatus,
+) error {
+	result, err := s.db.leader(ctx).ExecContext(ctx, `
+UPDATE
+	borrowers
+SET
+	kyc_status = $3,
+	kyc_updated_at = $4
+WHERE
+	id = $1
+	AND kyc_status = $2`,
+		borrower.ID,
+		from,
+		borrower.KYCStatus,
+		borrower.KYCUpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return billing.ErrInvalidKYCStatusTransition
+	}
+
+	return nil
+}
+
+const borrowerColumns = `id,
+	full_name,
+	identity_type,
+	identity_number,
+	date_of_birth,
+	email,
+	phone_number,
+	address,
+	kyc_status,
+	kyc_updated_at,
+	created_at`
+
+// scanBorrower reads a borrower row selected with borrowerColumns.
+func scanBorrower(row scanner) (*billing.Borrower, error) {
+	b := &billing.Borrower{}
+	err := row.Scan(
+		&b.ID,
+		&b.FullName,
+		&b.IdentityType,
+		&b.IdentityNumber,
+		&b.DateOfBirth,
+		&b.Email,
+		&b.PhoneNumber,
+		&b.Address,
+		&b.KYCStatus,
+		&b.KYCUpdatedAt,
+		&b.CreatedAt,
+	)
+	return b, err
+}
@@ -1,9 +1,12 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"net/url"
+	"sync/atomic"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/theyudiriski/billing-service/config"
@@ -12,6 +15,11 @@ import (
 type Client struct {
 	Leader   *sql.DB
 	Follower *sql.DB
+
+	// set while the follower lags too far behind to serve reads
+	followerLagging atomic.Bool
+	// stops the follower lag monitor
+	stopMonitor context.CancelFunc
 }
 
 func NewClient(c config.Database) (*Client, error) {
@@ -30,10 +38,29 @@ func NewClient(c config.Database) (*Client, error) {
 		followerDB = leaderDB
 	}
 
-	return &Client{
-		Leader:   leaderDB,
-		Follower: followerDB,
-	}, nil
+	ctx, cancel := context.WithCancel(context.Background())
+	client := &Client{
+		Leader:      leaderDB,
+		Follower:    followerDB,
+		stopMonitor: cancel,
+	}
+
+	if c.IsFollowerEnabled() {
+		go client.monitorFollowerLag(ctx, c.MaxFollowerLag, c.FollowerLagCheckInterval)
+	}
+
+	return client, nil
+}
+
+// Close stops the follower lag monitor and closes the databases.
+func (c *Client) Close() error {
+	c.stopMonitor()
+
+	err := c.Leader.Close()
+	if c.Follower != c.Leader {
+		err = errors.Join(err, c.Follower.Close())
+	}
+	return err
 }
 
 func openDB(c config.DatabaseConfig) (*sql.DB, error) {
@@ -0,0 +1,294 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	billing "github.com/theyudiriski/billing-service/internal/service"
+)
+
+func NewLateFeeStore(db *Client) billing.LateFeeStore {
+	return &lateFeeStore{db}
+}
+
+type lateFeeStore struct {
+	db *Client
+}
+
+// ListOverdueSchedules returns the unsettled schedules of payable loans past
+// their grace period on the given day, grouped by loan and oldest due first.
+// Loans without grace days of their own get defaultGraceDays.
+func (s *lateFeeStore) ListOverdueSchedules(
+	ctx context.Context,
+	today time.Time,
+	defaultGraceDays int,
+) ([]billing.LoanSchedule, error) {
+	rows, err := s.db.leader(ctx).QueryContext(ctx, `
+SELECT
+	`+scheduleColumns+`
+FROM
+	loan_schedules
+WHERE
+	status IN ('unpaid', 'partially_paid')
+	AND EXISTS (
+		SELECT
+			1
+		FROM
+			loans l
+		WHERE
+			l.id = loan_schedules.loan_id
+			AND l.status IN ('active', 'delinquent')
+			AND loan_schedules.due_date < $1 - make_interval(days => COALESCE(l.grace_days, $2))
+	)
+ORDER BY
+	loan_id,
+	due_date,
+	seq`,
+		today,
+		defaultGraceDays,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := []billing.LoanSchedule{}
+	for rows.Next() {
+		schedule, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return schedules, nil
+}
+
+// ListLateFeesByLoanID returns every late fee of a loan, waived ones included,
+// oldest first.
+func (s *lateFeeStore) ListLateFeesByLoanID(
+	ctx context.Context,
+	loanID string,
+) ([]billing.LateFee, error) {
+	rows, err := s.db.follower(ctx).QueryContext(ctx, `
+SELECT
+	id,
+	loan_id,
+	schedule_id,
+	kind,
+	amount,
+	accrued_on,
+	status,
+	waived_at
+FROM
+	late_fees
+WHERE
+	loan_id = $1
+ORDER BY
+	accrued_on,
+	schedule_id,
+	kind`,
+		loanID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fees := []billing.LateFee{}
+	for rows.Next() {
+		var fee billing.LateFee
+		if err := rows.Scan(
+			&fee.ID,
+			&fee.LoanID,
+			&fee.ScheduleID,
+			&fee.Kind,
+			&fee.Amount,
+			&fee.AccruedOn,
+			&fee.Status,
+			&fee.WaivedAt,
+		); err != nil {
+			return nil, err
+		}
+		fees = append(fees, fee)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return fees, nil
+}
+
+// CreateLateFees records the fees and adds them onto their schedules in a single
+// transaction. A fee already accrued for the same schedule, kind and day is
+// skipped so concurrent runs do not charge twice, a fee on a schedule that is
+// already settled fails the whole batch.
+func (s *lateFeeStore) CreateLateFees(
+	ctx context.Context,
+	fees []billing.LateFee,
+) error {
+	return runInTx(ctx, s.db.Leader, nil, func(tx *sql.Tx) error {
+		return createLateFees(ctx, tx, fees)
+	})
+}
+
+func createLateFees(
+	ctx context.Context,
+	tx *sql.Tx,
+	fees []billing.LateFee,
+) error {
+	feeStmt, err := tx.PrepareContext(ctx, `
+	INSERT INTO late_fees(
+		id,
+		loan_id,
+		schedule_id,
+		kind,
+		amount,
+		accrued_on,
+		status
+	) VALUES ($1, $2, $3, $4, $5, $6, $7)
+	ON CONFLICT (schedule_id, kind, accrued_on) DO NOTHING`)
+	if err != nil {
+		return err
+	}
+	defer feeStmt.Close()
+
+	scheduleStmt, err := tx.PrepareContext(ctx, `
+	UPDATE
+		loan_schedules
+	SET
+		amount_due = jsonb_set(amount_due, '{value}', to_jsonb(CAST(amount_due->>'value' AS BIGINT) + $2)),
+		late_fee_due = jsonb_set(late_fee_due, '{value}', to_jsonb(CAST(late_fee_due->>'value' AS BIGINT) + $2))
+	WHERE
+		id = $1
+		AND status IN ('unpaid', 'partially_paid')`)
+	if err != nil {
+		return err
+	}
+	defer scheduleStmt.Close()
+
+	for _, fee := range fees {
+		result, err := feeStmt.ExecContext(
+			ctx,
+			fee.ID,
+			fee.LoanID,
+			fee.ScheduleID,
+			fee.Kind,
+			fee.Amount,
+			fee.AccruedOn,
+			fee.Status,
+		)
+		if err != nil {
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			continue
+		}
+
+		result, err = scheduleStmt.ExecContext(ctx, fee.ScheduleID, fee.Amount.Val)
+		if err != nil {
+			return err
+		}
+
+		// a settled schedule must not be charged, the fee would never be owed
+		affected, err = result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return billing.ErrLoanNotPayable
+		}
+	}
+
+	return nil
+}
+
+// WaiveLateFee marks the fee as waived and takes it off its schedule in a single
+// transaction. A fee is only waivable while its schedule still owes at least the
+// fee amount; the schedule is settled when the fee was all that was left on it.
+func (s *lateFeeStore) WaiveLateFee(
+	ctx context.Context,
+	fee *billing.LateFee,
+) error {
+	return runInTx(ctx, s.db.Leader, nil, func(tx *sql.Tx) error {
+		return waiveLateFee(ctx, tx, fee)
+	})
+}
+
+func waiveLateFee(
+	ctx context.Context,
+	tx *sql.Tx,
+	fee *billing.LateFee,
+) error {
+	result, err := tx.ExecContext(ctx, `
+UPDATE
+	late_fees
+SET
+	status = $2,
+	waived_at = $3
+WHERE
+	id = $1
+	AND status = 'accrued'`,
+		fee.ID,
+		fee.Status,
+		fee.WaivedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return billing.ErrLateFeeNotWaivable
+	}
+
+	// what the schedule owes is after its approved adjustments
+	amountDue := adjustedValue("loan_schedules", "amount_due")
+	result, err = tx.ExecContext(ctx, `
+UPDATE
+	loan_schedules
+SET
+	amount_due = jsonb_set(amount_due, '{value}', to_jsonb(CAST(amount_due->>'value' AS BIGINT) - $2)),
+	late_fee_due = jsonb_set(late_fee_due, '{value}', to_jsonb(CAST(late_fee_due->>'value' AS BIGINT) - $2)),
+	status = CASE
+		WHEN CAST(paid_amount->>'value' AS BIGINT) >= `+amountDue+` - $2 THEN 'paid'
+		ELSE status
+	END,
+	paid_at = CASE
+		WHEN CAST(paid_amount->>'value' AS BIGINT) >= `+amountDue+` - $2 THEN $3
+		ELSE paid_at
+	END
+WHERE
+	id = $1
+	AND status IN ('unpaid', 'partially_paid')
+	AND `+amountDue+` - CAST(paid_amount->>'value' AS BIGINT) >= $2`,
+		fee.ScheduleID,
+		fee.Amount.Val,
+		fee.WaivedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err = result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return billing.ErrLateFeeNotWaivable
+	}
+
+	return nil
+}
@@ -4,12 +4,15 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	billing "github.com/theyudiriski/billing-service/internal/service"
 )
 
 const (
+	// a loan is delinquent once it misses more than this many installments
 	delinquencyThreshold = 2
 )
 
@@ -25,127 +28,119 @@ func (s *loanStore) CreateLoan(
 	ctx context.Context,
 	loan *billing.Loan,
 ) error {
-	tx, err := s.db.Leader.BeginTx(ctx, nil)
-	if err != nil {
-		return err
-	}
-
-	_, err = tx.ExecContext(ctx, `
+	return runInTx(ctx, s.db.Leader, nil, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
 INSERT INTO loans(
 	id,
 	borrower_id,
 	principal_amount,
 	interest_rate,
+	interest_model,
+	fee_amount,
 	started_at,
 	ended_at,
 	payment_frequency,
-	total_payments
+	total_payments,
+	status,
+	product_id,
+	grace_days,
+	created_at
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
-		loan.ID,
-		loan.BorrowerID,
-		loan.PrincipalAmount,
-		loan.InterestRate,
-		loan.StartedAt,
-		loan.EndedAt,
-		loan.PaymentFrequency,
-		loan.TotalPayments,
-	)
-	if err != nil {
-		return err
-	}
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`,
+			loan.ID,
+			loan.BorrowerID,
+			loan.PrincipalAmount,
+			loan.InterestRate,
+			loan.InterestModel,
+			loan.FeeAmount,
+			loan.StartedAt,
+			loan.EndedAt,
+			loan.PaymentFrequency,
+			loan.TotalPayments,
+			loan.Status,
+			loan.ProductID,
+			loan.GraceDays,
+			loan.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		return insertSchedules(ctx, tx, loan.Schedules)
+	})
+}
 
+func insertSchedules(
+	ctx context.Context,
+	tx *sql.Tx,
+	schedules []billing.LoanSchedule,
+) error {
 	stmt, err := tx.PrepareContext(ctx, `
 	INSERT INTO loan_schedules(
 		id,
 		loan_id,
 		seq,
 		due_date,
-		amount_due
-	) VALUES ($1, $2, $3, $4, $5)`)
+		amount_due,
+		principal_due,
+		interest_due,
+		fee_due,
+		late_fee_due,
+		paid_amount,
+		version,
+		anchored_at
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`)
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
-	for i := 1; i <= loan.TotalPayments; i++ {
-		scheduleID := billing.UUID()
-
-		// calculate due date for each period
-		days := loan.LoanTermDays * i / loan.TotalPayments
-		dueDate := loan.StartedAt.AddDate(0, 0, days)
-
-		_, err := stmt.Exec(
-			scheduleID,
-			loan.ID,
-			i,
-			dueDate,
-			loan.TermAmount,
+	for _, schedule := range schedules {
+		_, err := stmt.ExecContext(
+			ctx,
+			schedule.ID,
+			schedule.LoanID,
+			schedule.Seq,
+			schedule.DueDate,
+			schedule.AmountDue,
+			schedule.PrincipalDue,
+			schedule.InterestDue,
+			schedule.FeeDue,
+			schedule.LateFeeDue,
+			schedule.PaidAmount,
+			schedule.Version,
+			schedule.AnchoredAt,
 		)
 		if err != nil {
 			return err
 		}
 	}
 
-	if err = tx.Commit(); err != nil {
-		return err
-	}
-
 	return nil
 }
 
 // GetOutstanding returns the total amount of outstanding payments for a loan.
-// It calculates the total amount due from unpaid loan schedules.
+// It calculates the amount left to pay from unsettled loan schedules.
 func (s *loanStore) GetOutstanding(
 	ctx context.Context,
 	loanID string,
-) (*billing.Amount, error) {
-	var totalDue float64
-	tx, err := s.db.Leader.BeginTx(ctx, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	// fetch total amount due from unpaid loans
-	row := tx.QueryRowContext(ctx, `
-SELECT 
-	COALESCE(
-		SUM(
-			CAST(amount_due->>'value' AS BIGINT) / 
-			POWER(10, CAST(amount_due->>'decimal_precision' AS INTEGER))
-		), 
-		0
-	) AS total_pending
-FROM 
-	loan_schedules
-WHERE 
-	loan_id = $1 
-	AND status = 'unpaid'`,
-		loanID,
-	)
-
-	if err := row.Scan(&totalDue); err != nil {
-		return nil, err
-	}
-
-	amount := billing.NewAmount(totalDue)
-	return &amount, nil
+) (*billing.AmountBreakdown, error) {
+	return sumRemaining(ctx, s.db.follower(ctx), loanID, false)
 }
 
+// IsDelinquent tells whether the loan misses more installments than the
+// threshold. Installments deferred by a running deferral are not missed, the
+// ones overdue before it still are.
 func (s *loanStore) IsDelinquent(ctx context.Context, loanID string) (bool, error) {
-	tx, err := s.db.Leader.BeginTx(ctx, nil)
-	if err != nil {
-		return false, err
-	}
-
-	rows, err := tx.QueryContext(ctx, `
+	rows, err := s.db.follower(ctx).QueryContext(ctx, `
 SELECT
 	due_date
 FROM
 	loan_schedules
 WHERE
 	loan_id = $1 AND
-	status = 'unpaid'
+	status IN ('unpaid', 'partially_paid') AND
+	NOT EXISTS (`+runningDeferral("loan_schedules.loan_id", "loan_schedules.due_date")+`)
 ORDER BY
 	due_date
     `, loanID)
@@ -166,129 +161,1075 @@ ORDER BY
 		return false, err
 	}
 
-	missedWeeks := calculateMissedWeeks(dueDates)
+	missedInstallments := calculateMissedInstallments(dueDates)
 
-	return missedWeeks > delinquencyThreshold, nil
+	return missedInstallments > delinquencyThreshold, nil
 }
 
-func calculateMissedWeeks(dueDates []time.Time) int {
+// runningDeferral selects the deferrals running now on the loan of the given
+// column that deferred the schedule due on the given column.
+func runningDeferral(loanIDColumn, dueDateColumn string) string {
+	return `
+		SELECT
+			1
+		FROM
+			loan_deferrals d
+		WHERE
+			d.loan_id = ` + loanIDColumn + `
+			AND d.deferred_from <= NOW()
+			AND d.deferred_until > NOW()
+			AND ` + dueDateColumn + ` >= d.deferred_from
+	`
+}
+
+// calculateMissedInstallments counts the unsettled installments already past
+// their due date, dueDates must be sorted ascending.
+func calculateMissedInstallments(dueDates []time.Time) int {
 	// current time
 	now := billing.CurrentLocalTime()
 
-	missedWeeks := 0
+	missedInstallments := 0
 	for i := range dueDates {
 		// TODO: checker should only care about the date, not the time,
 		// but both of them must be in the same timezone.
 		if now.After(dueDates[i]) {
-			missedWeeks++
+			missedInstallments++
 		} else {
 			break
 		}
 	}
 
-	return missedWeeks
+	return missedInstallments
 }
 
 // GetTotalPending returns the total amount of pending payments for a loan that are past due date.
-func (s *loanStore) GetTotalPending(ctx context.Context, loanID string) (*billing.Amount, error) {
-	var totalPending float64
-	tx, err := s.db.Leader.BeginTx(ctx, nil)
+func (s *loanStore) GetTotalPending(ctx context.Context, loanID string) (*billing.AmountBreakdown, error) {
+	return sumRemaining(ctx, s.db.follower(ctx), loanID, true)
+}
+
+// sumRemaining sums what is left to pay on the unsettled schedules of a loan,
+// split into its components with the approved adjustments taken off. Amounts are
+// summed in minor units; schedules always share the currency and precision of
+// the principal. What has been paid on a schedule settles its late fee first,
+// then fee, then interest, then principal.
+func sumRemaining(
+	ctx context.Context,
+	db executor,
+	loanID string,
+	pastDueOnly bool,
+) (*billing.AmountBreakdown, error) {
+	dueFilter := ""
+	if pastDueOnly {
+		dueFilter = "AND ls.due_date < NOW()"
+	}
+
+	row := db.QueryRowContext(ctx, `
+WITH schedules AS (
+	SELECT
+		`+adjustedValue("ls", "principal_due", billing.AdjustmentTypeReduceInstallment)+` AS principal,
+		`+adjustedValue("ls", "interest_due", billing.AdjustmentTypeWriteOffInterest)+` AS interest,
+		`+adjustedValue("ls", "fee_due", billing.AdjustmentTypeWaiveFee)+` AS fee,
+		CAST(ls.late_fee_due->>'value' AS BIGINT) AS late_fee,
+		CAST(ls.paid_amount->>'value' AS BIGINT) AS paid
+	FROM
+		loan_schedules ls
+	WHERE
+		ls.loan_id = $1
+		AND ls.status IN ('unpaid', 'partially_paid')
+		`+dueFilter+`
+)
+SELECT
+	COALESCE((SELECT SUM(principal - GREATEST(paid - late_fee - fee - interest, 0)) FROM schedules), 0),
+	COALESCE((SELECT SUM(GREATEST(interest - GREATEST(paid - late_fee - fee, 0), 0)) FROM schedules), 0),
+	COALESCE((SELECT SUM(GREATEST(fee - GREATEST(paid - late_fee, 0), 0)) FROM schedules), 0),
+	COALESCE((SELECT SUM(GREATEST(late_fee - paid, 0)) FROM schedules), 0),
+	CAST(l.principal_amount->>'decimal_precision' AS INTEGER),
+	l.principal_amount->>'currency'
+FROM
+	loans l
+WHERE
+	l.id = $1`,
+		loanID,
+	)
+
+	var principal, interest, fee, lateFee billing.Amount
+	if err := row.Scan(
+		&principal.Val,
+		&interest.Val,
+		&fee.Val,
+		&lateFee.Val,
+		&principal.DecimalPrecision,
+		&principal.Currency,
+	); err != nil {
+		return nil, err
+	}
+
+	for _, component := range []*billing.Amount{&interest, &fee, &lateFee} {
+		component.DecimalPrecision, component.Currency = principal.DecimalPrecision, principal.Currency
+	}
+
+	return &billing.AmountBreakdown{
+		Principal: principal,
+		Interest:  interest,
+		Fee:       fee,
+		LateFee:   lateFee,
+	}, nil
+}
+
+func (s *loanStore) GetLoanByID(ctx context.Context, loanID string) (*billing.Loan, error) {
+	row := s.db.follower(ctx).QueryRowContext(ctx, `
+SELECT
+	`+loanColumns+`
+FROM
+	loans
+WHERE
+	id = $1`,
+		loanID,
+	)
+
+	loan, err := scanLoan(row)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, billing.ErrLoanNotFound
+		}
 		return nil, err
 	}
 
-	row := tx.QueryRowContext(ctx, `
-SELECT 
-	COALESCE(
-		SUM(
-			CAST(amount_due->>'value' AS BIGINT) / 
-			POWER(10, CAST(amount_due->>'decimal_precision' AS INTEGER))
-		), 
-		0
-	) AS total_pending
-FROM 
+	return loan, nil
+}
+
+// GetLoanByIDForUpdate returns the loan and locks it until the transaction in
+// ctx ends, so concurrent writers to the loan queue up behind each other.
+func (s *loanStore) GetLoanByIDForUpdate(ctx context.Context, loanID string) (*billing.Loan, error) {
+	row := s.db.leader(ctx).QueryRowContext(ctx, `
+SELECT
+	`+loanColumns+`
+FROM
+	loans
+WHERE
+	id = $1
+FOR UPDATE`,
+		loanID,
+	)
+
+	loan, err := scanLoan(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, billing.ErrLoanNotFound
+		}
+		return nil, err
+	}
+
+	return loan, nil
+}
+
+// GetUnsettledSchedules returns the schedules of a loan that are not fully paid,
+// oldest due first. The schedules stay locked until the transaction in ctx ends.
+func (s *loanStore) GetUnsettledSchedules(
+	ctx context.Context,
+	loanID string,
+) ([]billing.LoanSchedule, error) {
+	rows, err := s.db.leader(ctx).QueryContext(ctx, `
+SELECT
+	`+scheduleColumns+`
+FROM
 	loan_schedules
-WHERE 
-	loan_id = $1 
-	AND status = 'unpaid'
-	AND due_date < NOW()`,
+WHERE
+	loan_id = $1
+	AND status IN ('unpaid', 'partially_paid')
+ORDER BY
+	due_date,
+	seq
+FOR UPDATE`,
 		loanID,
 	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []billing.LoanSchedule
+	for rows.Next() {
+		schedule, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return schedules, nil
+}
+
+// ListSchedules returns the schedules of a loan matching the filter, ordered by
+// sequence then version.
+func (s *loanStore) ListSchedules(
+	ctx context.Context,
+	loanID string,
+	filter billing.LoanScheduleFilter,
+) ([]billing.LoanSchedule, error) {
+	query := `
+SELECT
+	` + scheduleColumns + `
+FROM
+	loan_schedules
+WHERE
+	loan_id = $1`
+	args := []any{loanID}
 
-	err = row.Scan(&totalPending)
+	if len(filter.Statuses) > 0 {
+		statuses := make([]string, 0, len(filter.Statuses))
+		for _, status := range filter.Statuses {
+			statuses = append(statuses, string(status))
+		}
+		args = append(args, statuses)
+		query += fmt.Sprintf("\n\tAND status = ANY($%d)", len(args))
+	}
+
+	if filter.DueFrom != nil {
+		args = append(args, *filter.DueFrom)
+		query += fmt.Sprintf("\n\tAND due_date >= $%d", len(args))
+	}
+
+	if filter.DueTo != nil {
+		args = append(args, *filter.DueTo)
+		query += fmt.Sprintf("\n\tAND due_date < $%d", len(args))
+	}
+
+	query += `
+ORDER BY
+	seq,
+	version`
+
+	rows, err := s.db.follower(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+
+	schedules := []billing.LoanSchedule{}
+	for rows.Next() {
+		schedule, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	amount := billing.NewAmount(totalPending)
-	return &amount, nil
+	return schedules, nil
 }
 
-func (s *loanStore) MarkPendingAsPaid(ctx context.Context, loanID string) error {
-	tx, err := s.db.Leader.BeginTx(ctx, nil)
+// HasPendingAdjustments tells whether the loan has adjustments waiting for
+// approval.
+func (s *loanStore) HasPendingAdjustments(ctx context.Context, loanID string) (bool, error) {
+	var pending bool
+	err := s.db.leader(ctx).QueryRowContext(ctx, `
+SELECT EXISTS (
+	SELECT
+		1
+	FROM
+		loan_adjustments
+	WHERE
+		loan_id = $1
+		AND status = 'pending_approval'
+)`,
+		loanID,
+	).Scan(&pending)
+	return pending, err
+}
+
+// UpdateLoanStatus moves the loan from one status to another, failing when the
+// loan is no longer in the expected status.
+func (s *loanStore) UpdateLoanStatus(
+	ctx context.Context,
+	loanID string,
+	from, to billing.LoanStatus,
+) error {
+	result, err := s.db.leader(ctx).ExecContext(ctx, `
+UPDATE
+	loans
+SET
+	status = $3
+WHERE
+	id = $1
+	AND status = $2`,
+		loanID,
+		from,
+		to,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
 
-	_, err = tx.ExecContext(ctx, `
-UPDATE 
+	if affected == 0 {
+		return billing.ErrInvalidLoanStatusTransition
+	}
+
+	return nil
+}
+
+// DisburseLoan activates a loan pending disbursement, recording its
+// disbursement and moving its dates, schedule due dates and schedule amounts to
+// the ones on the loan.
+func (s *loanStore) DisburseLoan(
+	ctx context.Context,
+	loan *billing.Loan,
+) error {
+	return runInTx(ctx, s.db.Leader, nil, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+UPDATE
+	loans
+SET
+	status = $3,
+	started_at = $4,
+	ended_at = $5,
+	disbursed_amount = $6,
+	disbursement_reference = $7,
+	disbursed_at = $8
+WHERE
+	id = $1
+	AND status = $2`,
+			loan.ID,
+			billing.LoanStatusPendingDisbursement,
+			loan.Status,
+			loan.StartedAt,
+			loan.EndedAt,
+			loan.Disbursement.Amount,
+			loan.Disbursement.Reference,
+			loan.Disbursement.DisbursedAt,
+		)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.ConstraintName == "uq_disbursement_reference" {
+				return billing.ErrDisbursementReferenceConflict
+			}
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return billing.ErrLoanNotPendingDisbursement
+		}
+
+		stmt, err := tx.PrepareContext(ctx, `
+	UPDATE
+		loan_schedules
+	SET
+		due_date = $2,
+		amount_due = $3,
+		principal_due = $4,
+		interest_due = $5,
+		fee_due = $6,
+		anchored_at = $8
+	WHERE
+		id = $1
+		AND loan_id = $7`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		for _, schedule := range loan.Schedules {
+			result, err := stmt.ExecContext(
+				ctx,
+				schedule.ID,
+				schedule.DueDate,
+				schedule.AmountDue,
+				schedule.PrincipalDue,
+				schedule.InterestDue,
+				schedule.FeeDue,
+				loan.ID,
+				schedule.AnchoredAt,
+			)
+			if err != nil {
+				return err
+			}
+
+			affected, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if affected == 0 {
+				return billing.ErrLoanNotPendingDisbursement
+			}
+		}
+
+		return nil
+	})
+}
+
+// CancelLoan moves the loan from the given status to cancelled, recording its
+// cancellation, and voids all of its schedules.
+func (s *loanStore) CancelLoan(
+	ctx context.Context,
+	loan *billing.Loan,
+	from billing.LoanStatus,
+) error {
+	return runInTx(ctx, s.db.Leader, nil, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+UPDATE
+	loans
+SET
+	status = $3,
+	cancellation_reason = $4,
+	cancelled_at = $5,
+	principal_to_return = $6,
+	refund_amount = $7
+WHERE
+	id = $1
+	AND status = $2`,
+			loan.ID,
+			from,
+			loan.Status,
+			loan.Cancellation.Reason,
+			loan.Cancellation.CancelledAt,
+			loan.Cancellation.PrincipalToReturn,
+			loan.Cancellation.RefundAmount,
+		)
+		if err != nil {
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return billing.ErrInvalidLoanStatusTransition
+		}
+
+		_, err = tx.ExecContext(ctx, `
+UPDATE
 	loan_schedules
-SET 
-	status = 'paid'
-WHERE 
-	loan_id = $1 
-	AND status = 'unpaid'
-	AND due_date < NOW()`,
-		loanID,
+SET
+	status = $2
+WHERE
+	loan_id = $1`,
+			loan.ID,
+			billing.LoanScheduleStatusVoid,
+		)
+		return err
+	})
+}
+
+// WriteOffLoan moves the loan from the given status to written off, recording
+// its write-off. Its schedules are kept as they are.
+func (s *loanStore) WriteOffLoan(
+	ctx context.Context,
+	loan *billing.Loan,
+	from billing.LoanStatus,
+) error {
+	result, err := s.db.leader(ctx).ExecContext(ctx, `
+UPDATE
+	loans
+SET
+	status = $3,
+	write_off_reason = $4,
+	written_off_at = $5,
+	written_off_amount = $6
+WHERE
+	id = $1
+	AND status = $2`,
+		loan.ID,
+		from,
+		loan.Status,
+		loan.WriteOff.Reason,
+		loan.WriteOff.WrittenOffAt,
+		loan.WriteOff.Amount,
 	)
 	if err != nil {
 		return err
 	}
 
-	if err = tx.Commit(); err != nil {
+	affected, err := result.RowsAffected()
+	if err != nil {
 		return err
 	}
+	if affected == 0 {
+		return billing.ErrInvalidLoanStatusTransition
+	}
 
 	return nil
 }
 
-func (s *loanStore) GetLoanByID(ctx context.Context, loanID string) (*billing.Loan, error) {
-	tx, err := s.db.Leader.BeginTx(ctx, nil)
+// RestructureLoan records the restructure, supersedes the unsettled schedules of
+// the loan with the restructure schedules and moves the loan from the given
+// status to the one on the loan, along with its end date and number of
+// payments.
+func (s *loanStore) RestructureLoan(
+	ctx context.Context,
+	loan *billing.Loan,
+	restructure *billing.LoanRestructure,
+	from billing.LoanStatus,
+) error {
+	return runInTx(ctx, s.db.Leader, nil, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+UPDATE
+	loans
+SET
+	status = $3,
+	ended_at = $4,
+	total_payments = $5
+WHERE
+	id = $1
+	AND status = $2`,
+			loan.ID,
+			from,
+			loan.Status,
+			loan.EndedAt,
+			loan.TotalPayments,
+		)
+		if err != nil {
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return billing.ErrInvalidLoanStatusTransition
+		}
+
+		_, err = tx.ExecContext(ctx, `
+INSERT INTO loan_restructures(
+	id,
+	loan_id,
+	version,
+	extend_payments,
+	holiday_payments,
+	capitalise_arrears,
+	outstanding,
+	arrears,
+	capitalised,
+	reason,
+	requested_by,
+	approved_by,
+	created_at
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+			restructure.ID,
+			restructure.LoanID,
+			restructure.Version,
+			restructure.ExtendPayments,
+			restructure.HolidayPayments,
+			restructure.CapitaliseArrears,
+			restructure.Outstanding,
+			restructure.Arrears,
+			restructure.Capitalised,
+			restructure.Reason,
+			restructure.RequestedBy,
+			restructure.ApprovedBy,
+			restructure.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+UPDATE
+	loan_schedules
+SET
+	status = $3
+WHERE
+	loan_id = $1
+	AND version < $2
+	AND status IN ('unpaid', 'partially_paid')`,
+			loan.ID,
+			restructure.Version,
+			billing.LoanScheduleStatusSuperseded,
+		)
+		if err != nil {
+			return err
+		}
+
+		return insertSchedules(ctx, tx, restructure.Schedules)
+	})
+}
+
+// DeferInstallments records the deferral and moves the deferred schedules to
+// their new due dates, along with the end date of the loan. The deferral
+// interest is on the last deferred schedule.
+func (s *loanStore) DeferInstallments(
+	ctx context.Context,
+	loan *billing.Loan,
+	deferral *billing.LoanDeferral,
+) error {
+	return runInTx(ctx, s.db.Leader, nil, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+INSERT INTO loan_deferrals(
+	id,
+	loan_id,
+	installments,
+	interest_rate,
+	interest,
+	reason,
+	requested_by,
+	deferred_from,
+	deferred_until,
+	created_at
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+			deferral.ID,
+			deferral.LoanID,
+			deferral.Installments,
+			deferral.InterestRate,
+			deferral.Interest,
+			deferral.Reason,
+			deferral.RequestedBy,
+			deferral.DeferredFrom,
+			deferral.DeferredUntil,
+			deferral.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		stmt, err := tx.PrepareContext(ctx, `
+	UPDATE
+		loan_schedules
+	SET
+		due_date = $2
+	WHERE
+		id = $1
+		AND status IN ('unpaid', 'partially_paid')`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		for _, schedule := range deferral.Schedules {
+			result, err := stmt.ExecContext(ctx, schedule.ID, schedule.DueDate)
+			if err != nil {
+				return err
+			}
+
+			affected, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if affected == 0 {
+				return billing.ErrLoanNotDeferrable
+			}
+		}
+
+		// added onto the schedule rather than set from it, the schedule read
+		// has its approved adjustments taken off
+		last := deferral.Schedules[len(deferral.Schedules)-1]
+		_, err = tx.ExecContext(ctx, `
+UPDATE
+	loan_schedules
+SET
+	interest_due = jsonb_set(interest_due, '{value}', to_jsonb(CAST(interest_due->>'value' AS BIGINT) + $2)),
+	amount_due = jsonb_set(amount_due, '{value}', to_jsonb(CAST(amount_due->>'value' AS BIGINT) + $2))
+WHERE
+	id = $1`,
+			last.ID,
+			deferral.Interest.Val,
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+UPDATE
+	loans
+SET
+	ended_at = $2
+WHERE
+	id = $1`,
+			loan.ID,
+			loan.EndedAt,
+		)
+		return err
+	})
+}
+
+// ListLoansByBorrowerID returns the loans of a borrower matching the filter,
+// newest first, starting after filter.After.
+func (s *loanStore) ListLoansByBorrowerID(
+	ctx context.Context,
+	borrowerID string,
+	filter billing.BorrowerLoanFilter,
+) ([]billing.Loan, error) {
+	query := `
+SELECT
+	` + loanColumns + `
+FROM
+	loans
+WHERE
+	borrower_id = $1`
+	args := []any{borrowerID}
+
+	if len(filter.Statuses) > 0 {
+		statuses := make([]string, 0, len(filter.Statuses))
+		for _, status := range filter.Statuses {
+			statuses = append(statuses, string(status))
+		}
+		args = append(args, statuses)
+		query += fmt.Sprintf("\n\tAND status = ANY($%d)", len(args))
+	}
+
+	if filter.After != nil {
+		args = append(args, filter.After.CreatedAt, filter.After.ID)
+		query += fmt.Sprintf("\n\tAND (created_at, id) < ($%d, $%d)", len(args)-1, len(args))
+	}
+
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(`
+ORDER BY
+	created_at DESC,
+	id DESC
+LIMIT $%d`, len(args))
+
+	rows, err := s.db.follower(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+
+	loans := []billing.Loan{}
+	for rows.Next() {
+		loan, err := scanLoan(rows)
+		if err != nil {
+			return nil, err
+		}
+		loans = append(loans, *loan)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return loans, nil
+}
+
+// GetBorrowerExposure sums per currency what is left to pay on the loans of a
+// borrower that are not closed, with the same settlement order as sumRemaining,
+// and finds the loan with the oldest installment past due.
+func (s *loanStore) GetBorrowerExposure(
+	ctx context.Context,
+	borrowerID string,
+) (*billing.BorrowerExposure, error) {
+	db := s.db.follower(ctx)
+
+	exposure := &billing.BorrowerExposure{
+		Currencies: []billing.CurrencyExposure{},
+	}
 
-	l := &billing.Loan{}
-	row := tx.QueryRowContext(ctx, `
+	// a loan is delinquent as in IsDelinquent, once it misses more installments
+	// than the threshold, deferred ones aside; loans pending disbursement count
+	// towards the borrower's loans but have nothing due yet
+	if err := db.QueryRowContext(ctx, `
+WITH loan_missed AS (
+	SELECT
+		l.id,
+		COUNT(ls.id) FILTER (
+			WHERE l.status <> 'pending_disbursement'
+			AND ls.status IN ('unpaid', 'partially_paid')
+			AND ls.due_date < NOW()
+			AND NOT EXISTS (`+runningDeferral("l.id", "ls.due_date")+`)
+		) AS missed
+	FROM
+		loans l
+		LEFT JOIN loan_schedules ls ON ls.loan_id = l.id
+	WHERE
+		l.borrower_id = $1
+		AND l.status IN ('pending_disbursement', 'active', 'delinquent')
+	GROUP BY
+		l.id
+)
 SELECT
-	id,
+	COUNT(*),
+	COUNT(*) FILTER (WHERE missed > $2)
+FROM
+	loan_missed`,
+		borrowerID,
+		delinquencyThreshold,
+	).Scan(
+		&exposure.ActiveLoans,
+		&exposure.DelinquentLoans,
+	); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, `
+WITH schedules AS (
+	SELECT
+		l.principal_amount->>'currency' AS currency,
+		CAST(l.principal_amount->>'decimal_precision' AS INTEGER) AS decimal_precision,
+		l.status = 'pending_disbursement' AS pending,
+		ls.due_date,
+		`+adjustedValue("ls", "principal_due", billing.AdjustmentTypeReduceInstallment)+` AS principal,
+		`+adjustedValue("ls", "interest_due", billing.AdjustmentTypeWriteOffInterest)+` AS interest,
+		`+adjustedValue("ls", "fee_due", billing.AdjustmentTypeWaiveFee)+` AS fee,
+		CAST(ls.late_fee_due->>'value' AS BIGINT) AS late_fee,
+		CAST(ls.paid_amount->>'value' AS BIGINT) AS paid
+	FROM
+		loans l
+		JOIN loan_schedules ls ON ls.loan_id = l.id
+	WHERE
+		l.borrower_id = $1
+		AND l.status IN ('pending_disbursement', 'active', 'delinquent')
+		AND ls.status IN ('unpaid', 'partially_paid')
+)
+SELECT
+	currency,
+	decimal_precision,
+	SUM(principal - GREATEST(paid - late_fee - fee - interest, 0)),
+	COALESCE(SUM(principal + interest + fee + late_fee - paid) FILTER (WHERE NOT pending AND due_date < NOW()), 0)
+FROM
+	schedules
+GROUP BY
+	currency,
+	decimal_precision
+ORDER BY
+	currency`,
+		borrowerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var principal, overdue billing.Amount
+		if err := rows.Scan(
+			&principal.Currency,
+			&principal.DecimalPrecision,
+			&principal.Val,
+			&overdue.Val,
+		); err != nil {
+			return nil, err
+		}
+		overdue.DecimalPrecision, overdue.Currency = principal.DecimalPrecision, principal.Currency
+
+		// loans stored under an older precision of the currency are summed apart,
+		// they join the others once in the precision the currency has now
+		if principal, err = principal.Normalized(); err != nil {
+			return nil, err
+		}
+		if overdue, err = overdue.Normalized(); err != nil {
+			return nil, err
+		}
+
+		if n := len(exposure.Currencies); n > 0 {
+			if last := &exposure.Currencies[n-1]; last.PrincipalOutstanding.Currency == principal.Currency {
+				last.PrincipalOutstanding = last.PrincipalOutstanding.Add(principal)
+				last.Overdue = last.Overdue.Add(overdue)
+				continue
+			}
+		}
+
+		exposure.Currencies = append(exposure.Currencies, billing.CurrencyExposure{
+			PrincipalOutstanding: principal,
+			Overdue:              overdue,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// the oldest missed installment decides, more missed installments break ties
+	var worst billing.LoanDelinquency
+	err = db.QueryRowContext(ctx, `
+SELECT
+	l.id,
+	l.status,
+	MIN(ls.due_date),
+	COUNT(*)
+FROM
+	loans l
+	JOIN loan_schedules ls ON ls.loan_id = l.id
+WHERE
+	l.borrower_id = $1
+	AND l.status IN ('active', 'delinquent')
+	AND ls.status IN ('unpaid', 'partially_paid')
+	AND ls.due_date < NOW()
+GROUP BY
+	l.id,
+	l.status
+ORDER BY
+	MIN(ls.due_date),
+	COUNT(*) DESC,
+	l.id
+LIMIT 1`,
+		borrowerID,
+	).Scan(
+		&worst.LoanID,
+		&worst.Status,
+		&worst.OldestDueDate,
+		&worst.MissedInstallments,
+	)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return nil, err
+	default:
+		exposure.WorstDelinquency = &worst
+	}
+
+	return exposure, nil
+}
+
+const loanColumns = `id,
 	borrower_id,
 	principal_amount,
 	interest_rate,
+	interest_model,
+	fee_amount,
 	started_at,
 	ended_at,
 	payment_frequency,
-	total_payments
-FROM
-	loans
-WHERE
-	id = $1`,
-		loanID)
-	if err = row.Scan(
+	total_payments,
+	status,
+	product_id,
+	grace_days,
+	disbursed_amount,
+	disbursement_reference,
+	disbursed_at,
+	cancellation_reason,
+	cancelled_at,
+	principal_to_return,
+	refund_amount,
+	write_off_reason,
+	written_off_at,
+	written_off_amount,
+	created_at`
+
+// scanner is satisfied by both *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanLoan reads a loan row selected with loanColumns.
+func scanLoan(row scanner) (*billing.Loan, error) {
+	var (
+		l                     = &billing.Loan{}
+		disbursedAmount       []byte
+		disbursementReference sql.NullString
+		disbursedAt           sql.NullTime
+		cancellationReason    sql.NullString
+		cancelledAt           sql.NullTime
+		principalToReturn     []byte
+		refundAmount          []byte
+		writeOffReason        sql.NullString
+		writtenOffAt          sql.NullTime
+		writtenOffAmount      []byte
+	)
+	err := row.Scan(
 		&l.ID,
 		&l.BorrowerID,
 		&l.PrincipalAmount,
 		&l.InterestRate,
+		&l.InterestModel,
+		&l.FeeAmount,
 		&l.StartedAt,
 		&l.EndedAt,
 		&l.PaymentFrequency,
 		&l.TotalPayments,
-	); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, billing.ErrLoanNotFound
-		}
+		&l.Status,
+		&l.ProductID,
+		&l.GraceDays,
+		&disbursedAmount,
+		&disbursementReference,
+		&disbursedAt,
+		&cancellationReason,
+		&cancelledAt,
+		&principalToReturn,
+		&refundAmount,
+		&writeOffReason,
+		&writtenOffAt,
+		&writtenOffAmount,
+		&l.CreatedAt,
+	)
+	if err != nil {
 		return nil, err
 	}
 
+	if disbursedAt.Valid {
+		l.Disbursement = &billing.LoanDisbursement{
+			Reference:   disbursementReference.String,
+			DisbursedAt: disbursedAt.Time,
+		}
+		if err := l.Disbursement.Amount.Scan(disbursedAmount); err != nil {
+			return nil, err
+		}
+	}
+
+	if cancelledAt.Valid {
+		l.Cancellation = &billing.LoanCancellation{
+			Reason:      cancellationReason.String,
+			CancelledAt: cancelledAt.Time,
+		}
+		if err := l.Cancellation.PrincipalToReturn.Scan(principalToReturn); err != nil {
+			return nil, err
+		}
+		if err := l.Cancellation.RefundAmount.Scan(refundAmount); err != nil {
+			return nil, err
+		}
+	}
+
+	if writtenOffAt.Valid {
+		l.WriteOff = &billing.LoanWriteOff{
+			Reason:       writeOffReason.String,
+			WrittenOffAt: writtenOffAt.Time,
+		}
+		if err := l.WriteOff.Amount.Scan(writtenOffAmount); err != nil {
+			return nil, err
+		}
+	}
+
 	return l, nil
 }
+
+// scheduleColumns selects a schedule of loan_schedules with its approved
+// adjustments taken off its amounts.
+var scheduleColumns = `id,
+	loan_id,
+	seq,
+	due_date,
+	` + adjustedAmount("loan_schedules", "amount_due") + `,
+	` + adjustedAmount("loan_schedules", "principal_due", billing.AdjustmentTypeReduceInstallment) + `,
+	` + adjustedAmount("loan_schedules", "interest_due", billing.AdjustmentTypeWriteOffInterest) + `,
+	` + adjustedAmount("loan_schedules", "fee_due", billing.AdjustmentTypeWaiveFee) + `,
+	late_fee_due,
+	paid_amount,
+	status,
+	paid_at,
+	version,
+	anchored_at`
+
+// scanSchedule reads a schedule row selected with scheduleColumns.
+func scanSchedule(rows *sql.Rows) (billing.LoanSchedule, error) {
+	var schedule billing.LoanSchedule
+	err := rows.Scan(
+		&schedule.ID,
+		&schedule.LoanID,
+		&schedule.Seq,
+		&schedule.DueDate,
+		&schedule.AmountDue,
+		&schedule.PrincipalDue,
+		&schedule.InterestDue,
+		&schedule.FeeDue,
+		&schedule.LateFeeDue,
+		&schedule.PaidAmount,
+		&schedule.Status,
+		&schedule.PaidAt,
+		&schedule.Version,
+		&schedule.AnchoredAt,
+	)
+	return schedule, err
+}
@@ -0,0 +1,307 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID keys the advisory lock held while migrating, so migrators
+// started together run one after another instead of applying a version twice.
+const migrationLockID = 4_150_716_001
+
+// migrationFileName matches <version>_<name>.<up|down>.sql
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type migration struct {
+	version int
+	name    string
+	up      string
+	down    string
+}
+
+// MigrationStatus is a known migration and when it was applied, AppliedAt is
+// nil while it is pending.
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+// Migrator applies the migrations embedded in the binary to the leader. Applied
+// versions are recorded in schema_migrations.
+type Migrator struct {
+	db         *sql.DB
+	migrations []migration
+}
+
+func NewMigrator(db *Client) (*Migrator, error) {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db.Leader,
+		migrations: migrations,
+	}, nil
+}
+
+// loadMigrations reads the up and down scripts of every version, ordered by
+// version.
+func loadMigrations(files fs.FS) ([]migration, error) {
+	paths, err := fs.Glob(files, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*migration{}
+	for _, path := range paths {
+		name := path[len("migrations/"):]
+		match := migrationFileName.FindStringSubmatch(name)
+		if match == nil {
+			return nil, fmt.Errorf("migration %s should be named <version>_<name>.<up|down>.sql", name)
+		}
+
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", name, err)
+		}
+
+		script, err := fs.ReadFile(files, path)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{version: version, name: match[2]}
+			byVersion[version] = m
+		}
+		if m.name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %s and %s", version, m.name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.up = string(script)
+		} else {
+			m.down = string(script)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("migration %d_%s should have both an up and a down script", m.version, m.name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+
+	return migrations, nil
+}
+
+// Up applies every pending migration in version order, each in its own
+// transaction, and returns the ones applied.
+func (m *Migrator) Up(ctx context.Context) ([]MigrationStatus, error) {
+	var applied []MigrationStatus
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		appliedAt, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := appliedAt[migration.version]; ok {
+				continue
+			}
+
+			now := time.Now()
+			if err := runMigration(ctx, conn, migration.up, `
+INSERT INTO schema_migrations(
+	version,
+	name,
+	applied_at
+)
+VALUES ($1, $2, $3)`,
+				migration.version,
+				migration.name,
+				now,
+			); err != nil {
+				return fmt.Errorf("migration %d_%s up: %w", migration.version, migration.name, err)
+			}
+
+			applied = append(applied, MigrationStatus{
+				Version:   migration.version,
+				Name:      migration.name,
+				AppliedAt: &now,
+			})
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down reverts the latest applied migrations, newest first, up to steps of them,
+// and returns the ones reverted.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]MigrationStatus, error) {
+	var reverted []MigrationStatus
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		appliedAt, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := appliedAt[migration.version]; !ok {
+				continue
+			}
+
+			if err := runMigration(ctx, conn, migration.down, `
+DELETE FROM
+	schema_migrations
+WHERE
+	version = $1`,
+				migration.version,
+			); err != nil {
+				return fmt.Errorf("migration %d_%s down: %w", migration.version, migration.name, err)
+			}
+
+			reverted = append(reverted, MigrationStatus{
+				Version: migration.version,
+				Name:    migration.name,
+			})
+		}
+
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Status lists every known migration in version order with when it was applied.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		appliedAt, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			status := MigrationStatus{
+				Version: migration.version,
+				Name:    migration.name,
+			}
+			if at, ok := appliedAt[migration.version]; ok {
+				status.AppliedAt = &at
+			}
+			statuses = append(statuses, status)
+		}
+
+		return nil
+	})
+
+	return statuses, err
+}
+
+// withLock runs fn on a single connection holding the migration lock, with the
+// schema_migrations table in place. The advisory lock belongs to the session,
+// so everything in fn must go through conn.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+
+	if _, err := conn.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version             BIGINT          NOT NULL,
+    name                VARCHAR(100)    NOT NULL,
+    applied_at          TIMESTAMPTZ     NOT NULL,
+
+    PRIMARY KEY (version)
+)`); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+// appliedVersions returns when each applied version was applied.
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, `
+SELECT
+	version,
+	applied_at
+FROM
+	schema_migrations`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	appliedAt := map[int]time.Time{}
+	for rows.Next() {
+		var (
+			version int
+			at      time.Time
+		)
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		appliedAt[version] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return appliedAt, nil
+}
+
+// runMigration runs the script and its bookkeeping statement in one transaction,
+// a failing script leaves neither the schema nor schema_migrations changed.
+func runMigration(
+	ctx context.Context,
+	conn *sql.Conn,
+	script string,
+	bookkeeping string,
+	args ...any,
+) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, bookkeeping, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
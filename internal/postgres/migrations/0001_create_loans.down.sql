@@ -0,0 +1,2 @@
+DROP TABLE loan_schedules;
+DROP TABLE loans;
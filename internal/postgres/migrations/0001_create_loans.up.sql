@@ -1,4 +1,6 @@
-CREATE TABLE loans (
+-- the tables as they were first created from docs/create_table.sql, databases
+-- set up from it before migrations existed already have them
+CREATE TABLE IF NOT EXISTS loans (
     id                  VARCHAR(36)     NOT NULL,
     borrower_id         VARCHAR(36)     NOT NULL,
     principal_amount    JSONB           NOT NULL,
@@ -12,7 +14,7 @@ CREATE TABLE loans (
     PRIMARY KEY (id)
 );
 
-CREATE TABLE loan_schedules (
+CREATE TABLE IF NOT EXISTS loan_schedules (
     id                  VARCHAR(36)     NOT NULL,
     loan_id             VARCHAR(36)     NOT NULL,
     seq                 INT             NOT NULL,
@@ -25,4 +27,4 @@ CREATE TABLE loan_schedules (
         FOREIGN KEY(loan_id) 
 	    REFERENCES loans(id)
         ON DELETE CASCADE
-);
\ No newline at end of file
+);
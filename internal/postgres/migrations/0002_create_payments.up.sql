@@ -0,0 +1,32 @@
+CREATE TABLE payments (
+    id                  VARCHAR(36)     NOT NULL,
+    loan_id             VARCHAR(36)     NOT NULL,
+    amount              JSONB           NOT NULL,
+    unapplied_amount    JSONB           NOT NULL,
+    channel             VARCHAR(50)     NOT NULL,
+    external_reference  VARCHAR(100)    NOT NULL DEFAULT '',
+    paid_at             TIMESTAMPTZ     NOT NULL,
+
+    PRIMARY KEY (id),
+    CONSTRAINT fk_loan_id
+        FOREIGN KEY(loan_id) 
+	    REFERENCES loans(id)
+        ON DELETE CASCADE
+);
+
+CREATE TABLE payment_allocations (
+    payment_id          VARCHAR(36)     NOT NULL,
+    schedule_id         VARCHAR(36)     NOT NULL,
+    amount              JSONB           NOT NULL,
+    schedule_status     VARCHAR(20)     NOT NULL,
+
+    PRIMARY KEY (payment_id, schedule_id),
+    CONSTRAINT fk_payment_id
+        FOREIGN KEY(payment_id) 
+	    REFERENCES payments(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_schedule_id
+        FOREIGN KEY(schedule_id) 
+	    REFERENCES loan_schedules(id)
+        ON DELETE CASCADE
+);
@@ -0,0 +1 @@
+DROP TABLE late_fees;
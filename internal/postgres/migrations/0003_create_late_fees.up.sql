@@ -0,0 +1,22 @@
+CREATE TABLE late_fees (
+    id                  VARCHAR(36)     NOT NULL,
+    loan_id             VARCHAR(36)     NOT NULL,
+    schedule_id         VARCHAR(36)     NOT NULL,
+    kind                VARCHAR(20)     NOT NULL,
+    amount              JSONB           NOT NULL,
+    accrued_on          DATE            NOT NULL,
+    status              VARCHAR(20)     NOT NULL DEFAULT 'accrued',
+    waived_at           TIMESTAMPTZ,
+
+    PRIMARY KEY (id),
+    CONSTRAINT uq_late_fee_schedule_kind_day
+        UNIQUE (schedule_id, kind, accrued_on),
+    CONSTRAINT fk_loan_id
+        FOREIGN KEY(loan_id) 
+	    REFERENCES loans(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_schedule_id
+        FOREIGN KEY(schedule_id) 
+	    REFERENCES loan_schedules(id)
+        ON DELETE CASCADE
+);
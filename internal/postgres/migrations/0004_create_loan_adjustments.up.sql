@@ -0,0 +1,27 @@
+CREATE TABLE loan_adjustments (
+    id                  VARCHAR(36)     NOT NULL,
+    loan_id             VARCHAR(36)     NOT NULL,
+    schedule_id         VARCHAR(36)     NOT NULL,
+    type                VARCHAR(30)     NOT NULL,
+    amount              JSONB           NOT NULL,
+    reason_code         VARCHAR(30)     NOT NULL,
+    status              VARCHAR(20)     NOT NULL DEFAULT 'pending_approval',
+    reverses_id         VARCHAR(36),
+    requested_by        VARCHAR(100)    NOT NULL,
+    requested_at        TIMESTAMPTZ     NOT NULL,
+    decided_by          VARCHAR(100),
+    decided_at          TIMESTAMPTZ,
+
+    PRIMARY KEY (id),
+    CONSTRAINT fk_loan_id
+        FOREIGN KEY(loan_id) 
+	    REFERENCES loans(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_schedule_id
+        FOREIGN KEY(schedule_id) 
+	    REFERENCES loan_schedules(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_reverses_id
+        FOREIGN KEY(reverses_id) 
+	    REFERENCES loan_adjustments(id)
+);
@@ -0,0 +1,13 @@
+CREATE TABLE idempotency_keys (
+    key                 VARCHAR(100)    NOT NULL,
+    request_hash        VARCHAR(64)     NOT NULL,
+    payment_id          VARCHAR(36)     NOT NULL,
+    response            JSONB           NOT NULL,
+    created_at          TIMESTAMPTZ     NOT NULL,
+
+    PRIMARY KEY (key),
+    CONSTRAINT fk_payment_id
+        FOREIGN KEY(payment_id) 
+	    REFERENCES payments(id)
+        ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+ALTER TABLE loans DROP CONSTRAINT fk_borrower_id;
+DROP TABLE borrowers;
@@ -0,0 +1,25 @@
+CREATE TABLE borrowers (
+    id                  VARCHAR(36)     NOT NULL,
+    full_name           VARCHAR(200)    NOT NULL,
+    identity_type       VARCHAR(20)     NOT NULL,
+    identity_number     VARCHAR(50)     NOT NULL,
+    date_of_birth       DATE            NOT NULL,
+    email               VARCHAR(254)    NOT NULL,
+    phone_number        VARCHAR(20)     NOT NULL,
+    address             TEXT            NOT NULL DEFAULT '',
+    kyc_status          VARCHAR(20)     NOT NULL DEFAULT 'pending',
+    kyc_updated_at      TIMESTAMPTZ,
+    created_at          TIMESTAMPTZ     NOT NULL,
+
+    PRIMARY KEY (id),
+    CONSTRAINT uq_borrower_identity
+        UNIQUE (identity_type, identity_number)
+);
+
+-- loans made before borrowers were registered keep their borrower_id, only new
+-- loans must point at a borrower
+ALTER TABLE loans
+    ADD CONSTRAINT fk_borrower_id
+        FOREIGN KEY(borrower_id)
+        REFERENCES borrowers(id)
+        NOT VALID;
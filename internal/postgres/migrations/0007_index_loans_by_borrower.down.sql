@@ -0,0 +1 @@
+DROP INDEX idx_loans_borrower_id_started_at;
@@ -0,0 +1,3 @@
+-- a borrower's loans are listed newest first, paged by (started_at, id)
+CREATE INDEX idx_loans_borrower_id_started_at
+    ON loans(borrower_id, started_at DESC, id DESC);
@@ -0,0 +1,5 @@
+ALTER TABLE loans
+    DROP CONSTRAINT fk_product_id,
+    DROP COLUMN grace_days,
+    DROP COLUMN product_id;
+DROP TABLE loan_products;
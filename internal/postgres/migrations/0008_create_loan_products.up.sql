@@ -0,0 +1,27 @@
+CREATE TABLE loan_products (
+    id                  VARCHAR(36)     NOT NULL,
+    name                VARCHAR(200)    NOT NULL,
+    min_principal       JSONB           NOT NULL,
+    max_principal       JSONB           NOT NULL,
+    min_interest_rate   FLOAT           NOT NULL,
+    max_interest_rate   FLOAT           NOT NULL,
+    interest_model      VARCHAR(20)     NOT NULL,
+    frequencies         JSONB           NOT NULL,
+    terms               JSONB           NOT NULL,
+    fee_amount          JSONB           NOT NULL,
+    fee_rate            FLOAT           NOT NULL DEFAULT 0,
+    grace_days          INT             NOT NULL DEFAULT 0,
+    status              VARCHAR(20)     NOT NULL DEFAULT 'active',
+    created_at          TIMESTAMPTZ     NOT NULL,
+
+    PRIMARY KEY (id)
+);
+
+-- loans made before products have neither, their late fees follow the late fee
+-- rules
+ALTER TABLE loans
+    ADD COLUMN product_id VARCHAR(36),
+    ADD COLUMN grace_days INT,
+    ADD CONSTRAINT fk_product_id
+        FOREIGN KEY(product_id)
+        REFERENCES loan_products(id);
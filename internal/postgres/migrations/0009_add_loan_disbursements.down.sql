@@ -0,0 +1,5 @@
+ALTER TABLE loans
+    DROP CONSTRAINT uq_disbursement_reference,
+    DROP COLUMN disbursed_at,
+    DROP COLUMN disbursement_reference,
+    DROP COLUMN disbursed_amount;
@@ -0,0 +1,8 @@
+-- loans already active were disbursed when they were made, they keep no record
+-- of it
+ALTER TABLE loans
+    ADD COLUMN disbursed_amount         JSONB,
+    ADD COLUMN disbursement_reference   VARCHAR(100),
+    ADD COLUMN disbursed_at             TIMESTAMPTZ,
+    ADD CONSTRAINT uq_disbursement_reference
+        UNIQUE (disbursement_reference);
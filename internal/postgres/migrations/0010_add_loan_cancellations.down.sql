@@ -0,0 +1,5 @@
+ALTER TABLE loans
+    DROP COLUMN refund_amount,
+    DROP COLUMN principal_to_return,
+    DROP COLUMN cancelled_at,
+    DROP COLUMN cancellation_reason;
@@ -0,0 +1,5 @@
+ALTER TABLE loans
+    ADD COLUMN cancellation_reason  VARCHAR(500),
+    ADD COLUMN cancelled_at         TIMESTAMPTZ,
+    ADD COLUMN principal_to_return  JSONB,
+    ADD COLUMN refund_amount        JSONB;
@@ -0,0 +1 @@
+DROP TABLE payoff_quotes;
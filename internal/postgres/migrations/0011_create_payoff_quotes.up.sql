@@ -0,0 +1,24 @@
+CREATE TABLE payoff_quotes (
+    id                  VARCHAR(36)     NOT NULL,
+    loan_id             VARCHAR(36)     NOT NULL,
+    as_of               DATE            NOT NULL,
+    principal           JSONB           NOT NULL,
+    accrued_interest    JSONB           NOT NULL,
+    fee                 JSONB           NOT NULL,
+    late_fee            JSONB           NOT NULL,
+    rebate              JSONB           NOT NULL,
+    prepayment_fee      JSONB           NOT NULL,
+    total               JSONB           NOT NULL,
+    valid_until         TIMESTAMPTZ     NOT NULL,
+    created_at          TIMESTAMPTZ     NOT NULL,
+    payment_id          VARCHAR(36),
+
+    PRIMARY KEY (id),
+    CONSTRAINT fk_loan_id
+        FOREIGN KEY(loan_id)
+        REFERENCES loans(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_payment_id
+        FOREIGN KEY(payment_id)
+        REFERENCES payments(id)
+);
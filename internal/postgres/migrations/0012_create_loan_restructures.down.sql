@@ -0,0 +1,4 @@
+DROP TABLE loan_restructures;
+
+ALTER TABLE loan_schedules
+    DROP COLUMN version;
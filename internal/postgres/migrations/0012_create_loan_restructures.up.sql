@@ -0,0 +1,27 @@
+-- schedules made before restructures are all on the first version
+ALTER TABLE loan_schedules
+    ADD COLUMN version INT NOT NULL DEFAULT 1;
+
+CREATE TABLE loan_restructures (
+    id                  VARCHAR(36)     NOT NULL,
+    loan_id             VARCHAR(36)     NOT NULL,
+    version             INT             NOT NULL,
+    extend_payments     INT             NOT NULL DEFAULT 0,
+    holiday_payments    INT             NOT NULL DEFAULT 0,
+    capitalise_arrears  BOOLEAN         NOT NULL DEFAULT FALSE,
+    outstanding         JSONB           NOT NULL,
+    arrears             JSONB           NOT NULL,
+    capitalised         JSONB           NOT NULL,
+    reason              VARCHAR(500)    NOT NULL,
+    requested_by        VARCHAR(100)    NOT NULL,
+    approved_by         VARCHAR(100)    NOT NULL,
+    created_at          TIMESTAMPTZ     NOT NULL,
+
+    PRIMARY KEY (id),
+    CONSTRAINT uq_loan_restructure_version
+        UNIQUE (loan_id, version),
+    CONSTRAINT fk_loan_id
+        FOREIGN KEY(loan_id)
+        REFERENCES loans(id)
+        ON DELETE CASCADE
+);
@@ -0,0 +1,22 @@
+CREATE TABLE loan_deferrals (
+    id                  VARCHAR(36)     NOT NULL,
+    loan_id             VARCHAR(36)     NOT NULL,
+    installments        INT             NOT NULL,
+    interest_rate       FLOAT           NOT NULL DEFAULT 0,
+    interest            JSONB           NOT NULL,
+    reason              VARCHAR(500)    NOT NULL,
+    requested_by        VARCHAR(100)    NOT NULL,
+    deferred_from       TIMESTAMPTZ     NOT NULL,
+    deferred_until      TIMESTAMPTZ     NOT NULL,
+    created_at          TIMESTAMPTZ     NOT NULL,
+
+    PRIMARY KEY (id),
+    CONSTRAINT fk_loan_id
+        FOREIGN KEY(loan_id)
+        REFERENCES loans(id)
+        ON DELETE CASCADE
+);
+
+-- delinquency checks look up the deferrals running on a loan
+CREATE INDEX idx_loan_deferrals_loan_id_deferred_until
+    ON loan_deferrals(loan_id, deferred_until);
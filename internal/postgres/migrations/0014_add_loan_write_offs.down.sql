@@ -0,0 +1,4 @@
+ALTER TABLE loans
+    DROP COLUMN written_off_amount,
+    DROP COLUMN written_off_at,
+    DROP COLUMN write_off_reason;
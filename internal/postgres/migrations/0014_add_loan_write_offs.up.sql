@@ -0,0 +1,4 @@
+ALTER TABLE loans
+    ADD COLUMN write_off_reason     VARCHAR(500),
+    ADD COLUMN written_off_at       TIMESTAMPTZ,
+    ADD COLUMN written_off_amount   JSONB;
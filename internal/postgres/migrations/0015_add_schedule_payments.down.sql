@@ -0,0 +1,3 @@
+ALTER TABLE loan_schedules
+    DROP COLUMN paid_at,
+    DROP COLUMN paid_amount;
@@ -0,0 +1,19 @@
+-- schedules were either unpaid or paid in full before partial payments, when
+-- they were paid is not known. Databases whose 0001 created the columns already
+-- skip the backfill.
+ALTER TABLE loan_schedules
+    ADD COLUMN IF NOT EXISTS paid_amount    JSONB,
+    ADD COLUMN IF NOT EXISTS paid_at        TIMESTAMPTZ;
+
+UPDATE
+    loan_schedules
+SET
+    paid_amount = CASE
+        WHEN status = 'paid' THEN amount_due
+        ELSE jsonb_set(amount_due, '{value}', '0')
+    END
+WHERE
+    paid_amount IS NULL;
+
+ALTER TABLE loan_schedules
+    ALTER COLUMN paid_amount SET NOT NULL;
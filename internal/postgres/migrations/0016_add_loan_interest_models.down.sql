@@ -0,0 +1,2 @@
+ALTER TABLE loans
+    DROP COLUMN interest_model;
@@ -0,0 +1,3 @@
+-- loans made before interest models charged flat interest
+ALTER TABLE loans
+    ADD COLUMN IF NOT EXISTS interest_model VARCHAR(20) NOT NULL DEFAULT 'flat';
@@ -0,0 +1,7 @@
+ALTER TABLE loan_schedules
+    DROP COLUMN fee_due,
+    DROP COLUMN interest_due,
+    DROP COLUMN principal_due;
+
+ALTER TABLE loans
+    DROP COLUMN fee_amount;
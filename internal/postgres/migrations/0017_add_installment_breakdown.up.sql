@@ -0,0 +1,44 @@
+-- loans made before the breakdown charged no fee and flat interest, so each
+-- installment splits into principal and interest in proportion to the interest
+-- rate. Databases whose 0001 created the columns already skip the backfill.
+ALTER TABLE loans
+    ADD COLUMN IF NOT EXISTS fee_amount JSONB;
+
+ALTER TABLE loan_schedules
+    ADD COLUMN IF NOT EXISTS principal_due  JSONB,
+    ADD COLUMN IF NOT EXISTS interest_due   JSONB,
+    ADD COLUMN IF NOT EXISTS fee_due        JSONB;
+
+UPDATE
+    loans
+SET
+    fee_amount = jsonb_set(principal_amount, '{value}', '0')
+WHERE
+    fee_amount IS NULL;
+
+UPDATE
+    loan_schedules ls
+SET
+    principal_due = jsonb_set(ls.amount_due, '{value}', to_jsonb(split.principal)),
+    interest_due = jsonb_set(ls.amount_due, '{value}', to_jsonb(CAST(ls.amount_due->>'value' AS BIGINT) - split.principal)),
+    fee_due = jsonb_set(ls.amount_due, '{value}', '0')
+FROM
+    (
+        SELECT
+            s.id,
+            CAST(ROUND(CAST(s.amount_due->>'value' AS BIGINT) / (1 + l.interest_rate)) AS BIGINT) AS principal
+        FROM
+            loan_schedules s
+            JOIN loans l ON l.id = s.loan_id
+    ) split
+WHERE
+    split.id = ls.id
+    AND ls.principal_due IS NULL;
+
+ALTER TABLE loans
+    ALTER COLUMN fee_amount SET NOT NULL;
+
+ALTER TABLE loan_schedules
+    ALTER COLUMN principal_due SET NOT NULL,
+    ALTER COLUMN interest_due SET NOT NULL,
+    ALTER COLUMN fee_due SET NOT NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE loan_schedules
+    DROP COLUMN late_fee_due;
@@ -0,0 +1,13 @@
+-- schedules made before late fees carry none
+ALTER TABLE loan_schedules
+    ADD COLUMN IF NOT EXISTS late_fee_due JSONB;
+
+UPDATE
+    loan_schedules
+SET
+    late_fee_due = jsonb_set(amount_due, '{value}', '0')
+WHERE
+    late_fee_due IS NULL;
+
+ALTER TABLE loan_schedules
+    ALTER COLUMN late_fee_due SET NOT NULL;
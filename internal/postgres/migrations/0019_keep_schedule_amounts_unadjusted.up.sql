@@ -0,0 +1,36 @@
+-- schedules keep the amounts they were made with, approved adjustments are taken
+-- off them when they are read instead of when they are approved. The ones
+-- already approved are added back onto their schedules.
+CREATE INDEX idx_loan_adjustments_schedule_id
+    ON loan_adjustments(schedule_id);
+
+UPDATE
+    loan_schedules ls
+SET
+    amount_due = jsonb_set(ls.amount_due, '{value}', to_jsonb(CAST(ls.amount_due->>'value' AS BIGINT) + adj.principal + adj.interest + adj.fee)),
+    principal_due = jsonb_set(ls.principal_due, '{value}', to_jsonb(CAST(ls.principal_due->>'value' AS BIGINT) + adj.principal)),
+    interest_due = jsonb_set(ls.interest_due, '{value}', to_jsonb(CAST(ls.interest_due->>'value' AS BIGINT) + adj.interest)),
+    fee_due = jsonb_set(ls.fee_due, '{value}', to_jsonb(CAST(ls.fee_due->>'value' AS BIGINT) + adj.fee))
+FROM
+    (
+        SELECT
+            a.schedule_id,
+            COALESCE(SUM(a.net) FILTER (WHERE a.type = 'reduce_installment'), 0) AS principal,
+            COALESCE(SUM(a.net) FILTER (WHERE a.type = 'write_off_interest'), 0) AS interest,
+            COALESCE(SUM(a.net) FILTER (WHERE a.type = 'waive_fee'), 0) AS fee
+        FROM
+            (
+                SELECT
+                    schedule_id,
+                    type,
+                    CASE WHEN reverses_id IS NULL THEN 1 ELSE -1 END * CAST(amount->>'value' AS BIGINT) AS net
+                FROM
+                    loan_adjustments
+                WHERE
+                    status = 'approved'
+            ) a
+        GROUP BY
+            a.schedule_id
+    ) adj
+WHERE
+    adj.schedule_id = ls.id;
@@ -0,0 +1,2 @@
+ALTER TABLE loan_schedules
+    DROP COLUMN anchored_at;
@@ -0,0 +1,27 @@
+-- the first version counts its periods from the start of the loan, later ones
+-- from the day of the restructure that made them
+ALTER TABLE loan_schedules
+    ADD COLUMN anchored_at TIMESTAMPTZ;
+
+UPDATE
+    loan_schedules ls
+SET
+    anchored_at = l.started_at
+FROM
+    loans l
+WHERE
+    l.id = ls.loan_id
+    AND ls.version = 1;
+
+UPDATE
+    loan_schedules ls
+SET
+    anchored_at = date_trunc('day', r.created_at)
+FROM
+    loan_restructures r
+WHERE
+    r.loan_id = ls.loan_id
+    AND r.version = ls.version;
+
+ALTER TABLE loan_schedules
+    ALTER COLUMN anchored_at SET NOT NULL;
@@ -0,0 +1,7 @@
+DROP INDEX idx_loans_borrower_id_created_at;
+
+CREATE INDEX idx_loans_borrower_id_started_at
+    ON loans(borrower_id, started_at DESC, id DESC);
+
+ALTER TABLE loans
+    DROP COLUMN created_at;
@@ -0,0 +1,19 @@
+-- loans are paged on when they were made, started_at moves on disbursement;
+-- loans made before this column count from their start, the closest record of
+-- when they were made
+ALTER TABLE loans
+    ADD COLUMN created_at TIMESTAMPTZ;
+
+UPDATE
+    loans
+SET
+    created_at = started_at;
+
+ALTER TABLE loans
+    ALTER COLUMN created_at SET NOT NULL;
+
+DROP INDEX idx_loans_borrower_id_started_at;
+
+-- a borrower's loans are listed newest first, paged by (created_at, id)
+CREATE INDEX idx_loans_borrower_id_created_at
+    ON loans(borrower_id, created_at DESC, id DESC);
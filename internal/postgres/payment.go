@@ -0,0 +1,299 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	billing "github.com/theyudiriski/billing-service/internal/service"
+)
+
+func NewPaymentStore(db *Client) billing.PaymentStore {
+	return &paymentStore{db}
+}
+
+type paymentStore struct {
+	db *Client
+}
+
+func (s *paymentStore) CreatePayment(
+	ctx context.Context,
+	payment *billing.Payment,
+	idempotencyKey *billing.IdempotencyKey,
+) error {
+	return runInTx(ctx, s.db.Leader, nil, func(tx *sql.Tx) error {
+		return createPayment(ctx, tx, payment, idempotencyKey)
+	})
+}
+
+func createPayment(
+	ctx context.Context,
+	tx *sql.Tx,
+	payment *billing.Payment,
+	idempotencyKey *billing.IdempotencyKey,
+) error {
+	_, err := tx.ExecContext(ctx, `
+INSERT INTO payments(
+	id,
+	loan_id,
+	amount,
+	unapplied_amount,
+	channel,
+	external_reference,
+	paid_at
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		payment.ID,
+		payment.LoanID,
+		payment.Amount,
+		payment.UnappliedAmount,
+		payment.Channel,
+		payment.ExternalReference,
+		payment.PaidAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if idempotencyKey != nil {
+		if err := createIdempotencyKey(ctx, tx, idempotencyKey); err != nil {
+			return err
+		}
+	}
+
+	scheduleStmt, err := tx.PrepareContext(ctx, `
+	UPDATE
+		loan_schedules
+	SET
+		paid_amount = $2,
+		status = $3,
+		paid_at = CASE WHEN $3 = 'paid' THEN $4::TIMESTAMPTZ ELSE NULL END
+	WHERE
+		id = $1`)
+	if err != nil {
+		return err
+	}
+	defer scheduleStmt.Close()
+
+	allocationStmt, err := tx.PrepareContext(ctx, `
+	INSERT INTO payment_allocations(
+		payment_id,
+		schedule_id,
+		amount,
+		schedule_status
+	) VALUES ($1, $2, $3, $4)`)
+	if err != nil {
+		return err
+	}
+	defer allocationStmt.Close()
+
+	for _, allocation := range payment.Allocations {
+		if _, err := scheduleStmt.ExecContext(
+			ctx,
+			allocation.ScheduleID,
+			allocation.SchedulePaidAmount,
+			allocation.ScheduleStatus,
+			payment.PaidAt,
+		); err != nil {
+			return err
+		}
+
+		if _, err := allocationStmt.ExecContext(
+			ctx,
+			payment.ID,
+			allocation.ScheduleID,
+			allocation.Amount,
+			allocation.ScheduleStatus,
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ListPaymentsByLoanID returns the payment history of a loan, oldest first.
+func (s *paymentStore) ListPaymentsByLoanID(
+	ctx context.Context,
+	loanID string,
+) ([]billing.Payment, error) {
+	rows, err := s.db.follower(ctx).QueryContext(ctx, `
+SELECT
+	id,
+	loan_id,
+	amount,
+	unapplied_amount,
+	channel,
+	external_reference,
+	paid_at
+FROM
+	payments
+WHERE
+	loan_id = $1
+ORDER BY
+	paid_at`,
+		loanID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payments := []billing.Payment{}
+	paymentIndex := map[string]int{}
+	for rows.Next() {
+		var p billing.Payment
+		if err := rows.Scan(
+			&p.ID,
+			&p.LoanID,
+			&p.Amount,
+			&p.UnappliedAmount,
+			&p.Channel,
+			&p.ExternalReference,
+			&p.PaidAt,
+		); err != nil {
+			return nil, err
+		}
+		paymentIndex[p.ID] = len(payments)
+		payments = append(payments, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	allocationRows, err := s.db.follower(ctx).QueryContext(ctx, `
+SELECT
+	pa.payment_id,
+	pa.schedule_id,
+	ls.seq,
+	ls.due_date,
+	pa.amount,
+	pa.schedule_status
+FROM
+	payment_allocations pa
+	JOIN payments p ON p.id = pa.payment_id
+	JOIN loan_schedules ls ON ls.id = pa.schedule_id
+WHERE
+	p.loan_id = $1
+ORDER BY
+	ls.due_date,
+	ls.seq`,
+		loanID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer allocationRows.Close()
+
+	for allocationRows.Next() {
+		var (
+			paymentID  string
+			allocation billing.PaymentAllocation
+		)
+		if err := allocationRows.Scan(
+			&paymentID,
+			&allocation.ScheduleID,
+			&allocation.Seq,
+			&allocation.DueDate,
+			&allocation.Amount,
+			&allocation.ScheduleStatus,
+		); err != nil {
+			return nil, err
+		}
+
+		i := paymentIndex[paymentID]
+		payments[i].Allocations = append(payments[i].Allocations, allocation)
+	}
+	if err := allocationRows.Err(); err != nil {
+		return nil, err
+	}
+
+	return payments, nil
+}
+
+// createIdempotencyKey stores the key with the payment it was used for. A key
+// taken by a concurrent request makes it wait for that request to finish and then
+// fail with ErrIdempotencyKeyConflict.
+func createIdempotencyKey(
+	ctx context.Context,
+	tx *sql.Tx,
+	idempotencyKey *billing.IdempotencyKey,
+) error {
+	response, err := json.Marshal(idempotencyKey.Payment)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+INSERT INTO idempotency_keys(
+	key,
+	request_hash,
+	payment_id,
+	response,
+	created_at
+)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (key) DO NOTHING`,
+		idempotencyKey.Key,
+		idempotencyKey.RequestHash,
+		idempotencyKey.Payment.ID,
+		response,
+		idempotencyKey.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return billing.ErrIdempotencyKeyConflict
+	}
+
+	return nil
+}
+
+// GetIdempotencyKey returns the stored key along with the payment made by the
+// request that first used it.
+func (s *paymentStore) GetIdempotencyKey(
+	ctx context.Context,
+	key string,
+) (*billing.IdempotencyKey, error) {
+	var (
+		idempotencyKey billing.IdempotencyKey
+		response       []byte
+	)
+
+	err := s.db.leader(ctx).QueryRowContext(ctx, `
+SELECT
+	key,
+	request_hash,
+	response,
+	created_at
+FROM
+	idempotency_keys
+WHERE
+	key = $1`,
+		key,
+	).Scan(
+		&idempotencyKey.Key,
+		&idempotencyKey.RequestHash,
+		&response,
+		&idempotencyKey.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, billing.ErrIdempotencyKeyNotFound
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(response, &idempotencyKey.Payment); err != nil {
+		return nil, err
+	}
+
+	return &idempotencyKey, nil
+}
@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	billing "github.com/theyudiriski/billing-service/internal/service"
+)
+
+func NewPayoffStore(db *Client) billing.PayoffStore {
+	return &payoffStore{db}
+}
+
+type payoffStore struct {
+	db *Client
+}
+
+func (s *payoffStore) CreateQuote(
+	ctx context.Context,
+	quote *billing.PayoffQuote,
+) error {
+	_, err := s.db.leader(ctx).ExecContext(ctx, `
+INSERT INTO payoff_quotes(
+	id,
+	loan_id,
+	as_of,
+	principal,
+	accrued_interest,
+	fee,
+	late_fee,
+	rebate,
+	prepayment_fee,
+	total,
+	valid_until,
+	created_at
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		quote.ID,
+		quote.LoanID,
+		quote.AsOf,
+		quote.Principal,
+		quote.AccruedInterest,
+		quote.Fee,
+		quote.LateFee,
+		quote.Rebate,
+		quote.PrepaymentFee,
+		quote.Total,
+		quote.ValidUntil,
+		quote.CreatedAt,
+	)
+	return err
+}
+
+// GetQuoteByID reads from the leader, quotes are paid moments after they are
+// made.
+func (s *payoffStore) GetQuoteByID(
+	ctx context.Context,
+	quoteID string,
+) (*billing.PayoffQuote, error) {
+	row := s.db.leader(ctx).QueryRowContext(ctx, `
+SELECT
+	id,
+	loan_id,
+	as_of,
+	principal,
+	accrued_interest,
+	fee,
+	late_fee,
+	rebate,
+	prepayment_fee,
+	total,
+	valid_until,
+	created_at,
+	payment_id
+FROM
+	payoff_quotes
+WHERE
+	id = $1`,
+		quoteID,
+	)
+
+	q := &billing.PayoffQuote{}
+	err := row.Scan(
+		&q.ID,
+		&q.LoanID,
+		&q.AsOf,
+		&q.Principal,
+		&q.AccruedInterest,
+		&q.Fee,
+		&q.LateFee,
+		&q.Rebate,
+		&q.PrepaymentFee,
+		&q.Total,
+		&q.ValidUntil,
+		&q.CreatedAt,
+		&q.PaymentID,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, billing.ErrPayoffQuoteNotFound
+		}
+		return nil, err
+	}
+
+	// DATE columns come back as UTC midnight
+	q.AsOf = billing.LocalDate(q.AsOf)
+
+	return q, nil
+}
+
+func (s *payoffStore) MarkQuotePaid(
+	ctx context.Context,
+	quote *billing.PayoffQuote,
+) error {
+	result, err := s.db.leader(ctx).ExecContext(ctx, `
+UPDATE
+	payoff_quotes
+SET
+	payment_id = $2
+WHERE
+	id = $1
+	AND payment_id IS NULL`,
+		quote.ID,
+		quote.PaymentID,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return billing.ErrPayoffQuoteUsed
+	}
+
+	return nil
+}
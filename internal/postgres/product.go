@@ -0,0 +1,224 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	billing "github.com/theyudiriski/billing-service/internal/service"
+)
+
+func NewLoanProductStore(db *Client) billing.LoanProductStore {
+	return &loanProductStore{db}
+}
+
+type loanProductStore struct {
+	db *Client
+}
+
+func (s *loanProductStore) CreateProduct(
+	ctx context.Context,
+	product *billing.LoanProduct,
+) error {
+	frequencies, err := json.Marshal(product.Frequencies)
+	if err != nil {
+		return err
+	}
+
+	terms, err := json.Marshal(product.Terms)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.leader(ctx).ExecContext(ctx, `
+INSERT INTO loan_products(
+	id,
+	name,
+	min_principal,
+	max_principal,
+	min_interest_rate,
+	max_interest_rate,
+	interest_model,
+	frequencies,
+	terms,
+	fee_amount,
+	fee_rate,
+	grace_days,
+	status,
+	created_at
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`,
+		product.ID,
+		product.Name,
+		product.MinPrincipal,
+		product.MaxPrincipal,
+		product.MinInterestRate,
+		product.MaxInterestRate,
+		product.InterestModel,
+		frequencies,
+		terms,
+		product.FeeAmount,
+		product.FeeRate,
+		product.GraceDays,
+		product.Status,
+		product.CreatedAt,
+	)
+	return err
+}
+
+func (s *loanProductStore) GetProductByID(
+	ctx context.Context,
+	productID string,
+) (*billing.LoanProduct, error) {
+	row := s.db.follower(ctx).QueryRowContext(ctx, `
+SELECT
+	`+productColumns+`
+FROM
+	loan_products
+WHERE
+	id = $1`,
+		productID,
+	)
+
+	product, err := scanProduct(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, billing.ErrLoanProductNotFound
+		}
+		return nil, err
+	}
+
+	return product, nil
+}
+
+// ListProducts returns the products in the given status, every product when it
+// is empty, newest first.
+func (s *loanProductStore) ListProducts(
+	ctx context.Context,
+	status billing.LoanProductStatus,
+) ([]billing.LoanProduct, error) {
+	query := `
+SELECT
+	` + productColumns + `
+FROM
+	loan_products`
+	args := []any{}
+
+	if status != "" {
+		args = append(args, status)
+		query += `
+WHERE
+	status = $1`
+	}
+
+	query += `
+ORDER BY
+	created_at DESC,
+	id`
+
+	rows, err := s.db.follower(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []billing.LoanProduct{}
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, *product)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
+
+// UpdateProductStatus moves the product from one status to another, failing
+// when the product is no longer in the expected status.
+func (s *loanProductStore) UpdateProductStatus(
+	ctx context.Context,
+	product *billing.LoanProduct,
+	from billing.LoanProductStatus,
+) error {
+	result, err := s.db.leader(ctx).ExecContext(ctx, `
+UPDATE
+	loan_products
+SET
+	status = $3
+WHERE
+	id = $1
+	AND status = $2`,
+		product.ID,
+		from,
+		product.Status,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return billing.ErrInvalidLoanProductStatusTransition
+	}
+
+	return nil
+}
+
+const productColumns = `id,
+	name,
+	min_principal,
+	max_principal,
+	min_interest_rate,
+	max_interest_rate,
+	interest_model,
+	frequencies,
+	terms,
+	fee_amount,
+	fee_rate,
+	grace_days,
+	status,
+	created_at`
+
+// scanProduct reads a product row selected with productColumns.
+func scanProduct(row scanner) (*billing.LoanProduct, error) {
+	var (
+		p                  = &billing.LoanProduct{}
+		frequencies, terms []byte
+	)
+	err := row.Scan(
+		&p.ID,
+		&p.Name,
+		&p.MinPrincipal,
+		&p.MaxPrincipal,
+		&p.MinInterestRate,
+		&p.MaxInterestRate,
+		&p.InterestModel,
+		&frequencies,
+		&terms,
+		&p.FeeAmount,
+		&p.FeeRate,
+		&p.GraceDays,
+		&p.Status,
+		&p.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(frequencies, &p.Frequencies); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(terms, &p.Terms); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	billing "github.com/theyudiriski/billing-service/internal/service"
+)
+
+// reader returns the database reads go to. That is the follower, unless ctx
+// asks to read its own writes or the follower lags too far behind.
+func (c *Client) reader(ctx context.Context) *sql.DB {
+	if billing.IsReadYourWrites(ctx) || c.followerLagging.Load() {
+		return c.Leader
+	}
+	return c.Follower
+}
+
+// monitorFollowerLag checks the follower lag on every interval until ctx is
+// done. A follower that cannot be checked counts as lagging.
+func (c *Client) monitorFollowerLag(ctx context.Context, maxLag, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, interval)
+		lag, err := c.followerLag(checkCtx)
+		cancel()
+
+		c.followerLagging.Store(err != nil || lag > maxLag)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// followerLag returns how far the follower is behind the leader. A follower that
+// has replayed all it received is not behind, however long ago the last
+// transaction was.
+func (c *Client) followerLag(ctx context.Context) (time.Duration, error) {
+	var seconds float64
+	err := c.Follower.QueryRowContext(ctx, `
+SELECT
+	CASE
+		WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE COALESCE(EXTRACT(EPOCH FROM NOW() - pg_last_xact_replay_timestamp()), 0)
+	END`,
+	).Scan(&seconds)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(seconds * float64(time.Second)), nil
+}
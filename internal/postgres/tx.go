@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	billing "github.com/theyudiriski/billing-service/internal/service"
+)
+
+type txKey struct{}
+
+// executor is satisfied by both *sql.DB and *sql.Tx, so a store query runs the
+// same in and out of a transaction.
+type executor interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func NewTxManager(db *Client) billing.TxManager {
+	return &txManager{db}
+}
+
+type txManager struct {
+	db *Client
+}
+
+func (m *txManager) WithinTx(
+	ctx context.Context,
+	fn func(ctx context.Context) error,
+) error {
+	return runInTx(ctx, m.db.Leader, nil, func(tx *sql.Tx) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// WithinReadOnlyTx runs fn in a read-only transaction on the follower. Repeatable
+// read gives every read in fn the same snapshot.
+func (m *txManager) WithinReadOnlyTx(
+	ctx context.Context,
+	fn func(ctx context.Context) error,
+) error {
+	opts := &sql.TxOptions{
+		Isolation: sql.LevelRepeatableRead,
+		ReadOnly:  true,
+	}
+	return runInTx(ctx, m.db.reader(ctx), opts, func(tx *sql.Tx) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// runInTx runs fn in the transaction carried by ctx, or else in a new one on db
+// that is committed when fn succeeds.
+func runInTx(
+	ctx context.Context,
+	db *sql.DB,
+	opts *sql.TxOptions,
+	fn func(tx *sql.Tx) error,
+) error {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(tx)
+	}
+
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// leader returns the transaction carried by ctx, or the leader database when
+// there is none.
+func (c *Client) leader(ctx context.Context) executor {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return c.Leader
+}
+
+// follower returns the transaction carried by ctx, or the database reads go to
+// when there is none.
+func (c *Client) follower(ctx context.Context) executor {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return c.reader(ctx)
+}
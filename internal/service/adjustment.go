@@ -0,0 +1,588 @@
+package billing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type AdjustmentService interface {
+	// CreateAdjustment records an adjustment pending approval, it has no effect
+	// on the loan until it is approved.
+	CreateAdjustment(
+		ctx context.Context,
+		loanID string,
+		scheduleID string,
+		adjustmentType AdjustmentType,
+		amount Amount,
+		reasonCode AdjustmentReasonCode,
+		operatorID string,
+	) (*Adjustment, error)
+	ApproveAdjustment(ctx context.Context, loanID string, adjustmentID string, operatorID string) (*Adjustment, error)
+	RejectAdjustment(ctx context.Context, loanID string, adjustmentID string, operatorID string) (*Adjustment, error)
+	// ReverseAdjustment records a compensating adjustment pending approval that
+	// undoes an approved one once approved.
+	ReverseAdjustment(
+		ctx context.Context,
+		loanID string,
+		adjustmentID string,
+		reasonCode AdjustmentReasonCode,
+		operatorID string,
+	) (*Adjustment, error)
+	GetAdjustments(ctx context.Context, loanID string) ([]Adjustment, error)
+}
+
+type AdjustmentStore interface {
+	CreateAdjustment(ctx context.Context, adjustment *Adjustment) error
+	ListAdjustmentsByLoanID(ctx context.Context, loanID string) ([]Adjustment, error)
+	// ApproveAdjustment marks a pending adjustment as approved and settles or
+	// reopens its schedule in a single transaction. Schedules keep the amounts
+	// they were made with, approved adjustments are taken off when they are
+	// read.
+	ApproveAdjustment(ctx context.Context, adjustment *Adjustment) error
+	// RejectAdjustment marks a pending adjustment as rejected.
+	RejectAdjustment(ctx context.Context, adjustment *Adjustment) error
+}
+
+func NewAdjustmentService(
+	logger Logger,
+	txManager TxManager,
+	loanStore LoanStore,
+	adjustmentStore AdjustmentStore,
+) AdjustmentService {
+	return &adjustmentService{
+		logger:          logger,
+		txManager:       txManager,
+		loanStore:       loanStore,
+		adjustmentStore: adjustmentStore,
+	}
+}
+
+type adjustmentService struct {
+	logger          Logger
+	txManager       TxManager
+	loanStore       LoanStore
+	adjustmentStore AdjustmentStore
+}
+
+// Adjustment is an immutable correction of a schedule, only its approval state
+// ever changes. An approved adjustment is undone by approving a compensating one
+// that reverses it.
+type Adjustment struct {
+	ID         string
+	LoanID     string
+	ScheduleID string
+	Type       AdjustmentType
+	// always positive, a reversal adds it back to the schedule
+	Amount     Amount
+	ReasonCode AdjustmentReasonCode
+	Status     AdjustmentStatus
+	// adjustment undone by this one, if it is a reversal
+	ReversesID *string
+
+	RequestedBy string
+	RequestedAt time.Time
+	DecidedBy   *string
+	DecidedAt   *time.Time
+}
+
+// IsReversal reports whether the adjustment compensates an earlier one.
+func (a Adjustment) IsReversal() bool {
+	return a.ReversesID != nil
+}
+
+type (
+	AdjustmentType       string
+	AdjustmentReasonCode string
+	AdjustmentStatus     string
+)
+
+var (
+	// takes off part of the fee of an installment
+	AdjustmentTypeWaiveFee AdjustmentType = "waive_fee"
+	// takes off part of the principal of an installment
+	AdjustmentTypeReduceInstallment AdjustmentType = "reduce_installment"
+	// takes off part of the interest of an installment
+	AdjustmentTypeWriteOffInterest AdjustmentType = "write_off_interest"
+
+	AdjustmentTypes = []AdjustmentType{
+		AdjustmentTypeWaiveFee,
+		AdjustmentTypeReduceInstallment,
+		AdjustmentTypeWriteOffInterest,
+	}
+
+	AdjustmentReasonBillingError AdjustmentReasonCode = "billing_error"
+	AdjustmentReasonGoodwill     AdjustmentReasonCode = "goodwill"
+	AdjustmentReasonHardship     AdjustmentReasonCode = "hardship"
+	AdjustmentReasonSettlement   AdjustmentReasonCode = "settlement"
+
+	AdjustmentReasonCodes = []AdjustmentReasonCode{
+		AdjustmentReasonBillingError,
+		AdjustmentReasonGoodwill,
+		AdjustmentReasonHardship,
+		AdjustmentReasonSettlement,
+	}
+
+	AdjustmentStatusPendingApproval AdjustmentStatus = "pending_approval"
+	AdjustmentStatusApproved        AdjustmentStatus = "approved"
+	AdjustmentStatusRejected        AdjustmentStatus = "rejected"
+)
+
+func (t AdjustmentType) IsValid() bool {
+	for _, adjustmentType := range AdjustmentTypes {
+		if adjustmentType == t {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *AdjustmentType) UnmarshalText(text []byte) error {
+	for _, adjustmentType := range AdjustmentTypes {
+		if strings.EqualFold(string(adjustmentType), string(text)) {
+			*t = adjustmentType
+			return nil
+		}
+	}
+	return NewError(
+		ErrValidationError.Error(),
+		fmt.Sprintf("AdjustmentType should be one of %v", AdjustmentTypes),
+		http.StatusBadRequest,
+	)
+}
+
+// component returns the part of the schedule the adjustment type corrects.
+func (t AdjustmentType) component(schedule LoanSchedule) Amount {
+	switch t {
+	case AdjustmentTypeWaiveFee:
+		return schedule.FeeDue
+	case AdjustmentTypeWriteOffInterest:
+		return schedule.InterestDue
+	default:
+		return schedule.PrincipalDue
+	}
+}
+
+func (c AdjustmentReasonCode) IsValid() bool {
+	for _, reasonCode := range AdjustmentReasonCodes {
+		if reasonCode == c {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *AdjustmentReasonCode) UnmarshalText(text []byte) error {
+	for _, reasonCode := range AdjustmentReasonCodes {
+		if strings.EqualFold(string(reasonCode), string(text)) {
+			*c = reasonCode
+			return nil
+		}
+	}
+	return NewError(
+		ErrValidationError.Error(),
+		fmt.Sprintf("AdjustmentReasonCode should be one of %v", AdjustmentReasonCodes),
+		http.StatusBadRequest,
+	)
+}
+
+func (s *adjustmentService) CreateAdjustment(
+	ctx context.Context,
+	loanID string,
+	scheduleID string,
+	adjustmentType AdjustmentType,
+	amount Amount,
+	reasonCode AdjustmentReasonCode,
+	operatorID string,
+) (*Adjustment, error) {
+	// the schedule is checked against what the leader has, not a lagging
+	// follower
+	var adjustment *Adjustment
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		adjustment, err = s.createAdjustment(ctx, loanID, scheduleID, adjustmentType, amount, reasonCode, operatorID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return adjustment, nil
+}
+
+func (s *adjustmentService) createAdjustment(
+	ctx context.Context,
+	loanID string,
+	scheduleID string,
+	adjustmentType AdjustmentType,
+	amount Amount,
+	reasonCode AdjustmentReasonCode,
+	operatorID string,
+) (*Adjustment, error) {
+	if !adjustmentType.IsValid() {
+		return nil, NewError(
+			ErrValidationError.Error(),
+			fmt.Sprintf("AdjustmentType should be one of %v", AdjustmentTypes),
+			http.StatusBadRequest,
+		)
+	}
+
+	if !reasonCode.IsValid() {
+		return nil, NewError(
+			ErrValidationError.Error(),
+			fmt.Sprintf("AdjustmentReasonCode should be one of %v", AdjustmentReasonCodes),
+			http.StatusBadRequest,
+		)
+	}
+
+	loan, err := s.loanStore.GetLoanByID(ctx, loanID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to get loan", "error", err)
+		return nil, err
+	}
+
+	if !loan.Status.IsPayable() {
+		s.logger.WarnContext(ctx, "loan is not adjustable", "status", loan.Status)
+		return nil, ErrLoanNotPayable
+	}
+
+	// adjustments are made in the loan currency
+	if _, err := amount.EqualTo(loan.PrincipalAmount); err != nil {
+		return nil, err
+	}
+
+	schedules, err := s.loanStore.GetUnsettledSchedules(ctx, loanID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to get unsettled schedules", "error", err)
+		return nil, err
+	}
+
+	var schedule *LoanSchedule
+	for i := range schedules {
+		if schedules[i].ID == scheduleID {
+			schedule = &schedules[i]
+			break
+		}
+	}
+	if schedule == nil {
+		return nil, NewError(
+			ErrAdjustmentNotApplicable.Error(),
+			"schedule is not an unsettled schedule of the loan",
+			http.StatusUnprocessableEntity,
+		)
+	}
+
+	if amount.Cmp(adjustmentType.component(*schedule)) > 0 || amount.Cmp(schedule.Balance()) > 0 {
+		return nil, NewError(
+			ErrAdjustmentNotApplicable.Error(),
+			"amount exceeds what is left to adjust on the schedule",
+			http.StatusUnprocessableEntity,
+		)
+	}
+
+	adjustment := &Adjustment{
+		ID:          UUID(),
+		LoanID:      loanID,
+		ScheduleID:  scheduleID,
+		Type:        adjustmentType,
+		Amount:      amount,
+		ReasonCode:  reasonCode,
+		Status:      AdjustmentStatusPendingApproval,
+		RequestedBy: operatorID,
+		RequestedAt: CurrentLocalTime(),
+	}
+
+	if err := s.adjustmentStore.CreateAdjustment(ctx, adjustment); err != nil {
+		s.logger.WarnContext(ctx, "failed to create adjustment", "error", err)
+		return nil, err
+	}
+
+	return adjustment, nil
+}
+
+func (s *adjustmentService) ApproveAdjustment(
+	ctx context.Context,
+	loanID string,
+	adjustmentID string,
+	operatorID string,
+) (*Adjustment, error) {
+	// the loan lock keeps payments from settling the schedule while it is
+	// adjusted, and the paid off check sees the adjusted schedule
+	var adjustment *Adjustment
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		adjustment, err = s.approveAdjustment(ctx, loanID, adjustmentID, operatorID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return adjustment, nil
+}
+
+func (s *adjustmentService) approveAdjustment(
+	ctx context.Context,
+	loanID string,
+	adjustmentID string,
+	operatorID string,
+) (*Adjustment, error) {
+	loan, err := s.loanStore.GetLoanByIDForUpdate(ctx, loanID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to get loan", "error", err)
+		return nil, err
+	}
+
+	if !loan.Status.IsPayable() {
+		s.logger.WarnContext(ctx, "loan is not adjustable", "status", loan.Status)
+		return nil, ErrLoanNotPayable
+	}
+
+	adjustment, err := s.pendingAdjustment(ctx, loanID, adjustmentID, operatorID)
+	if err != nil {
+		return nil, err
+	}
+
+	decidedAt := CurrentLocalTime()
+	adjustment.Status = AdjustmentStatusApproved
+	adjustment.DecidedBy = &operatorID
+	adjustment.DecidedAt = &decidedAt
+
+	if err := s.adjustmentStore.ApproveAdjustment(ctx, adjustment); err != nil {
+		s.logger.WarnContext(ctx, "failed to approve adjustment", "error", err)
+		return nil, err
+	}
+
+	// the adjustment may have taken off all that was left on the loan
+	if err := settleLoanIfPaidOff(ctx, s.logger, s.loanStore, loan); err != nil {
+		return nil, err
+	}
+
+	return adjustment, nil
+}
+
+func (s *adjustmentService) RejectAdjustment(
+	ctx context.Context,
+	loanID string,
+	adjustmentID string,
+	operatorID string,
+) (*Adjustment, error) {
+	// the adjustment is looked up on the leader, a lagging follower may not
+	// have it yet
+	var adjustment *Adjustment
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		adjustment, err = s.rejectAdjustment(ctx, loanID, adjustmentID, operatorID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return adjustment, nil
+}
+
+func (s *adjustmentService) rejectAdjustment(
+	ctx context.Context,
+	loanID string,
+	adjustmentID string,
+	operatorID string,
+) (*Adjustment, error) {
+	_, err := s.loanStore.GetLoanByID(ctx, loanID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to get loan", "error", err)
+		return nil, err
+	}
+
+	adjustment, err := s.pendingAdjustment(ctx, loanID, adjustmentID, operatorID)
+	if err != nil {
+		return nil, err
+	}
+
+	decidedAt := CurrentLocalTime()
+	adjustment.Status = AdjustmentStatusRejected
+	adjustment.DecidedBy = &operatorID
+	adjustment.DecidedAt = &decidedAt
+
+	if err := s.adjustmentStore.RejectAdjustment(ctx, adjustment); err != nil {
+		s.logger.WarnContext(ctx, "failed to reject adjustment", "error", err)
+		return nil, err
+	}
+
+	return adjustment, nil
+}
+
+// pendingAdjustment finds an adjustment of the loan the operator may decide on,
+// nobody approves or rejects their own request.
+func (s *adjustmentService) pendingAdjustment(
+	ctx context.Context,
+	loanID string,
+	adjustmentID string,
+	operatorID string,
+) (*Adjustment, error) {
+	adjustments, err := s.adjustmentStore.ListAdjustmentsByLoanID(ctx, loanID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to list adjustments", "error", err)
+		return nil, err
+	}
+
+	adjustment := findAdjustment(adjustments, adjustmentID)
+	if adjustment == nil {
+		return nil, ErrAdjustmentNotFound
+	}
+
+	if adjustment.Status != AdjustmentStatusPendingApproval {
+		return nil, NewError(
+			ErrAdjustmentNotPending.Error(),
+			fmt.Sprintf("adjustment is already %s", adjustment.Status),
+			http.StatusUnprocessableEntity,
+		)
+	}
+
+	if adjustment.RequestedBy == operatorID {
+		return nil, NewError(
+			ErrAdjustmentSelfApproval.Error(),
+			"adjustment must be decided by another operator than the one who requested it",
+			http.StatusUnprocessableEntity,
+		)
+	}
+
+	return adjustment, nil
+}
+
+func (s *adjustmentService) ReverseAdjustment(
+	ctx context.Context,
+	loanID string,
+	adjustmentID string,
+	reasonCode AdjustmentReasonCode,
+	operatorID string,
+) (*Adjustment, error) {
+	// an earlier reversal may not have reached a lagging follower yet, so the
+	// adjustments are read from the leader
+	var adjustment *Adjustment
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		adjustment, err = s.reverseAdjustment(ctx, loanID, adjustmentID, reasonCode, operatorID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return adjustment, nil
+}
+
+func (s *adjustmentService) reverseAdjustment(
+	ctx context.Context,
+	loanID string,
+	adjustmentID string,
+	reasonCode AdjustmentReasonCode,
+	operatorID string,
+) (*Adjustment, error) {
+	if !reasonCode.IsValid() {
+		return nil, NewError(
+			ErrValidationError.Error(),
+			fmt.Sprintf("AdjustmentReasonCode should be one of %v", AdjustmentReasonCodes),
+			http.StatusBadRequest,
+		)
+	}
+
+	loan, err := s.loanStore.GetLoanByID(ctx, loanID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to get loan", "error", err)
+		return nil, err
+	}
+
+	if !loan.Status.IsPayable() {
+		s.logger.WarnContext(ctx, "loan is not adjustable", "status", loan.Status)
+		return nil, ErrLoanNotPayable
+	}
+
+	adjustments, err := s.adjustmentStore.ListAdjustmentsByLoanID(ctx, loanID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to list adjustments", "error", err)
+		return nil, err
+	}
+
+	original := findAdjustment(adjustments, adjustmentID)
+	if original == nil {
+		return nil, ErrAdjustmentNotFound
+	}
+
+	if original.Status != AdjustmentStatusApproved || original.IsReversal() {
+		return nil, NewError(
+			ErrAdjustmentNotReversible.Error(),
+			"only approved adjustments that are not reversals themselves can be reversed",
+			http.StatusUnprocessableEntity,
+		)
+	}
+
+	for _, adjustment := range adjustments {
+		if adjustment.IsReversal() && *adjustment.ReversesID == original.ID &&
+			adjustment.Status != AdjustmentStatusRejected {
+			return nil, NewError(
+				ErrAdjustmentNotReversible.Error(),
+				fmt.Sprintf("adjustment is already reversed by %s", adjustment.ID),
+				http.StatusUnprocessableEntity,
+			)
+		}
+	}
+
+	reversal := &Adjustment{
+		ID:          UUID(),
+		LoanID:      loanID,
+		ScheduleID:  original.ScheduleID,
+		Type:        original.Type,
+		Amount:      original.Amount,
+		ReasonCode:  reasonCode,
+		Status:      AdjustmentStatusPendingApproval,
+		ReversesID:  &original.ID,
+		RequestedBy: operatorID,
+		RequestedAt: CurrentLocalTime(),
+	}
+
+	if err := s.adjustmentStore.CreateAdjustment(ctx, reversal); err != nil {
+		s.logger.WarnContext(ctx, "failed to create adjustment", "error", err)
+		return nil, err
+	}
+
+	return reversal, nil
+}
+
+func (s *adjustmentService) GetAdjustments(
+	ctx context.Context,
+	loanID string,
+) ([]Adjustment, error) {
+	var adjustments []Adjustment
+	err := s.txManager.WithinReadOnlyTx(ctx, func(ctx context.Context) error {
+		if _, err := s.loanStore.GetLoanByID(ctx, loanID); err != nil {
+			s.logger.WarnContext(ctx, "failed to get loan", "error", err)
+			return err
+		}
+
+		var err error
+		adjustments, err = s.adjustmentStore.ListAdjustmentsByLoanID(ctx, loanID)
+		if err != nil {
+			s.logger.WarnContext(ctx, "failed to list adjustments", "error", err)
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return adjustments, nil
+}
+
+func findAdjustment(adjustments []Adjustment, adjustmentID string) *Adjustment {
+	for i := range adjustments {
+		if adjustments[i].ID == adjustmentID {
+			return &adjustments[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,490 @@
+package billing_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mock_billing "github.com/theyudiriski/billing-service/internal/service/mock"
+
+	billing "github.com/theyudiriski/billing-service/internal/service"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var (
+	mockAdjustmentStore *mock_billing.MockAdjustmentStore
+
+	adjustmentService billing.AdjustmentService
+)
+
+func provideAdjustmentTest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	mockTxManager = newMockTxManager(ctrl)
+	mockLoanStore = mock_billing.NewMockLoanStore(ctrl)
+	mockAdjustmentStore = mock_billing.NewMockAdjustmentStore(ctrl)
+
+	adjustmentService = billing.NewAdjustmentService(
+		billing.NewLogger(),
+		mockTxManager,
+		mockLoanStore,
+		mockAdjustmentStore,
+	)
+}
+
+func TestCreateAdjustment(t *testing.T) {
+	provideAdjustmentTest(t)
+
+	Convey("CreateAdjustment", t, FailureHalts, func() {
+		type (
+			args struct {
+				ctx            context.Context
+				loanID         string
+				scheduleID     string
+				adjustmentType billing.AdjustmentType
+				amount         billing.Amount
+				reasonCode     billing.AdjustmentReasonCode
+				operatorID     string
+			}
+		)
+
+		var (
+			ctx  = context.Background()
+			loan = &billing.Loan{
+				ID:              "loan-id",
+				PrincipalAmount: billing.NewAmount(5_000_000),
+				Status:          billing.LoanStatusActive,
+			}
+			schedule = billing.LoanSchedule{
+				ID:           "schedule-id",
+				LoanID:       loan.ID,
+				AmountDue:    billing.NewAmount(110_000),
+				PrincipalDue: billing.NewAmount(100_000),
+				InterestDue:  billing.NewAmount(10_000),
+				FeeDue:       billing.NewAmount(0),
+				PaidAmount:   billing.NewAmount(0),
+				Status:       billing.LoanScheduleStatusUnpaid,
+			}
+		)
+
+		testCases := []struct {
+			testID   int
+			testDesc string
+			testType string
+			args     args
+			mock     func()
+		}{
+			{
+				testID:   1,
+				testDesc: "success create adjustment pending approval",
+				testType: "P",
+				args: args{
+					ctx:            ctx,
+					loanID:         loan.ID,
+					scheduleID:     schedule.ID,
+					adjustmentType: billing.AdjustmentTypeWriteOffInterest,
+					amount:         billing.NewAmount(10_000),
+					reasonCode:     billing.AdjustmentReasonHardship,
+					operatorID:     "operator-a",
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByID(ctx, loan.ID).Return(loan, nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loan.ID).
+						Return([]billing.LoanSchedule{schedule}, nil)
+					mockAdjustmentStore.EXPECT().CreateAdjustment(ctx, gomock.Any()).
+						Do(func(ctx context.Context, adjustment *billing.Adjustment) {
+							So(adjustment.ScheduleID, ShouldEqual, schedule.ID)
+							So(adjustment.Type, ShouldEqual, billing.AdjustmentTypeWriteOffInterest)
+							So(adjustment.Amount, ShouldEqual, billing.NewAmount(10_000))
+							So(adjustment.Status, ShouldEqual, billing.AdjustmentStatusPendingApproval)
+							So(adjustment.RequestedBy, ShouldEqual, "operator-a")
+							So(adjustment.IsReversal(), ShouldBeFalse)
+						}).Return(nil)
+				},
+			},
+			{
+				testID:   2,
+				testDesc: "failed unknown adjustment type",
+				testType: "N",
+				args: args{
+					ctx:            ctx,
+					loanID:         loan.ID,
+					scheduleID:     schedule.ID,
+					adjustmentType: billing.AdjustmentType("forgive_everything"),
+					amount:         billing.NewAmount(10_000),
+					reasonCode:     billing.AdjustmentReasonHardship,
+					operatorID:     "operator-a",
+				},
+				mock: func() {},
+			},
+			{
+				testID:   3,
+				testDesc: "failed amount exceeds the adjusted component",
+				testType: "N",
+				args: args{
+					ctx:            ctx,
+					loanID:         loan.ID,
+					scheduleID:     schedule.ID,
+					adjustmentType: billing.AdjustmentTypeWriteOffInterest,
+					amount:         billing.NewAmount(10_001),
+					reasonCode:     billing.AdjustmentReasonHardship,
+					operatorID:     "operator-a",
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByID(ctx, loan.ID).Return(loan, nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loan.ID).
+						Return([]billing.LoanSchedule{schedule}, nil)
+				},
+			},
+			{
+				testID:   4,
+				testDesc: "failed schedule is not unsettled",
+				testType: "N",
+				args: args{
+					ctx:            ctx,
+					loanID:         loan.ID,
+					scheduleID:     "settled-schedule-id",
+					adjustmentType: billing.AdjustmentTypeReduceInstallment,
+					amount:         billing.NewAmount(10_000),
+					reasonCode:     billing.AdjustmentReasonBillingError,
+					operatorID:     "operator-a",
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByID(ctx, loan.ID).Return(loan, nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loan.ID).
+						Return([]billing.LoanSchedule{schedule}, nil)
+				},
+			},
+			{
+				testID:   5,
+				testDesc: "failed amount in another currency",
+				testType: "N",
+				args: args{
+					ctx:            ctx,
+					loanID:         loan.ID,
+					scheduleID:     schedule.ID,
+					adjustmentType: billing.AdjustmentTypeReduceInstallment,
+					amount:         billing.NewAmountIn(1_000, billing.CurrencyUSD),
+					reasonCode:     billing.AdjustmentReasonBillingError,
+					operatorID:     "operator-a",
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByID(ctx, loan.ID).Return(loan, nil)
+				},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Logf("%d - [%s] : %s", tc.testID, tc.testType, tc.testDesc)
+			tc.mock()
+
+			_, err := adjustmentService.CreateAdjustment(
+				tc.args.ctx,
+				tc.args.loanID,
+				tc.args.scheduleID,
+				tc.args.adjustmentType,
+				tc.args.amount,
+				tc.args.reasonCode,
+				tc.args.operatorID,
+			)
+
+			if tc.testType == "P" {
+				So(err, ShouldBeNil)
+			} else {
+				So(err, ShouldNotBeNil)
+			}
+		}
+	})
+}
+
+func TestApproveAdjustment(t *testing.T) {
+	provideAdjustmentTest(t)
+
+	Convey("ApproveAdjustment", t, FailureHalts, func() {
+		type (
+			args struct {
+				ctx          context.Context
+				loanID       string
+				adjustmentID string
+				operatorID   string
+			}
+		)
+
+		var (
+			ctx  = context.Background()
+			loan = &billing.Loan{
+				ID:              "loan-id",
+				PrincipalAmount: billing.NewAmount(5_000_000),
+				Status:          billing.LoanStatusActive,
+			}
+			pending = billing.Adjustment{
+				ID:          "adjustment-id",
+				LoanID:      loan.ID,
+				ScheduleID:  "schedule-id",
+				Type:        billing.AdjustmentTypeWaiveFee,
+				Amount:      billing.NewAmount(5_000),
+				Status:      billing.AdjustmentStatusPendingApproval,
+				RequestedBy: "operator-a",
+			}
+			approved = billing.Adjustment{
+				ID:          "approved-adjustment-id",
+				LoanID:      loan.ID,
+				Status:      billing.AdjustmentStatusApproved,
+				RequestedBy: "operator-a",
+			}
+		)
+
+		testCases := []struct {
+			testID   int
+			testDesc string
+			testType string
+			args     args
+			mock     func()
+		}{
+			{
+				testID:   1,
+				testDesc: "success approve adjustment",
+				testType: "P",
+				args: args{
+					ctx:          ctx,
+					loanID:       loan.ID,
+					adjustmentID: pending.ID,
+					operatorID:   "operator-b",
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loan.ID).Return(loan, nil)
+					mockAdjustmentStore.EXPECT().ListAdjustmentsByLoanID(ctx, loan.ID).
+						Return([]billing.Adjustment{pending, approved}, nil)
+					mockAdjustmentStore.EXPECT().ApproveAdjustment(ctx, gomock.Any()).
+						Do(func(ctx context.Context, adjustment *billing.Adjustment) {
+							So(adjustment.ID, ShouldEqual, pending.ID)
+							So(adjustment.Status, ShouldEqual, billing.AdjustmentStatusApproved)
+							So(*adjustment.DecidedBy, ShouldEqual, "operator-b")
+							So(adjustment.DecidedAt, ShouldNotBeNil)
+						}).Return(nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loan.ID).
+						Return([]billing.LoanSchedule{{ID: pending.ScheduleID}}, nil)
+					mockLoanStore.EXPECT().IsDelinquent(ctx, loan.ID).Return(false, nil)
+				},
+			},
+			{
+				testID:   2,
+				testDesc: "failed approve own adjustment",
+				testType: "N",
+				args: args{
+					ctx:          ctx,
+					loanID:       loan.ID,
+					adjustmentID: pending.ID,
+					operatorID:   "operator-a",
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loan.ID).Return(loan, nil)
+					mockAdjustmentStore.EXPECT().ListAdjustmentsByLoanID(ctx, loan.ID).
+						Return([]billing.Adjustment{pending}, nil)
+				},
+			},
+			{
+				testID:   3,
+				testDesc: "failed adjustment already decided",
+				testType: "N",
+				args: args{
+					ctx:          ctx,
+					loanID:       loan.ID,
+					adjustmentID: approved.ID,
+					operatorID:   "operator-b",
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loan.ID).Return(loan, nil)
+					mockAdjustmentStore.EXPECT().ListAdjustmentsByLoanID(ctx, loan.ID).
+						Return([]billing.Adjustment{pending, approved}, nil)
+				},
+			},
+			{
+				testID:   4,
+				testDesc: "failed adjustment no longer fits the schedule",
+				testType: "N",
+				args: args{
+					ctx:          ctx,
+					loanID:       loan.ID,
+					adjustmentID: pending.ID,
+					operatorID:   "operator-b",
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loan.ID).Return(loan, nil)
+					mockAdjustmentStore.EXPECT().ListAdjustmentsByLoanID(ctx, loan.ID).
+						Return([]billing.Adjustment{pending}, nil)
+					mockAdjustmentStore.EXPECT().ApproveAdjustment(ctx, gomock.Any()).
+						Return(billing.ErrAdjustmentNotApplicable)
+				},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Logf("%d - [%s] : %s", tc.testID, tc.testType, tc.testDesc)
+			tc.mock()
+
+			_, err := adjustmentService.ApproveAdjustment(
+				tc.args.ctx,
+				tc.args.loanID,
+				tc.args.adjustmentID,
+				tc.args.operatorID,
+			)
+
+			if tc.testType == "P" {
+				So(err, ShouldBeNil)
+			} else {
+				So(err, ShouldNotBeNil)
+			}
+		}
+	})
+}
+
+func TestReverseAdjustment(t *testing.T) {
+	provideAdjustmentTest(t)
+
+	Convey("ReverseAdjustment", t, FailureHalts, func() {
+		type (
+			args struct {
+				ctx          context.Context
+				loanID       string
+				adjustmentID string
+				reasonCode   billing.AdjustmentReasonCode
+				operatorID   string
+			}
+		)
+
+		var (
+			ctx  = context.Background()
+			loan = &billing.Loan{
+				ID:              "loan-id",
+				PrincipalAmount: billing.NewAmount(5_000_000),
+				Status:          billing.LoanStatusActive,
+			}
+			approved = billing.Adjustment{
+				ID:          "adjustment-id",
+				LoanID:      loan.ID,
+				ScheduleID:  "schedule-id",
+				Type:        billing.AdjustmentTypeReduceInstallment,
+				Amount:      billing.NewAmount(20_000),
+				Status:      billing.AdjustmentStatusApproved,
+				RequestedBy: "operator-a",
+			}
+			rejected = billing.Adjustment{
+				ID:     "rejected-adjustment-id",
+				LoanID: loan.ID,
+				Status: billing.AdjustmentStatusRejected,
+			}
+			reversal = billing.Adjustment{
+				ID:         "reversal-id",
+				LoanID:     loan.ID,
+				Status:     billing.AdjustmentStatusPendingApproval,
+				ReversesID: &approved.ID,
+			}
+		)
+
+		testCases := []struct {
+			testID   int
+			testDesc string
+			testType string
+			args     args
+			mock     func()
+		}{
+			{
+				testID:   1,
+				testDesc: "success reverse adjustment with a compensating one",
+				testType: "P",
+				args: args{
+					ctx:          ctx,
+					loanID:       loan.ID,
+					adjustmentID: approved.ID,
+					reasonCode:   billing.AdjustmentReasonBillingError,
+					operatorID:   "operator-c",
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByID(ctx, loan.ID).Return(loan, nil)
+					mockAdjustmentStore.EXPECT().ListAdjustmentsByLoanID(ctx, loan.ID).
+						Return([]billing.Adjustment{approved, rejected}, nil)
+					mockAdjustmentStore.EXPECT().CreateAdjustment(ctx, gomock.Any()).
+						Do(func(ctx context.Context, adjustment *billing.Adjustment) {
+							So(*adjustment.ReversesID, ShouldEqual, approved.ID)
+							So(adjustment.ScheduleID, ShouldEqual, approved.ScheduleID)
+							So(adjustment.Type, ShouldEqual, approved.Type)
+							So(adjustment.Amount, ShouldEqual, approved.Amount)
+							So(adjustment.Status, ShouldEqual, billing.AdjustmentStatusPendingApproval)
+						}).Return(nil)
+				},
+			},
+			{
+				testID:   2,
+				testDesc: "failed reverse adjustment not approved",
+				testType: "N",
+				args: args{
+					ctx:          ctx,
+					loanID:       loan.ID,
+					adjustmentID: rejected.ID,
+					reasonCode:   billing.AdjustmentReasonBillingError,
+					operatorID:   "operator-c",
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByID(ctx, loan.ID).Return(loan, nil)
+					mockAdjustmentStore.EXPECT().ListAdjustmentsByLoanID(ctx, loan.ID).
+						Return([]billing.Adjustment{approved, rejected}, nil)
+				},
+			},
+			{
+				testID:   3,
+				testDesc: "failed reverse adjustment already reversed",
+				testType: "N",
+				args: args{
+					ctx:          ctx,
+					loanID:       loan.ID,
+					adjustmentID: approved.ID,
+					reasonCode:   billing.AdjustmentReasonBillingError,
+					operatorID:   "operator-c",
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByID(ctx, loan.ID).Return(loan, nil)
+					mockAdjustmentStore.EXPECT().ListAdjustmentsByLoanID(ctx, loan.ID).
+						Return([]billing.Adjustment{approved, reversal}, nil)
+				},
+			},
+			{
+				testID:   4,
+				testDesc: "failed adjustment not found",
+				testType: "N",
+				args: args{
+					ctx:          ctx,
+					loanID:       loan.ID,
+					adjustmentID: "unknown-adjustment-id",
+					reasonCode:   billing.AdjustmentReasonBillingError,
+					operatorID:   "operator-c",
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByID(ctx, loan.ID).Return(loan, nil)
+					mockAdjustmentStore.EXPECT().ListAdjustmentsByLoanID(ctx, loan.ID).
+						Return([]billing.Adjustment{approved}, nil)
+				},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Logf("%d - [%s] : %s", tc.testID, tc.testType, tc.testDesc)
+			tc.mock()
+
+			_, err := adjustmentService.ReverseAdjustment(
+				tc.args.ctx,
+				tc.args.loanID,
+				tc.args.adjustmentID,
+				tc.args.reasonCode,
+				tc.args.operatorID,
+			)
+
+			if tc.testType == "P" {
+				So(err, ShouldBeNil)
+			} else {
+				So(err, ShouldNotBeNil)
+			}
+		}
+	})
+}
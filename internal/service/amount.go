@@ -5,8 +5,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"math"
+	"math/big"
 	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
 )
@@ -14,26 +15,71 @@ import (
 var (
 	ten int = 10
 
-	CurrencyIDR = "IDR"
+	// decimalPattern is a plain decimal number, no exponents or fractions
+	decimalPattern = regexp.MustCompile(`^-?\d+(\.\d+)?$`)
 )
 
-const DefaultDecimalPrecision = 2
-
+// Amount is a monetary value kept in minor units, Val = 12345 with
+// DecimalPrecision = 2 reads as 123.45. Arithmetic never goes through floats.
 type Amount struct {
 	Val              int    `json:"value"`
 	DecimalPrecision int    `json:"decimal_precision"`
 	Currency         string `json:"currency"`
 }
 
+// NewAmount builds an amount in the default currency from a float literal, it
+// is a convenience for constants; user input should go through ParseAmount.
 func NewAmount(val float64) Amount {
-	formmatter := fmt.Sprintf("%%.%df", DefaultDecimalPrecision)
-	v1 := strings.Replace(fmt.Sprintf(formmatter, val), ".", "", 1)
-	v2, _ := strconv.Atoi(v1)
-	return Amount{
-		Val:              v2,
-		DecimalPrecision: DefaultDecimalPrecision,
-		Currency:         CurrencyIDR,
+	return NewAmountIn(val, DefaultCurrency)
+}
+
+// NewAmountIn is NewAmount for the given currency.
+func NewAmountIn(val float64, currency string) Amount {
+	amount, _ := ParseAmount(strconv.FormatFloat(val, 'f', -1, 64), currency)
+	return amount
+}
+
+// ParseAmount parses a decimal string such as "1500000" or "10.255" into an
+// amount of the given currency, digits beyond the currency minor units are
+// rounded with the currency rounding mode.
+func ParseAmount(s string, currencyCode string) (Amount, error) {
+	currency, err := LookupCurrency(currencyCode)
+	if err != nil {
+		return Amount{}, err
+	}
+
+	trimmed := strings.TrimSpace(s)
+	if !decimalPattern.MatchString(trimmed) {
+		return Amount{}, NewError(
+			ErrValidationError.Error(),
+			fmt.Sprintf("ParseAmount error: %q is not a valid decimal number", s),
+			http.StatusBadRequest,
+		)
+	}
+
+	r, ok := new(big.Rat).SetString(trimmed)
+	if !ok {
+		return Amount{}, NewError(
+			ErrValidationError.Error(),
+			fmt.Sprintf("ParseAmount error: %q is not a valid decimal number", s),
+			http.StatusBadRequest,
+		)
+	}
+
+	val, ok := roundRat(r, currency.MinorUnits, currency.Rounding)
+	if !ok {
+		return Amount{}, NewError(
+			ErrValidationError.Error(),
+			fmt.Sprintf("ParseAmount error: %q is out of range", s),
+			http.StatusBadRequest,
+		)
 	}
+
+	return Amount{
+		Val:              val,
+		DecimalPrecision: currency.MinorUnits,
+		Currency:         currency.Code,
+	}, nil
 }
 
 func (a Amount) Value() (driver.Value, error) {
@@ -49,22 +95,249 @@ func (a *Amount) Scan(value interface{}) error {
 	return json.Unmarshal(b, &a)
 }
 
+// String formats the amount as a plain decimal with as many fraction digits as
+// the currency minor units, e.g. "1500000" for IDR and "12.50" for USD.
 func (a Amount) String() string {
-	return strconv.FormatFloat(a.ToFloat64(), 'f', -1, 64)
+	currency := currencyOf(a)
+	if a.DecimalPrecision == currency.MinorUnits {
+		return a.decimalString()
+	}
+
+	// rounding to fewer minor units only ever shrinks the value, it fits
+	r := new(big.Rat).SetFrac64(int64(a.Val), pow10(a.DecimalPrecision))
+	val, _ := roundRat(r, currency.MinorUnits, currency.Rounding)
+	rounded := Amount{
+		Val:              val,
+		DecimalPrecision: currency.MinorUnits,
+		Currency:         a.Currency,
+	}
+	return rounded.decimalString()
+}
+
+// decimalString formats the amount with exactly DecimalPrecision fraction digits.
+func (a Amount) decimalString() string {
+	sign := ""
+	val := a.Val
+	if val < 0 {
+		sign = "-"
+		val = -val
+	}
+
+	digits := strconv.Itoa(val)
+	if a.DecimalPrecision <= 0 {
+		return sign + digits
+	}
+
+	if len(digits) <= a.DecimalPrecision {
+		digits = strings.Repeat("0", a.DecimalPrecision-len(digits)+1) + digits
+	}
+
+	point := len(digits) - a.DecimalPrecision
+	return sign + digits[:point] + "." + digits[point:]
 }
 
 func (a Amount) EqualTo(b Amount) (bool, error) {
 	if a.Currency != b.Currency {
 		return false, NewError(
-			ErrValidationError.Error(),
+			ErrCurrencyMismatch.Error(),
 			"EqualTo error: amounts needs to have same currency",
 			http.StatusUnprocessableEntity,
 		)
 	}
 
-	return a.ToFloat64() == b.ToFloat64(), nil
+	return a.Cmp(b) == 0, nil
+}
+
+// Add returns a + b. Amounts of different currencies never add up, Add panics
+// on them; amounts coming from a request are checked with EqualTo first.
+func (a Amount) Add(b Amount) Amount {
+	mustShareCurrency("Add", a, b)
+	a, b = rescale(a, b)
+	a.Val += b.Val
+	return a
+}
+
+// Sub returns a - b, panicking like Add on amounts of different currencies.
+func (a Amount) Sub(b Amount) Amount {
+	mustShareCurrency("Sub", a, b)
+	a, b = rescale(a, b)
+	a.Val -= b.Val
+	return a
+}
+
+// Normalized returns the amount in the minor units of its registered currency,
+// rounded with the currency rounding mode. Amounts stored before the precision
+// of their currency changed still carry the old one.
+func (a Amount) Normalized() (Amount, error) {
+	currency := currencyOf(a)
+	if a.DecimalPrecision == currency.MinorUnits {
+		return a, nil
+	}
+
+	r := new(big.Rat).SetFrac64(int64(a.Val), pow10(a.DecimalPrecision))
+	val, ok := roundRat(r, currency.MinorUnits, currency.Rounding)
+	if !ok {
+		return Amount{}, NewError(
+			ErrUnprocessableContentError.Error(),
+			fmt.Sprintf("Normalized error: %s is out of range", a),
+			http.StatusUnprocessableEntity,
+		)
+	}
+
+	a.Val, a.DecimalPrecision = val, currency.MinorUnits
+	return a, nil
+}
+
+// MulRate returns a * rate rounded to the amount precision with the currency
+// rounding mode, or an error when the product does not fit an amount.
+func (a Amount) MulRate(rate float64) (Amount, error) {
+	return a.mulRat(ratFromFloat(rate))
+}
+
+func (a Amount) mulRat(r *big.Rat) (Amount, error) {
+	product := new(big.Rat).Mul(new(big.Rat).SetFrac64(int64(a.Val), pow10(a.DecimalPrecision)), r)
+	val, ok := roundRat(product, a.DecimalPrecision, currencyOf(a).Rounding)
+	if !ok {
+		return Amount{}, NewError(
+			ErrUnprocessableContentError.Error(),
+			fmt.Sprintf("MulRate error: %s * %s is out of range", a, r.RatString()),
+			http.StatusUnprocessableEntity,
+		)
+	}
+	a.Val = val
+	return a, nil
+}
+
+// Allocate splits the amount into n parts that add up exactly to the amount,
+// the minor units left over by the division go one by one to the first parts.
+func (a Amount) Allocate(n int) []Amount {
+	if n <= 0 {
+		return nil
+	}
+
+	parts := make([]Amount, n)
+	quotient, remainder := a.Val/n, a.Val%n
+	for i := range parts {
+		parts[i] = a
+		parts[i].Val = quotient
+		if i < abs(remainder) {
+			if remainder > 0 {
+				parts[i].Val++
+			} else {
+				parts[i].Val--
+			}
+		}
+	}
+
+	return parts
+}
+
+// Cmp compares a and b and returns -1, 0 or +1.
+func (a Amount) Cmp(b Amount) int {
+	a, b = rescale(a, b)
+	switch {
+	case a.Val < b.Val:
+		return -1
+	case a.Val > b.Val:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (a Amount) IsZero() bool {
+	return a.Val == 0
+}
+
+// Min returns the smaller of a and b.
+func (a Amount) Min(b Amount) Amount {
+	if a.Cmp(b) <= 0 {
+		return a
+	}
+	return b
+}
+
+// ZeroLike returns a zero amount in the currency and precision of a.
+func (a Amount) ZeroLike() Amount {
+	a.Val = 0
+	return a
+}
+
+// mustShareCurrency panics when a and b are in different currencies, a sum of
+// them would mean nothing.
+func mustShareCurrency(op string, a, b Amount) {
+	if a.Currency != b.Currency {
+		panic(fmt.Sprintf("Amount.%s error: %s and %s amounts do not mix", op, a.Currency, b.Currency))
+	}
+}
+
+// rescale brings both amounts to the same decimal precision.
+func rescale(a, b Amount) (Amount, Amount) {
+	for a.DecimalPrecision < b.DecimalPrecision {
+		a.Val *= ten
+		a.DecimalPrecision++
+	}
+	for b.DecimalPrecision < a.DecimalPrecision {
+		b.Val *= ten
+		b.DecimalPrecision++
+	}
+	return a, b
+}
+
+// roundRat converts r to minor units of the given precision using the rounding
+// mode, ok is false when the result does not fit in an int.
+func roundRat(r *big.Rat, precision int, mode RoundingMode) (val int, ok bool) {
+	scaled := new(big.Rat).Mul(r, new(big.Rat).SetInt64(pow10(precision)))
+
+	// quotient is truncated towards zero
+	quotient, remainder := new(big.Int).QuoRem(scaled.Num(), scaled.Denom(), new(big.Int))
+	if remainder.Sign() == 0 || mode == RoundingDown {
+		return intOf(quotient)
+	}
+
+	// compare |remainder| * 2 against the denominator to know where the half is
+	half := new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2)).Cmp(scaled.Denom())
+
+	roundAway := half > 0
+	if half == 0 {
+		switch mode {
+		case RoundingHalfEven:
+			roundAway = quotient.Bit(0) == 1
+		default:
+			roundAway = true
+		}
+	}
+
+	if roundAway {
+		if scaled.Sign() < 0 {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+
+	return intOf(quotient)
+}
+
+// intOf converts n to an int, ok is false when it does not fit.
+func intOf(n *big.Int) (int, bool) {
+	if !n.IsInt64() || int64(int(n.Int64())) != n.Int64() {
+		return 0, false
+	}
+	return int(n.Int64()), true
+}
+
+func pow10(n int) int64 {
+	p := int64(1)
+	for i := 0; i < n; i++ {
+		p *= int64(ten)
+	}
+	return p
 }
 
-func (a Amount) ToFloat64() float64 {
-	return float64(a.Val) / math.Pow(float64(ten), float64(a.DecimalPrecision))
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
 }
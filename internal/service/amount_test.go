@@ -0,0 +1,134 @@
+package billing_test
+
+import (
+	"testing"
+
+	billing "github.com/theyudiriski/billing-service/internal/service"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAmount(t *testing.T) {
+	usd := func(val float64) billing.Amount {
+		return billing.NewAmountIn(val, billing.CurrencyUSD)
+	}
+
+	Convey("ParseAmount", t, func() {
+		amount, err := billing.ParseAmount("10.255", billing.CurrencySGD)
+		So(err, ShouldBeNil)
+		So(amount.Val, ShouldEqual, 1026)
+		So(amount.DecimalPrecision, ShouldEqual, 2)
+
+		amount, err = billing.ParseAmount("1500000.5", billing.CurrencyIDR)
+		So(err, ShouldBeNil)
+		So(amount.Val, ShouldEqual, 1_500_001)
+		So(amount.DecimalPrecision, ShouldEqual, 0)
+		So(amount.String(), ShouldEqual, "1500001")
+
+		// half even rounding for USD
+		amount, err = billing.ParseAmount("10.245", "usd")
+		So(err, ShouldBeNil)
+		So(amount, ShouldEqual, usd(10.24))
+
+		_, err = billing.ParseAmount("ten", billing.CurrencyIDR)
+		So(err, ShouldNotBeNil)
+
+		_, err = billing.ParseAmount("10", "XYZ")
+		So(err, ShouldNotBeNil)
+
+		// fractions and exponents are not decimals
+		_, err = billing.ParseAmount("1/3", billing.CurrencyIDR)
+		So(err, ShouldNotBeNil)
+
+		_, err = billing.ParseAmount("1e6", billing.CurrencyIDR)
+		So(err, ShouldNotBeNil)
+
+		_, err = billing.ParseAmount("99999999999999999999", billing.CurrencyIDR)
+		So(err, ShouldNotBeNil)
+
+		amount, err = billing.ParseAmount("-5.5", billing.CurrencyIDR)
+		So(err, ShouldBeNil)
+		So(amount.Val, ShouldEqual, -6)
+	})
+
+	Convey("Add, Sub and Cmp", t, func() {
+		a := usd(0.1)
+		b := usd(0.2)
+
+		So(a.Add(b), ShouldEqual, usd(0.3))
+		So(b.Sub(a), ShouldEqual, usd(0.1))
+		So(a.Cmp(b), ShouldEqual, -1)
+		So(b.Cmp(a), ShouldEqual, 1)
+		So(a.Add(b).Cmp(usd(0.3)), ShouldEqual, 0)
+
+		// amounts with different precision are compared on the finer one
+		c := billing.Amount{Val: 3, DecimalPrecision: 1, Currency: billing.CurrencyUSD}
+		So(c.Cmp(usd(0.3)), ShouldEqual, 0)
+
+		// amounts of different currencies never add up
+		So(func() { a.Add(billing.NewAmount(1)) }, ShouldPanic)
+		So(func() { a.Sub(billing.NewAmount(1)) }, ShouldPanic)
+	})
+
+	Convey("Normalized", t, func() {
+		// IDR once stored with two decimals
+		stored := billing.Amount{Val: 1_500_050, DecimalPrecision: 2, Currency: billing.CurrencyIDR}
+		amount, err := stored.Normalized()
+		So(err, ShouldBeNil)
+		So(amount, ShouldEqual, billing.NewAmount(15_001))
+
+		amount, err = usd(10.5).Normalized()
+		So(err, ShouldBeNil)
+		So(amount, ShouldEqual, usd(10.5))
+	})
+
+	Convey("EqualTo", t, func() {
+		isEqual, err := usd(10).EqualTo(usd(10))
+		So(err, ShouldBeNil)
+		So(isEqual, ShouldBeTrue)
+
+		_, err = usd(10).EqualTo(billing.NewAmount(10))
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("MulRate", t, func() {
+		mulRate := func(a billing.Amount, rate float64) billing.Amount {
+			product, err := a.MulRate(rate)
+			So(err, ShouldBeNil)
+			return product
+		}
+
+		So(mulRate(billing.NewAmount(5_000_000), 0.1), ShouldEqual, billing.NewAmount(500_000))
+		So(mulRate(billing.NewAmount(15), 0.1), ShouldEqual, billing.NewAmount(2))
+		So(mulRate(usd(10.01), 0.5), ShouldEqual, usd(5.00))
+		So(mulRate(usd(10.03), 0.5), ShouldEqual, usd(5.02))
+
+		_, err := billing.NewAmount(5_000_000).MulRate(1e15)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Allocate", t, func() {
+		parts := usd(100).Allocate(3)
+		So(parts, ShouldResemble, []billing.Amount{
+			usd(33.34),
+			usd(33.33),
+			usd(33.33),
+		})
+
+		total := usd(0)
+		for _, part := range parts {
+			total = total.Add(part)
+		}
+		So(total, ShouldEqual, usd(100))
+	})
+
+	Convey("String", t, func() {
+		So(billing.NewAmount(110_000).String(), ShouldEqual, "110000")
+		So(usd(0.05).String(), ShouldEqual, "0.05")
+		So(usd(-1.5).String(), ShouldEqual, "-1.50")
+
+		// legacy IDR amounts kept in cents are rendered in rupiah
+		legacy := billing.Amount{Val: 11_000_050, DecimalPrecision: 2, Currency: billing.CurrencyIDR}
+		So(legacy.String(), ShouldEqual, "110001")
+	})
+}
@@ -0,0 +1,281 @@
+package billing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type BorrowerService interface {
+	CreateBorrower(
+		ctx context.Context,
+		fullName string,
+		identityType IdentityType,
+		identityNumber string,
+		dateOfBirth time.Time,
+		email string,
+		phoneNumber string,
+		address string,
+	) (*Borrower, error)
+	GetBorrower(ctx context.Context, borrowerID string) (*Borrower, error)
+	UpdateKYCStatus(ctx context.Context, borrowerID string, status KYCStatus) (*Borrower, error)
+}
+
+type BorrowerStore interface {
+	// CreateBorrower fails with ErrBorrowerAlreadyExists when the identity is
+	// already registered.
+	CreateBorrower(ctx context.Context, borrower *Borrower) error
+	GetBorrowerByID(ctx context.Context, borrowerID string) (*Borrower, error)
+	// GetBorrowerByIDForUpdate returns the borrower and locks it until the
+	// transaction ends.
+	GetBorrowerByIDForUpdate(ctx context.Context, borrowerID string) (*Borrower, error)
+	// UpdateKYCStatus moves the borrower from one KYC status to another, failing
+	// when the borrower is no longer in the expected status.
+	UpdateKYCStatus(ctx context.Context, borrower *Borrower, from KYCStatus) error
+}
+
+func NewBorrowerService(
+	logger Logger,
+	txManager TxManager,
+	borrowerStore BorrowerStore,
+) BorrowerService {
+	return &borrowerService{
+		logger:        logger,
+		txManager:     txManager,
+		borrowerStore: borrowerStore,
+	}
+}
+
+type borrowerService struct {
+	logger        Logger
+	txManager     TxManager
+	borrowerStore BorrowerStore
+}
+
+type Borrower struct {
+	ID       string
+	FullName string
+
+	// identity document the borrower registered with, unique per type
+	IdentityType   IdentityType
+	IdentityNumber string
+	DateOfBirth    time.Time
+
+	// contact info
+	Email       string
+	PhoneNumber string
+	Address     string
+
+	KYCStatus    KYCStatus
+	KYCUpdatedAt *time.Time
+	CreatedAt    time.Time
+}
+
+type (
+	IdentityType string
+	KYCStatus    string
+)
+
+var (
+	IdentityTypeNationalID IdentityType = "national_id"
+	IdentityTypePassport   IdentityType = "passport"
+
+	IdentityTypes = []IdentityType{
+		IdentityTypeNationalID,
+		IdentityTypePassport,
+	}
+
+	// registered, waiting for the identity to be checked
+	KYCStatusPending  KYCStatus = "pending"
+	KYCStatusVerified KYCStatus = "verified"
+	KYCStatusRejected KYCStatus = "rejected"
+
+	KYCStatuses = []KYCStatus{
+		KYCStatusPending,
+		KYCStatusVerified,
+		KYCStatusRejected,
+	}
+)
+
+func (t IdentityType) IsValid() bool {
+	for _, identityType := range IdentityTypes {
+		if identityType == t {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *IdentityType) UnmarshalText(text []byte) error {
+	for _, identityType := range IdentityTypes {
+		if strings.EqualFold(string(identityType), string(text)) {
+			*t = identityType
+			return nil
+		}
+	}
+	return NewError(
+		ErrValidationError.Error(),
+		fmt.Sprintf("IdentityType should be one of %v", IdentityTypes),
+		http.StatusBadRequest,
+	)
+}
+
+func (s KYCStatus) IsValid() bool {
+	for _, status := range KYCStatuses {
+		if status == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *KYCStatus) UnmarshalText(text []byte) error {
+	for _, status := range KYCStatuses {
+		if strings.EqualFold(string(status), string(text)) {
+			*s = status
+			return nil
+		}
+	}
+	return NewError(
+		ErrValidationError.Error(),
+		fmt.Sprintf("KYCStatus should be one of %v", KYCStatuses),
+		http.StatusBadRequest,
+	)
+}
+
+// CanTransitionTo reports whether the KYC review may move from s to next. A
+// pending review is decided once, a rejected borrower may resubmit and a
+// verified one is sent back for review when their identity needs rechecking.
+func (s KYCStatus) CanTransitionTo(next KYCStatus) bool {
+	switch s {
+	case KYCStatusPending:
+		return next == KYCStatusVerified || next == KYCStatusRejected
+	case KYCStatusVerified, KYCStatusRejected:
+		return next == KYCStatusPending
+	default:
+		return false
+	}
+}
+
+func (s *borrowerService) CreateBorrower(
+	ctx context.Context,
+	fullName string,
+	identityType IdentityType,
+	identityNumber string,
+	dateOfBirth time.Time,
+	email string,
+	phoneNumber string,
+	address string,
+) (*Borrower, error) {
+	if !identityType.IsValid() {
+		return nil, NewError(
+			ErrValidationError.Error(),
+			fmt.Sprintf("IdentityType should be one of %v", IdentityTypes),
+			http.StatusBadRequest,
+		)
+	}
+
+	if !dateOfBirth.Before(CurrentLocalTime()) {
+		return nil, NewError(
+			ErrValidationError.Error(),
+			"date of birth must be in the past",
+			http.StatusBadRequest,
+		)
+	}
+
+	borrower := &Borrower{
+		ID:             UUID(),
+		FullName:       fullName,
+		IdentityType:   identityType,
+		IdentityNumber: identityNumber,
+		DateOfBirth:    dateOfBirth,
+		Email:          email,
+		PhoneNumber:    phoneNumber,
+		Address:        address,
+		KYCStatus:      KYCStatusPending,
+		CreatedAt:      CurrentLocalTime(),
+	}
+
+	if err := s.borrowerStore.CreateBorrower(ctx, borrower); err != nil {
+		s.logger.WarnContext(ctx, "failed to create borrower", "error", err)
+		return nil, err
+	}
+
+	return borrower, nil
+}
+
+func (s *borrowerService) GetBorrower(
+	ctx context.Context,
+	borrowerID string,
+) (*Borrower, error) {
+	borrower, err := s.borrowerStore.GetBorrowerByID(ctx, borrowerID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to get borrower", "error", err)
+		return nil, err
+	}
+
+	return borrower, nil
+}
+
+func (s *borrowerService) UpdateKYCStatus(
+	ctx context.Context,
+	borrowerID string,
+	status KYCStatus,
+) (*Borrower, error) {
+	if !status.IsValid() {
+		return nil, NewError(
+			ErrValidationError.Error(),
+			fmt.Sprintf("KYCStatus should be one of %v", KYCStatuses),
+			http.StatusBadRequest,
+		)
+	}
+
+	// the transition is checked against the status on the leader, not a
+	// lagging follower
+	var borrower *Borrower
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		borrower, err = s.updateKYCStatus(ctx, borrowerID, status)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return borrower, nil
+}
+
+func (s *borrowerService) updateKYCStatus(
+	ctx context.Context,
+	borrowerID string,
+	status KYCStatus,
+) (*Borrower, error) {
+	borrower, err := s.borrowerStore.GetBorrowerByID(ctx, borrowerID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to get borrower", "error", err)
+		return nil, err
+	}
+
+	if !borrower.KYCStatus.CanTransitionTo(status) {
+		s.logger.WarnContext(ctx, "invalid kyc status transition", "from", borrower.KYCStatus, "to", status)
+		return nil, NewError(
+			ErrInvalidKYCStatusTransition.Error(),
+			fmt.Sprintf("KYC status cannot move from %s to %s", borrower.KYCStatus, status),
+			http.StatusUnprocessableEntity,
+		)
+	}
+
+	from := borrower.KYCStatus
+	updatedAt := CurrentLocalTime()
+	borrower.KYCStatus = status
+	borrower.KYCUpdatedAt = &updatedAt
+
+	if err := s.borrowerStore.UpdateKYCStatus(ctx, borrower, from); err != nil {
+		s.logger.WarnContext(ctx, "failed to update kyc status", "error", err)
+		return nil, err
+	}
+
+	return borrower, nil
+}
@@ -0,0 +1,262 @@
+package billing_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mock_billing "github.com/theyudiriski/billing-service/internal/service/mock"
+
+	billing "github.com/theyudiriski/billing-service/internal/service"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var (
+	borrowerService billing.BorrowerService
+)
+
+func provideBorrowerTest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	mockTxManager = newMockTxManager(ctrl)
+	mockBorrowerStore = mock_billing.NewMockBorrowerStore(ctrl)
+
+	borrowerService = billing.NewBorrowerService(
+		billing.NewLogger(),
+		mockTxManager,
+		mockBorrowerStore,
+	)
+}
+
+func TestCreateBorrower(t *testing.T) {
+	provideBorrowerTest(t)
+
+	Convey("CreateBorrower", t, FailureHalts, func() {
+		type (
+			args struct {
+				ctx            context.Context
+				fullName       string
+				identityType   billing.IdentityType
+				identityNumber string
+				dateOfBirth    time.Time
+				email          string
+				phoneNumber    string
+				address        string
+			}
+		)
+
+		var (
+			ctx         = context.Background()
+			dateOfBirth = time.Date(1990, 1, 31, 0, 0, 0, 0, time.UTC)
+		)
+
+		testCases := []struct {
+			testID      int
+			testDesc    string
+			testType    string
+			args        args
+			mock        func()
+			expectedErr error
+		}{
+			{
+				testID:   1,
+				testDesc: "success create borrower pending kyc",
+				testType: "P",
+				args: args{
+					ctx:            ctx,
+					fullName:       "Jane Doe",
+					identityType:   billing.IdentityTypeNationalID,
+					identityNumber: "3171234567890001",
+					dateOfBirth:    dateOfBirth,
+					email:          "jane@example.com",
+					phoneNumber:    "+6281234567890",
+				},
+				mock: func() {
+					mockBorrowerStore.EXPECT().CreateBorrower(ctx, gomock.Any()).
+						Do(func(ctx context.Context, borrower *billing.Borrower) {
+							So(borrower.ID, ShouldNotBeEmpty)
+							So(borrower.FullName, ShouldEqual, "Jane Doe")
+							So(borrower.IdentityNumber, ShouldEqual, "3171234567890001")
+							So(borrower.KYCStatus, ShouldEqual, billing.KYCStatusPending)
+							So(borrower.KYCUpdatedAt, ShouldBeNil)
+						}).Return(nil)
+				},
+			},
+			{
+				testID:   2,
+				testDesc: "failed identity already registered",
+				testType: "N",
+				args: args{
+					ctx:            ctx,
+					fullName:       "Jane Doe",
+					identityType:   billing.IdentityTypeNationalID,
+					identityNumber: "3171234567890001",
+					dateOfBirth:    dateOfBirth,
+					email:          "jane@example.com",
+					phoneNumber:    "+6281234567890",
+				},
+				mock: func() {
+					mockBorrowerStore.EXPECT().CreateBorrower(ctx, gomock.Any()).Return(billing.ErrBorrowerAlreadyExists)
+				},
+				expectedErr: billing.ErrBorrowerAlreadyExists,
+			},
+			{
+				testID:   3,
+				testDesc: "failed date of birth in the future",
+				testType: "N",
+				args: args{
+					ctx:            ctx,
+					fullName:       "Jane Doe",
+					identityType:   billing.IdentityTypePassport,
+					identityNumber: "A1234567",
+					dateOfBirth:    billing.CurrentLocalTime().AddDate(0, 0, 1),
+					email:          "jane@example.com",
+					phoneNumber:    "+6281234567890",
+				},
+				mock: func() {},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Logf("%d - [%s] : %s", tc.testID, tc.testType, tc.testDesc)
+			tc.mock()
+
+			_, err := borrowerService.CreateBorrower(
+				tc.args.ctx,
+				tc.args.fullName,
+				tc.args.identityType,
+				tc.args.identityNumber,
+				tc.args.dateOfBirth,
+				tc.args.email,
+				tc.args.phoneNumber,
+				tc.args.address,
+			)
+
+			if tc.testType == "P" {
+				So(err, ShouldBeNil)
+			} else {
+				So(err, ShouldNotBeNil)
+				if tc.expectedErr != nil {
+					So(err, ShouldEqual, tc.expectedErr)
+				}
+			}
+		}
+	})
+}
+
+func TestUpdateKYCStatus(t *testing.T) {
+	provideBorrowerTest(t)
+
+	Convey("UpdateKYCStatus", t, FailureHalts, func() {
+		type (
+			args struct {
+				ctx        context.Context
+				borrowerID string
+				status     billing.KYCStatus
+			}
+		)
+
+		var (
+			ctx        = context.Background()
+			borrowerID = "borrower-id"
+
+			borrowerWithStatus = func(status billing.KYCStatus) *billing.Borrower {
+				return &billing.Borrower{
+					ID:        borrowerID,
+					KYCStatus: status,
+				}
+			}
+		)
+
+		testCases := []struct {
+			testID      int
+			testDesc    string
+			testType    string
+			args        args
+			mock        func()
+			expectedErr error
+		}{
+			{
+				testID:   1,
+				testDesc: "success verify pending borrower",
+				testType: "P",
+				args: args{
+					ctx:        ctx,
+					borrowerID: borrowerID,
+					status:     billing.KYCStatusVerified,
+				},
+				mock: func() {
+					mockBorrowerStore.EXPECT().GetBorrowerByID(ctx, borrowerID).Return(borrowerWithStatus(billing.KYCStatusPending), nil)
+					mockBorrowerStore.EXPECT().UpdateKYCStatus(ctx, gomock.Any(), billing.KYCStatusPending).
+						Do(func(ctx context.Context, borrower *billing.Borrower, from billing.KYCStatus) {
+							So(borrower.KYCStatus, ShouldEqual, billing.KYCStatusVerified)
+							So(borrower.KYCUpdatedAt, ShouldNotBeNil)
+						}).Return(nil)
+				},
+			},
+			{
+				testID:   2,
+				testDesc: "success rejected borrower resubmits",
+				testType: "P",
+				args: args{
+					ctx:        ctx,
+					borrowerID: borrowerID,
+					status:     billing.KYCStatusPending,
+				},
+				mock: func() {
+					mockBorrowerStore.EXPECT().GetBorrowerByID(ctx, borrowerID).Return(borrowerWithStatus(billing.KYCStatusRejected), nil)
+					mockBorrowerStore.EXPECT().UpdateKYCStatus(ctx, gomock.Any(), billing.KYCStatusRejected).Return(nil)
+				},
+			},
+			{
+				testID:   3,
+				testDesc: "failed rejected borrower cannot be verified without review",
+				testType: "N",
+				args: args{
+					ctx:        ctx,
+					borrowerID: borrowerID,
+					status:     billing.KYCStatusVerified,
+				},
+				mock: func() {
+					mockBorrowerStore.EXPECT().GetBorrowerByID(ctx, borrowerID).Return(borrowerWithStatus(billing.KYCStatusRejected), nil)
+				},
+			},
+			{
+				testID:   4,
+				testDesc: "failed borrower not found",
+				testType: "N",
+				args: args{
+					ctx:        ctx,
+					borrowerID: borrowerID,
+					status:     billing.KYCStatusVerified,
+				},
+				mock: func() {
+					mockBorrowerStore.EXPECT().GetBorrowerByID(ctx, borrowerID).Return(nil, billing.ErrBorrowerNotFound)
+				},
+				expectedErr: billing.ErrBorrowerNotFound,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Logf("%d - [%s] : %s", tc.testID, tc.testType, tc.testDesc)
+			tc.mock()
+
+			_, err := borrowerService.UpdateKYCStatus(
+				tc.args.ctx,
+				tc.args.borrowerID,
+				tc.args.status,
+			)
+
+			if tc.testType == "P" {
+				So(err, ShouldBeNil)
+			} else {
+				So(err, ShouldNotBeNil)
+				if tc.expectedErr != nil {
+					So(err, ShouldEqual, tc.expectedErr)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,137 @@
+package billing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// LoanCancellation records why and when a loan was cancelled, and what the
+// borrower owes back for it.
+type LoanCancellation struct {
+	Reason      string
+	CancelledAt time.Time
+	// disbursed principal the borrower has to return, no interest or fees are
+	// owed on a cancelled loan and what was paid on it counts towards this
+	PrincipalToReturn Amount
+	// paid beyond the disbursed principal, to be refunded to the borrower
+	RefundAmount Amount
+}
+
+// CancelLoan cancels a loan before it is disbursed, or within the cooling-off
+// period after. Its schedules are voided.
+func (s *loanService) CancelLoan(
+	ctx context.Context,
+	loanID string,
+	reason string,
+) (*Loan, error) {
+	if reason == "" {
+		return nil, NewError(
+			ErrValidationError.Error(),
+			"cancellation reason is required",
+			http.StatusBadRequest,
+		)
+	}
+
+	var loan *Loan
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		loan, err = s.cancelLoan(ctx, loanID, reason)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return loan, nil
+}
+
+func (s *loanService) cancelLoan(
+	ctx context.Context,
+	loanID string,
+	reason string,
+) (*Loan, error) {
+	loan, err := s.loanStore.GetLoanByIDForUpdate(ctx, loanID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to get loan", "error", err)
+		return nil, err
+	}
+
+	now := CurrentLocalTime()
+	if err := s.checkCancellable(loan, now); err != nil {
+		s.logger.WarnContext(ctx, "loan is not cancellable", "status", loan.Status, "error", err)
+		return nil, err
+	}
+
+	cancellation := &LoanCancellation{
+		Reason:            reason,
+		CancelledAt:       now,
+		PrincipalToReturn: loan.PrincipalAmount.ZeroLike(),
+		RefundAmount:      loan.PrincipalAmount.ZeroLike(),
+	}
+
+	// nothing reached the borrower before disbursement, so nothing is owed back
+	if loan.Status != LoanStatusPendingDisbursement {
+		schedules, err := s.loanStore.ListSchedules(ctx, loan.ID, LoanScheduleFilter{})
+		if err != nil {
+			s.logger.WarnContext(ctx, "failed to list schedules", "error", err)
+			return nil, err
+		}
+
+		paid := loan.PrincipalAmount.ZeroLike()
+		for _, schedule := range schedules {
+			paid = paid.Add(schedule.PaidAmount)
+		}
+
+		disbursed := loan.PrincipalAmount
+		if loan.Disbursement != nil {
+			disbursed = loan.Disbursement.Amount
+		}
+
+		if paid.Cmp(disbursed) > 0 {
+			cancellation.RefundAmount = paid.Sub(disbursed)
+		} else {
+			cancellation.PrincipalToReturn = disbursed.Sub(paid)
+		}
+	}
+
+	from := loan.Status
+	loan.Status = LoanStatusCancelled
+	loan.Cancellation = cancellation
+
+	if err := s.loanStore.CancelLoan(ctx, loan, from); err != nil {
+		s.logger.WarnContext(ctx, "failed to cancel loan", "error", err)
+		return nil, err
+	}
+
+	return loan, nil
+}
+
+// checkCancellable tells whether the loan may still be cancelled at now. A
+// delinquent loan is past any cooling-off period.
+func (s *loanService) checkCancellable(loan *Loan, now time.Time) error {
+	if loan.Status == LoanStatusPendingDisbursement {
+		return nil
+	}
+
+	if loan.Status != LoanStatusActive {
+		return ErrLoanNotCancellable
+	}
+
+	// loans disbursed before disbursements were recorded started on disbursement
+	disbursedAt := loan.StartedAt
+	if loan.Disbursement != nil {
+		disbursedAt = loan.Disbursement.DisbursedAt
+	}
+
+	if !now.Before(disbursedAt.AddDate(0, 0, s.coolOffDays)) {
+		return NewError(
+			ErrLoanNotCancellable.Error(),
+			fmt.Sprintf("loan can only be cancelled within %d days of disbursement", s.coolOffDays),
+			http.StatusUnprocessableEntity,
+		)
+	}
+
+	return nil
+}
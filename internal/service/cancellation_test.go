@@ -0,0 +1,201 @@
+package billing_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	billing "github.com/theyudiriski/billing-service/internal/service"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCancelLoan(t *testing.T) {
+	provideLoanTest(t)
+
+	Convey("CancelLoan", t, FailureHalts, func() {
+		type (
+			args struct {
+				ctx    context.Context
+				loanID string
+				reason string
+			}
+		)
+
+		var (
+			ctx    = context.Background()
+			loanID = "loan-id"
+			reason = "borrower changed their mind"
+
+			loanWithStatus = func(status billing.LoanStatus, disbursedDaysAgo int) *billing.Loan {
+				loan := &billing.Loan{
+					ID:              loanID,
+					PrincipalAmount: billing.NewAmount(1_000_000),
+					StartedAt:       billing.CurrentLocalTime().AddDate(0, 0, -disbursedDaysAgo),
+					Status:          status,
+				}
+				if status != billing.LoanStatusPendingDisbursement {
+					loan.Disbursement = &billing.LoanDisbursement{
+						Amount:      billing.NewAmount(1_000_000),
+						Reference:   "payout-1",
+						DisbursedAt: loan.StartedAt,
+					}
+				}
+				return loan
+			}
+
+			paidSchedules = func(paid ...float64) []billing.LoanSchedule {
+				schedules := make([]billing.LoanSchedule, 0, len(paid))
+				for i, amount := range paid {
+					schedules = append(schedules, billing.LoanSchedule{
+						LoanID:     loanID,
+						Seq:        i + 1,
+						PaidAmount: billing.NewAmount(amount),
+					})
+				}
+				return schedules
+			}
+		)
+
+		testCases := []struct {
+			testID      int
+			testDesc    string
+			testType    string
+			args        args
+			mock        func()
+			expectedErr error
+		}{
+			{
+				testID:   1,
+				testDesc: "success cancel before disbursement, nothing to return",
+				testType: "P",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+					reason: reason,
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(loanWithStatus(billing.LoanStatusPendingDisbursement, 0), nil)
+					mockLoanStore.EXPECT().CancelLoan(ctx, gomock.Any(), billing.LoanStatusPendingDisbursement).
+						Do(func(ctx context.Context, loan *billing.Loan, from billing.LoanStatus) {
+							So(loan.Status, ShouldEqual, billing.LoanStatusCancelled)
+							So(loan.Cancellation.Reason, ShouldEqual, reason)
+							So(loan.Cancellation.PrincipalToReturn, ShouldEqual, billing.NewAmount(0))
+							So(loan.Cancellation.RefundAmount, ShouldEqual, billing.NewAmount(0))
+						}).Return(nil)
+				},
+			},
+			{
+				testID:   2,
+				testDesc: "success cancel within cooling-off, payments count towards the principal",
+				testType: "P",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+					reason: reason,
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(loanWithStatus(billing.LoanStatusActive, 2), nil)
+					mockLoanStore.EXPECT().ListSchedules(ctx, loanID, billing.LoanScheduleFilter{}).Return(paidSchedules(110_000, 0, 0), nil)
+					mockLoanStore.EXPECT().CancelLoan(ctx, gomock.Any(), billing.LoanStatusActive).
+						Do(func(ctx context.Context, loan *billing.Loan, from billing.LoanStatus) {
+							So(loan.Status, ShouldEqual, billing.LoanStatusCancelled)
+							So(loan.Cancellation.PrincipalToReturn, ShouldEqual, billing.NewAmount(890_000))
+							So(loan.Cancellation.RefundAmount, ShouldEqual, billing.NewAmount(0))
+						}).Return(nil)
+				},
+			},
+			{
+				testID:   3,
+				testDesc: "success cancel within cooling-off, paid beyond the principal is refunded",
+				testType: "P",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+					reason: reason,
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(loanWithStatus(billing.LoanStatusActive, 2), nil)
+					mockLoanStore.EXPECT().ListSchedules(ctx, loanID, billing.LoanScheduleFilter{}).Return(paidSchedules(550_000, 550_000), nil)
+					mockLoanStore.EXPECT().CancelLoan(ctx, gomock.Any(), billing.LoanStatusActive).
+						Do(func(ctx context.Context, loan *billing.Loan, from billing.LoanStatus) {
+							So(loan.Cancellation.PrincipalToReturn, ShouldEqual, billing.NewAmount(0))
+							So(loan.Cancellation.RefundAmount, ShouldEqual, billing.NewAmount(100_000))
+						}).Return(nil)
+				},
+			},
+			{
+				testID:   4,
+				testDesc: "failed cooling-off period is over",
+				testType: "N",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+					reason: reason,
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(loanWithStatus(billing.LoanStatusActive, 14), nil)
+				},
+			},
+			{
+				testID:   5,
+				testDesc: "failed delinquent loan",
+				testType: "N",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+					reason: reason,
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(loanWithStatus(billing.LoanStatusDelinquent, 2), nil)
+				},
+				expectedErr: billing.ErrLoanNotCancellable,
+			},
+			{
+				testID:   6,
+				testDesc: "failed already cancelled",
+				testType: "N",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+					reason: reason,
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(loanWithStatus(billing.LoanStatusCancelled, 2), nil)
+				},
+				expectedErr: billing.ErrLoanNotCancellable,
+			},
+			{
+				testID:   7,
+				testDesc: "failed without a reason",
+				testType: "N",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+				},
+				mock: func() {},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Logf("%d - [%s] : %s", tc.testID, tc.testType, tc.testDesc)
+			tc.mock()
+
+			_, err := loanService.CancelLoan(
+				tc.args.ctx,
+				tc.args.loanID,
+				tc.args.reason,
+			)
+
+			if tc.testType == "P" {
+				So(err, ShouldBeNil)
+			} else {
+				So(err, ShouldNotBeNil)
+				if tc.expectedErr != nil {
+					So(err, ShouldEqual, tc.expectedErr)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package billing
+
+import (
+	"errors"
+	"fmt"
+)
+
+// CreditPolicy limits what a borrower may owe before a new loan is granted. A
+// zero value grants every loan.
+type CreditPolicy struct {
+	// loans a borrower may be repaying at once, 0 means unlimited
+	MaxActiveLoans int
+	// principal a borrower may owe across their loans, the new one included,
+	// keyed by currency code; a currency without a limit is unlimited
+	MaxOutstandingPrincipal map[string]Amount
+	// refuse new loans while any loan of the borrower is delinquent
+	BlockDelinquent bool
+}
+
+// NewCreditPolicy builds the policy from plain config values,
+// maxOutstandingPrincipal maps a currency code to a decimal amount.
+func NewCreditPolicy(
+	maxActiveLoans int,
+	maxOutstandingPrincipal map[string]string,
+	blockDelinquent bool,
+) (CreditPolicy, error) {
+	if maxActiveLoans < 0 {
+		return CreditPolicy{}, errors.New("credit policy max active loans must not be negative")
+	}
+
+	policy := CreditPolicy{
+		MaxActiveLoans:          maxActiveLoans,
+		MaxOutstandingPrincipal: map[string]Amount{},
+		BlockDelinquent:         blockDelinquent,
+	}
+
+	for currency, value := range maxOutstandingPrincipal {
+		amount, err := ParseAmount(value, currency)
+		if err != nil {
+			return CreditPolicy{}, fmt.Errorf("credit policy max outstanding principal for %s: %w", currency, err)
+		}
+		policy.MaxOutstandingPrincipal[currency] = amount
+	}
+
+	return policy, nil
+}
+
+// check returns why a borrower with the given exposure may not take a new loan
+// of principalAmount, or nil when they may.
+func (p CreditPolicy) check(exposure *BorrowerExposure, principalAmount Amount) error {
+	if p.BlockDelinquent && exposure.DelinquentLoans > 0 {
+		return ErrBorrowerDelinquent
+	}
+
+	if p.MaxActiveLoans > 0 && exposure.ActiveLoans >= p.MaxActiveLoans {
+		return ErrActiveLoanLimitExceeded
+	}
+
+	limit, ok := p.MaxOutstandingPrincipal[principalAmount.Currency]
+	if !ok {
+		return nil
+	}
+
+	outstanding := principalAmount
+	for _, c := range exposure.Currencies {
+		if c.PrincipalOutstanding.Currency == principalAmount.Currency {
+			outstanding = outstanding.Add(c.PrincipalOutstanding)
+		}
+	}
+
+	if outstanding.Cmp(limit) > 0 {
+		return ErrOutstandingLimitExceeded
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package billing
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+type (
+	RoundingMode string
+)
+
+var (
+	// RoundingHalfUp rounds halves away from zero, 0.5 -> 1 and -0.5 -> -1.
+	RoundingHalfUp RoundingMode = "half_up"
+	// RoundingHalfEven rounds halves to the nearest even digit, 0.5 -> 0 and 1.5 -> 2.
+	RoundingHalfEven RoundingMode = "half_even"
+	// RoundingDown truncates towards zero.
+	RoundingDown RoundingMode = "down"
+)
+
+// Currency describes how amounts of an ISO 4217 currency are kept and rounded.
+type Currency struct {
+	Code       string
+	MinorUnits int
+	Rounding   RoundingMode
+}
+
+var (
+	CurrencyIDR = "IDR"
+	CurrencySGD = "SGD"
+	CurrencyUSD = "USD"
+	CurrencyJPY = "JPY"
+
+	DefaultCurrency = CurrencyIDR
+
+	// currencies is the registry of supported currencies. IDR officially has 2
+	// minor units but sen are not in circulation, so amounts are kept in rupiah.
+	currencies = map[string]Currency{
+		CurrencyIDR: {Code: CurrencyIDR, MinorUnits: 0, Rounding: RoundingHalfUp},
+		CurrencySGD: {Code: CurrencySGD, MinorUnits: 2, Rounding: RoundingHalfUp},
+		CurrencyUSD: {Code: CurrencyUSD, MinorUnits: 2, Rounding: RoundingHalfEven},
+		CurrencyJPY: {Code: CurrencyJPY, MinorUnits: 0, Rounding: RoundingHalfUp},
+	}
+)
+
+// LookupCurrency returns the registered currency for an ISO 4217 code.
+func LookupCurrency(code string) (Currency, error) {
+	currency, ok := currencies[strings.ToUpper(code)]
+	if !ok {
+		return Currency{}, NewError(
+			ErrUnsupportedCurrency.Error(),
+			fmt.Sprintf("currency should be one of %v", SupportedCurrencies()),
+			http.StatusBadRequest,
+		)
+	}
+	return currency, nil
+}
+
+// SupportedCurrencies returns the registered currency codes, sorted.
+func SupportedCurrencies() []string {
+	codes := make([]string, 0, len(currencies))
+	for code := range currencies {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// currencyOf returns the registered currency of an amount, falling back to the
+// amount's own precision for unknown codes.
+func currencyOf(a Amount) Currency {
+	if currency, ok := currencies[a.Currency]; ok {
+		return currency
+	}
+	return Currency{
+		Code:       a.Currency,
+		MinorUnits: a.DecimalPrecision,
+		Rounding:   RoundingHalfUp,
+	}
+}
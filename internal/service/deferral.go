@@ -0,0 +1,206 @@
+package billing
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+// maxDeferralInterestRate caps the nominal annual rate a deferral may charge.
+const maxDeferralInterestRate = 1.0
+
+// LoanDeferralTerms skip the next upcoming installments of a loan, lighter than
+// a restructure: the installments keep their amounts and move to the end of the
+// schedule.
+type LoanDeferralTerms struct {
+	// upcoming installments skipped
+	Installments int
+	// nominal annual rate charged on the principal still to come for the
+	// deferred periods, 0 defers for free, at most maxDeferralInterestRate
+	InterestRate float64
+
+	Reason      string
+	RequestedBy string
+}
+
+// LoanDeferral records a deferral of a loan. From DeferredFrom until
+// DeferredUntil, when the first deferred installment is due, the loan is not
+// delinquent.
+type LoanDeferral struct {
+	ID     string
+	LoanID string
+	LoanDeferralTerms
+
+	// deferral interest added to the last installment
+	Interest      Amount
+	DeferredFrom  time.Time
+	DeferredUntil time.Time
+	CreatedAt     time.Time
+
+	// the deferred installments with their new due dates
+	Schedules []LoanSchedule
+}
+
+// DeferInstallments pushes the due dates of every upcoming installment of an
+// active or delinquent loan terms.Installments periods later, so the next ones
+// are skipped and the schedule ends that much later. Installments already past
+// due stay due.
+func (s *loanService) DeferInstallments(
+	ctx context.Context,
+	loanID string,
+	terms LoanDeferralTerms,
+) (*LoanDeferral, error) {
+	if err := terms.validate(); err != nil {
+		return nil, err
+	}
+
+	var deferral *LoanDeferral
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		deferral, err = s.deferInstallments(ctx, loanID, terms)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deferral, nil
+}
+
+func (t LoanDeferralTerms) validate() error {
+	switch {
+	case t.Installments <= 0:
+		return NewError(
+			ErrValidationError.Error(),
+			"deferred installments must be positive",
+			http.StatusBadRequest,
+		)
+	case t.InterestRate < 0:
+		return NewError(
+			ErrValidationError.Error(),
+			"deferral interest rate must not be negative",
+			http.StatusBadRequest,
+		)
+	case t.InterestRate > maxDeferralInterestRate:
+		return NewError(
+			ErrValidationError.Error(),
+			fmt.Sprintf("deferral interest rate must not be above %v", maxDeferralInterestRate),
+			http.StatusBadRequest,
+		)
+	case t.Reason == "":
+		return NewError(
+			ErrValidationError.Error(),
+			"deferral reason is required",
+			http.StatusBadRequest,
+		)
+	case t.RequestedBy == "":
+		return NewError(
+			ErrValidationError.Error(),
+			"deferral requester is required",
+			http.StatusBadRequest,
+		)
+	}
+
+	return nil
+}
+
+func (s *loanService) deferInstallments(
+	ctx context.Context,
+	loanID string,
+	terms LoanDeferralTerms,
+) (*LoanDeferral, error) {
+	loan, err := s.loanStore.GetLoanByIDForUpdate(ctx, loanID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to get loan", "error", err)
+		return nil, err
+	}
+
+	if !loan.Status.IsPayable() {
+		s.logger.WarnContext(ctx, "loan is not deferrable", "status", loan.Status)
+		return nil, ErrLoanNotDeferrable
+	}
+
+	unsettled, err := s.loanStore.GetUnsettledSchedules(ctx, loanID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to get unsettled schedules", "error", err)
+		return nil, err
+	}
+
+	now := CurrentLocalTime()
+	today := LocalDate(now)
+
+	var upcoming []LoanSchedule
+	for _, schedule := range unsettled {
+		if !LocalDate(schedule.DueDate).Before(today) {
+			upcoming = append(upcoming, schedule)
+		}
+	}
+
+	if len(upcoming) < terms.Installments {
+		s.logger.WarnContext(ctx, "not enough upcoming installments to defer",
+			"upcoming", len(upcoming), "deferred", terms.Installments)
+		return nil, NewError(
+			ErrLoanNotDeferrable.Error(),
+			fmt.Sprintf("loan has %d upcoming installments to defer", len(upcoming)),
+			http.StatusUnprocessableEntity,
+		)
+	}
+
+	deferral, err := newDeferral(loan, upcoming, terms, now)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to compute deferral interest", "error", err)
+		return nil, err
+	}
+	loan.EndedAt = deferral.Schedules[len(deferral.Schedules)-1].DueDate
+
+	if err := s.loanStore.DeferInstallments(ctx, loan, deferral); err != nil {
+		s.logger.WarnContext(ctx, "failed to defer installments", "error", err)
+		return nil, err
+	}
+
+	return deferral, nil
+}
+
+// newDeferral moves the upcoming schedules of a loan the deferred periods later,
+// charging the deferral interest on the last one.
+func newDeferral(
+	loan *Loan,
+	upcoming []LoanSchedule,
+	terms LoanDeferralTerms,
+	now time.Time,
+) (*LoanDeferral, error) {
+	principal := loan.PrincipalAmount.ZeroLike()
+	for i := range upcoming {
+		principal = principal.Add(upcoming[i].Remaining().Principal)
+		// moved along the calendar of its schedule version, stepping on from the
+		// due date itself would lose the anniversary day after a shorter month
+		anchor := upcoming[i].AnchoredAt
+		period := loan.PaymentFrequency.period(anchor, upcoming[i].DueDate)
+		upcoming[i].DueDate = loan.PaymentFrequency.DueDate(anchor, period+terms.Installments)
+	}
+
+	// the interest of the principal still to come, over the deferred periods
+	rate := periodicRate(ratFromFloat(terms.InterestRate), loan.PaymentFrequency)
+	rate.Mul(rate, big.NewRat(int64(terms.Installments), 1))
+	interest, err := principal.mulRat(rate)
+	if err != nil {
+		return nil, err
+	}
+
+	last := &upcoming[len(upcoming)-1]
+	last.InterestDue = last.InterestDue.Add(interest)
+	last.AmountDue = last.AmountDue.Add(interest)
+
+	return &LoanDeferral{
+		ID:                UUID(),
+		LoanID:            loan.ID,
+		LoanDeferralTerms: terms,
+		Interest:          interest,
+		DeferredFrom:      now,
+		DeferredUntil:     upcoming[0].DueDate,
+		CreatedAt:         now,
+		Schedules:         upcoming,
+	}, nil
+}
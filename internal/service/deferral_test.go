@@ -0,0 +1,289 @@
+package billing_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+
+	billing "github.com/theyudiriski/billing-service/internal/service"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDeferInstallments(t *testing.T) {
+	provideLoanTest(t)
+
+	Convey("DeferInstallments", t, FailureHalts, func() {
+		type (
+			args struct {
+				ctx    context.Context
+				loanID string
+				terms  billing.LoanDeferralTerms
+			}
+		)
+
+		var (
+			ctx       = context.Background()
+			loanID    = "loan-id"
+			today     = billing.LocalDate(billing.CurrentLocalTime())
+			startedAt = today.AddDate(0, 0, -10)
+
+			terms = func(installments int, interestRate float64) billing.LoanDeferralTerms {
+				return billing.LoanDeferralTerms{
+					Installments: installments,
+					InterestRate: interestRate,
+					Reason:       "borrower in hospital",
+					RequestedBy:  "collector-1",
+				}
+			}
+
+			activeLoan = func() *billing.Loan {
+				return &billing.Loan{
+					ID:               loanID,
+					PrincipalAmount:  billing.NewAmount(1_000_000),
+					StartedAt:        startedAt,
+					EndedAt:          billing.LoanFrequencyWeekly.DueDate(startedAt, 4),
+					PaymentFrequency: billing.LoanFrequencyWeekly,
+					TotalPayments:    4,
+					Status:           billing.LoanStatusActive,
+				}
+			}
+
+			// the 1st installment is 3 days overdue, the other three are upcoming
+			unsettled = func() []billing.LoanSchedule {
+				schedules := make([]billing.LoanSchedule, 0, 4)
+				for seq := 1; seq <= 4; seq++ {
+					schedules = append(schedules, billing.LoanSchedule{
+						ID:           "schedule-id",
+						LoanID:       loanID,
+						Seq:          seq,
+						Version:      1,
+						AnchoredAt:   startedAt,
+						DueDate:      billing.LoanFrequencyWeekly.DueDate(startedAt, seq),
+						AmountDue:    billing.NewAmount(257_000),
+						PrincipalDue: billing.NewAmount(250_000),
+						InterestDue:  billing.NewAmount(7_000),
+						FeeDue:       billing.NewAmount(0),
+						LateFeeDue:   billing.NewAmount(0),
+						PaidAmount:   billing.NewAmount(0),
+						Status:       billing.LoanScheduleStatusUnpaid,
+					})
+				}
+				return schedules
+			}
+		)
+
+		testCases := []struct {
+			testID      int
+			testDesc    string
+			testType    string
+			args        args
+			mock        func()
+			expectedErr error
+		}{
+			{
+				testID:   1,
+				testDesc: "success upcoming installments move one period later",
+				testType: "P",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+					terms:  terms(1, 0),
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(activeLoan(), nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loanID).Return(unsettled(), nil)
+					mockLoanStore.EXPECT().DeferInstallments(ctx, gomock.Any(), gomock.Any()).
+						Do(func(ctx context.Context, loan *billing.Loan, deferral *billing.LoanDeferral) {
+							So(deferral.Interest, ShouldEqual, billing.NewAmount(0))
+							// the overdue installment stays due
+							So(deferral.Schedules, ShouldHaveLength, 3)
+							So(deferral.Schedules[0].Seq, ShouldEqual, 2)
+							So(deferral.Schedules[0].DueDate.Equal(today.AddDate(0, 0, 11)), ShouldBeTrue)
+							So(deferral.DeferredUntil.Equal(deferral.Schedules[0].DueDate), ShouldBeTrue)
+							So(deferral.Schedules[2].AmountDue, ShouldEqual, billing.NewAmount(257_000))
+							So(loan.EndedAt.Equal(today.AddDate(0, 0, 25)), ShouldBeTrue)
+						}).Return(nil)
+				},
+			},
+			{
+				testID:   2,
+				testDesc: "success deferral interest charged on the last installment",
+				testType: "P",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+					terms:  terms(2, 0.52),
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(activeLoan(), nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loanID).Return(unsettled(), nil)
+					mockLoanStore.EXPECT().DeferInstallments(ctx, gomock.Any(), gomock.Any()).
+						Do(func(ctx context.Context, loan *billing.Loan, deferral *billing.LoanDeferral) {
+							// 2 weeks at 1% a week on the 750,000 still to come
+							So(deferral.Interest, ShouldEqual, billing.NewAmount(15_000))
+							So(deferral.Schedules[0].DueDate.Equal(today.AddDate(0, 0, 18)), ShouldBeTrue)
+							So(deferral.Schedules[0].AmountDue, ShouldEqual, billing.NewAmount(257_000))
+							So(deferral.Schedules[2].InterestDue, ShouldEqual, billing.NewAmount(22_000))
+							So(deferral.Schedules[2].AmountDue, ShouldEqual, billing.NewAmount(272_000))
+							So(loan.EndedAt.Equal(today.AddDate(0, 0, 32)), ShouldBeTrue)
+						}).Return(nil)
+				},
+			},
+			{
+				testID:   3,
+				testDesc: "failed more installments than upcoming",
+				testType: "N",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+					terms:  terms(4, 0),
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(activeLoan(), nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loanID).Return(unsettled(), nil)
+				},
+			},
+			{
+				testID:   4,
+				testDesc: "failed loan pending disbursement",
+				testType: "N",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+					terms:  terms(1, 0),
+				},
+				mock: func() {
+					loan := activeLoan()
+					loan.Status = billing.LoanStatusPendingDisbursement
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(loan, nil)
+				},
+				expectedErr: billing.ErrLoanNotDeferrable,
+			},
+			{
+				testID:   5,
+				testDesc: "failed nothing to defer",
+				testType: "N",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+					terms:  terms(0, 0),
+				},
+				mock: func() {},
+			},
+			{
+				testID:   6,
+				testDesc: "failed without a reason",
+				testType: "N",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+					terms: billing.LoanDeferralTerms{
+						Installments: 1,
+						RequestedBy:  "collector-1",
+					},
+				},
+				mock: func() {},
+			},
+			{
+				testID:   7,
+				testDesc: "success monthly installments keep the anniversary day",
+				testType: "P",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+					terms:  terms(1, 0),
+				},
+				mock: func() {
+					startedAt := time.Date(today.Year()+1, time.January, 31, 0, 0, 0, 0, today.Location())
+
+					loan := activeLoan()
+					loan.StartedAt = startedAt
+					loan.PaymentFrequency = billing.LoanFrequencyMonthly
+					loan.TotalPayments = 3
+					loan.EndedAt = billing.LoanFrequencyMonthly.DueDate(startedAt, 3)
+
+					schedules := unsettled()[:3]
+					for i := range schedules {
+						schedules[i].AnchoredAt = startedAt
+						schedules[i].DueDate = billing.LoanFrequencyMonthly.DueDate(startedAt, i+1)
+					}
+
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(loan, nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loanID).Return(schedules, nil)
+					mockLoanStore.EXPECT().DeferInstallments(ctx, gomock.Any(), gomock.Any()).
+						Do(func(ctx context.Context, loan *billing.Loan, deferral *billing.LoanDeferral) {
+							// due at the end of February, moved to the end of March
+							So(deferral.Schedules[0].DueDate.Equal(startedAt.AddDate(0, 2, 0)), ShouldBeTrue)
+							So(deferral.Schedules[1].DueDate.Equal(time.Date(startedAt.Year(), time.April, 30, 0, 0, 0, 0, startedAt.Location())), ShouldBeTrue)
+							So(loan.EndedAt.Equal(startedAt.AddDate(0, 4, 0)), ShouldBeTrue)
+						}).Return(nil)
+				},
+			},
+			{
+				testID:   8,
+				testDesc: "success restructured installments move along the restructure calendar",
+				testType: "P",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+					terms:  terms(1, 0),
+				},
+				mock: func() {
+					// restructured 5 days ago, off the weekly calendar of the start
+					restructuredAt := today.AddDate(0, 0, -5)
+					schedules := unsettled()[:2]
+					for i := range schedules {
+						schedules[i].Seq = i + 2
+						schedules[i].Version = 2
+						schedules[i].AnchoredAt = restructuredAt
+						schedules[i].DueDate = billing.LoanFrequencyWeekly.DueDate(restructuredAt, i+1)
+					}
+
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(activeLoan(), nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loanID).Return(schedules, nil)
+					mockLoanStore.EXPECT().DeferInstallments(ctx, gomock.Any(), gomock.Any()).
+						Do(func(ctx context.Context, loan *billing.Loan, deferral *billing.LoanDeferral) {
+							// a whole week later each, not snapped back onto the start calendar
+							So(deferral.Schedules[0].DueDate.Equal(today.AddDate(0, 0, 9)), ShouldBeTrue)
+							So(deferral.Schedules[1].DueDate.Equal(today.AddDate(0, 0, 16)), ShouldBeTrue)
+							So(loan.EndedAt.Equal(today.AddDate(0, 0, 16)), ShouldBeTrue)
+						}).Return(nil)
+				},
+			},
+			{
+				testID:   9,
+				testDesc: "failed interest rate above the cap",
+				testType: "N",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+					terms:  terms(1, 50),
+				},
+				mock: func() {},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Logf("%d - [%s] : %s", tc.testID, tc.testType, tc.testDesc)
+			tc.mock()
+
+			_, err := loanService.DeferInstallments(
+				tc.args.ctx,
+				tc.args.loanID,
+				tc.args.terms,
+			)
+
+			if tc.testType == "P" {
+				So(err, ShouldBeNil)
+			} else {
+				So(err, ShouldNotBeNil)
+				if tc.expectedErr != nil {
+					So(err, ShouldEqual, tc.expectedErr)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,159 @@
+package billing
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// LoanDisbursement records the payout of a loan to the borrower.
+type LoanDisbursement struct {
+	// what reached the borrower, at most the principal; the installments are
+	// billed on it
+	Amount Amount
+	// payout reference at the provider, unique across loans
+	Reference   string
+	DisbursedAt time.Time
+}
+
+// DisburseLoan records the payout of a loan pending disbursement and activates
+// it. The loan starts on the disbursement date, its schedules are due relative
+// to it, and their principal and interest are those of the disbursed amount,
+// which may be less than the principal applied for. Recording the same reference again returns the loan unchanged, so a
+// payout notification may be delivered more than once.
+func (s *loanService) DisburseLoan(
+	ctx context.Context,
+	loanID string,
+	disbursement LoanDisbursement,
+) (*Loan, error) {
+	if disbursement.Reference == "" {
+		return nil, NewError(
+			ErrValidationError.Error(),
+			"disbursement reference is required",
+			http.StatusBadRequest,
+		)
+	}
+
+	if disbursement.Amount.Cmp(disbursement.Amount.ZeroLike()) <= 0 {
+		return nil, NewError(
+			ErrValidationError.Error(),
+			"disbursed amount must be greater than 0",
+			http.StatusBadRequest,
+		)
+	}
+
+	now := CurrentLocalTime()
+	if disbursement.DisbursedAt.IsZero() {
+		disbursement.DisbursedAt = now
+	}
+
+	if disbursement.DisbursedAt.After(now) {
+		return nil, NewError(
+			ErrValidationError.Error(),
+			"disbursement date must not be in the future",
+			http.StatusBadRequest,
+		)
+	}
+
+	var loan *Loan
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		loan, err = s.disburseLoan(ctx, loanID, disbursement)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return loan, nil
+}
+
+func (s *loanService) disburseLoan(
+	ctx context.Context,
+	loanID string,
+	disbursement LoanDisbursement,
+) (*Loan, error) {
+	loan, err := s.loanStore.GetLoanByIDForUpdate(ctx, loanID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to get loan", "error", err)
+		return nil, err
+	}
+
+	if loan.Status != LoanStatusPendingDisbursement {
+		// a repeated notification of the payout already recorded
+		if loan.Disbursement != nil && loan.Disbursement.Reference == disbursement.Reference {
+			return loan, nil
+		}
+
+		s.logger.WarnContext(ctx, "loan is not pending disbursement", "status", loan.Status)
+		return nil, ErrLoanNotPendingDisbursement
+	}
+
+	if _, err := disbursement.Amount.EqualTo(loan.PrincipalAmount); err != nil {
+		s.logger.WarnContext(ctx, "disbursement currency mismatch", "amount", disbursement.Amount, "loanCurrency", loan.PrincipalAmount.Currency)
+		return nil, err
+	}
+
+	if disbursement.Amount.Cmp(loan.PrincipalAmount) > 0 {
+		return nil, NewError(
+			ErrValidationError.Error(),
+			"disbursed amount must not exceed the principal",
+			http.StatusBadRequest,
+		)
+	}
+
+	// until disbursed, StartedAt is when the loan was made
+	if disbursement.DisbursedAt.Before(loan.StartedAt) {
+		return nil, NewError(
+			ErrValidationError.Error(),
+			"disbursement date must not be before the loan was made",
+			http.StatusBadRequest,
+		)
+	}
+
+	schedules, err := s.loanStore.ListSchedules(ctx, loan.ID, LoanScheduleFilter{})
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to list schedules", "error", err)
+		return nil, err
+	}
+
+	disbursement.DisbursedAt = LocalTime(disbursement.DisbursedAt)
+	loan.Disbursement = &disbursement
+	loan.StartedAt = disbursement.DisbursedAt
+	loan.EndedAt = loan.PaymentFrequency.DueDate(loan.StartedAt, loan.TotalPayments)
+	loan.Status = LoanStatusActive
+
+	// a partial payout bills principal and interest on what was paid out only,
+	// the fee stays as agreed
+	installments, err := loan.InterestModel.splitInstallments(
+		disbursement.Amount,
+		loan.InterestRate,
+		loan.PaymentFrequency,
+		loan.TotalPayments,
+	)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to split installments", "error", err)
+		return nil, err
+	}
+	for i, fee := range loan.FeeAmount.Allocate(loan.TotalPayments) {
+		installments[i].Fee = fee
+	}
+
+	// the schedules keep their identity, only their dates and amounts change
+	scheduleIDs := map[int]string{}
+	for _, schedule := range schedules {
+		scheduleIDs[schedule.Seq] = schedule.ID
+	}
+
+	loan.Schedules = buildSchedules(loan, installments)
+	for i := range loan.Schedules {
+		loan.Schedules[i].ID = scheduleIDs[loan.Schedules[i].Seq]
+	}
+
+	if err := s.loanStore.DisburseLoan(ctx, loan); err != nil {
+		s.logger.WarnContext(ctx, "failed to disburse loan", "error", err)
+		return nil, err
+	}
+
+	return loan, nil
+}
@@ -0,0 +1,277 @@
+package billing_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	billing "github.com/theyudiriski/billing-service/internal/service"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDisburseLoan(t *testing.T) {
+	provideLoanTest(t)
+
+	Convey("DisburseLoan", t, FailureHalts, func() {
+		type (
+			args struct {
+				ctx          context.Context
+				loanID       string
+				disbursement billing.LoanDisbursement
+			}
+		)
+
+		var (
+			ctx         = context.Background()
+			loanID      = "loan-id"
+			reference   = "payout-1"
+			madeAt      = billing.CurrentLocalTime().AddDate(0, 0, -3)
+			disbursedAt = billing.CurrentLocalTime().AddDate(0, 0, -1)
+
+			disbursement = billing.LoanDisbursement{
+				Amount:      billing.NewAmount(1_000_000),
+				Reference:   reference,
+				DisbursedAt: disbursedAt,
+			}
+
+			pendingLoan = func() *billing.Loan {
+				return &billing.Loan{
+					ID:               loanID,
+					PrincipalAmount:  billing.NewAmount(1_000_000),
+					InterestRate:     0.12,
+					InterestModel:    billing.InterestModelFlat,
+					FeeAmount:        billing.NewAmount(30_000),
+					StartedAt:        madeAt,
+					EndedAt:          billing.LoanFrequencyWeekly.DueDate(madeAt, 3),
+					PaymentFrequency: billing.LoanFrequencyWeekly,
+					TotalPayments:    3,
+					Status:           billing.LoanStatusPendingDisbursement,
+				}
+			}
+
+			disbursedLoan = func(reference string) *billing.Loan {
+				loan := pendingLoan()
+				loan.Status = billing.LoanStatusActive
+				loan.Disbursement = &billing.LoanDisbursement{
+					Amount:      billing.NewAmount(1_000_000),
+					Reference:   reference,
+					DisbursedAt: disbursedAt,
+				}
+				return loan
+			}
+
+			schedules = func() []billing.LoanSchedule {
+				schedules := make([]billing.LoanSchedule, 0, 3)
+				for seq := 1; seq <= 3; seq++ {
+					schedules = append(schedules, billing.LoanSchedule{
+						ID:      "schedule-id",
+						LoanID:  loanID,
+						Seq:     seq,
+						DueDate: billing.LoanFrequencyWeekly.DueDate(madeAt, seq),
+					})
+				}
+				return schedules
+			}
+		)
+
+		testCases := []struct {
+			testID      int
+			testDesc    string
+			testType    string
+			args        args
+			mock        func()
+			expectedErr error
+		}{
+			{
+				testID:   1,
+				testDesc: "success dates follow the disbursement date",
+				testType: "P",
+				args: args{
+					ctx:          ctx,
+					loanID:       loanID,
+					disbursement: disbursement,
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(pendingLoan(), nil)
+					mockLoanStore.EXPECT().ListSchedules(ctx, loanID, billing.LoanScheduleFilter{}).Return(schedules(), nil)
+					mockLoanStore.EXPECT().DisburseLoan(ctx, gomock.Any()).
+						Do(func(ctx context.Context, loan *billing.Loan) {
+							So(loan.Status, ShouldEqual, billing.LoanStatusActive)
+							So(loan.Disbursement.Reference, ShouldEqual, reference)
+							So(loan.StartedAt.Equal(disbursedAt), ShouldBeTrue)
+							So(loan.EndedAt.Equal(disbursedAt.AddDate(0, 0, 21)), ShouldBeTrue)
+							So(loan.Schedules, ShouldHaveLength, 3)
+							So(loan.Schedules[0].DueDate.Equal(disbursedAt.AddDate(0, 0, 7)), ShouldBeTrue)
+							So(loan.Schedules[2].DueDate.Equal(loan.EndedAt), ShouldBeTrue)
+							So(loan.Schedules[0].ID, ShouldEqual, "schedule-id")
+							So(loan.Schedules[0].PrincipalDue, ShouldEqual, billing.NewAmount(333_334))
+							So(loan.Schedules[0].InterestDue, ShouldEqual, billing.NewAmount(40_000))
+						}).Return(nil)
+				},
+			},
+			{
+				testID:   2,
+				testDesc: "success repeated notification of the same payout",
+				testType: "P",
+				args: args{
+					ctx:          ctx,
+					loanID:       loanID,
+					disbursement: disbursement,
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(disbursedLoan(reference), nil)
+				},
+			},
+			{
+				testID:   3,
+				testDesc: "failed loan already disbursed by another payout",
+				testType: "N",
+				args: args{
+					ctx:          ctx,
+					loanID:       loanID,
+					disbursement: disbursement,
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(disbursedLoan("payout-0"), nil)
+				},
+				expectedErr: billing.ErrLoanNotPendingDisbursement,
+			},
+			{
+				testID:   4,
+				testDesc: "failed disbursed amount above the principal",
+				testType: "N",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+					disbursement: billing.LoanDisbursement{
+						Amount:      billing.NewAmount(1_000_001),
+						Reference:   reference,
+						DisbursedAt: disbursedAt,
+					},
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(pendingLoan(), nil)
+				},
+			},
+			{
+				testID:   5,
+				testDesc: "failed disbursed before the loan was made",
+				testType: "N",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+					disbursement: billing.LoanDisbursement{
+						Amount:      billing.NewAmount(1_000_000),
+						Reference:   reference,
+						DisbursedAt: madeAt.AddDate(0, 0, -1),
+					},
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(pendingLoan(), nil)
+				},
+			},
+			{
+				testID:   6,
+				testDesc: "failed reference recorded on another loan",
+				testType: "N",
+				args: args{
+					ctx:          ctx,
+					loanID:       loanID,
+					disbursement: disbursement,
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(pendingLoan(), nil)
+					mockLoanStore.EXPECT().ListSchedules(ctx, loanID, billing.LoanScheduleFilter{}).Return(schedules(), nil)
+					mockLoanStore.EXPECT().DisburseLoan(ctx, gomock.Any()).Return(billing.ErrDisbursementReferenceConflict)
+				},
+				expectedErr: billing.ErrDisbursementReferenceConflict,
+			},
+			{
+				testID:   7,
+				testDesc: "success partial payout bills principal and interest on what was paid out",
+				testType: "P",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+					disbursement: billing.LoanDisbursement{
+						Amount:      billing.NewAmount(600_000),
+						Reference:   reference,
+						DisbursedAt: disbursedAt,
+					},
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(pendingLoan(), nil)
+					mockLoanStore.EXPECT().ListSchedules(ctx, loanID, billing.LoanScheduleFilter{}).Return(schedules(), nil)
+					mockLoanStore.EXPECT().DisburseLoan(ctx, gomock.Any()).
+						Do(func(ctx context.Context, loan *billing.Loan) {
+							So(loan.Disbursement.Amount, ShouldEqual, billing.NewAmount(600_000))
+							So(loan.Schedules, ShouldHaveLength, 3)
+
+							principal := billing.NewAmount(0)
+							for _, schedule := range loan.Schedules {
+								principal = principal.Add(schedule.PrincipalDue)
+								So(schedule.InterestDue, ShouldEqual, billing.NewAmount(24_000))
+								So(schedule.FeeDue, ShouldEqual, billing.NewAmount(10_000))
+								So(schedule.AmountDue, ShouldEqual, billing.NewAmount(234_000))
+							}
+							So(principal, ShouldEqual, billing.NewAmount(600_000))
+						}).Return(nil)
+				},
+			},
+			{
+				testID:   8,
+				testDesc: "failed disbursement date in the future",
+				testType: "N",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+					disbursement: billing.LoanDisbursement{
+						Amount:      billing.NewAmount(1_000_000),
+						Reference:   reference,
+						DisbursedAt: billing.CurrentLocalTime().AddDate(0, 0, 1),
+					},
+				},
+				mock: func() {},
+			},
+			{
+				testID:   9,
+				testDesc: "failed disbursed in another currency than the loan",
+				testType: "N",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+					disbursement: billing.LoanDisbursement{
+						Amount:      billing.NewAmountIn(100, billing.CurrencyUSD),
+						Reference:   reference,
+						DisbursedAt: disbursedAt,
+					},
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(pendingLoan(), nil)
+				},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Logf("%d - [%s] : %s", tc.testID, tc.testType, tc.testDesc)
+			tc.mock()
+
+			_, err := loanService.DisburseLoan(
+				tc.args.ctx,
+				tc.args.loanID,
+				tc.args.disbursement,
+			)
+
+			if tc.testType == "P" {
+				So(err, ShouldBeNil)
+			} else {
+				So(err, ShouldNotBeNil)
+				if tc.expectedErr != nil {
+					So(err, ShouldEqual, tc.expectedErr)
+				}
+			}
+		}
+	})
+}
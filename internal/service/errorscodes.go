@@ -9,6 +9,51 @@ var (
 
 	ErrInvalidUUID error = errors.New("INVALID_UUID")
 
-	ErrLoanNotFound          error = errors.New("LOAN_NOT_FOUND")
-	ErrPaymentAmountMismatch error = errors.New("PAYMENT_AMOUNT_MISMATCH")
+	ErrUnsupportedCurrency error = errors.New("UNSUPPORTED_CURRENCY")
+	ErrCurrencyMismatch    error = errors.New("CURRENCY_MISMATCH")
+
+	ErrLoanNotFound error = errors.New("LOAN_NOT_FOUND")
+
+	ErrLoanProductNotFound                error = errors.New("LOAN_PRODUCT_NOT_FOUND")
+	ErrLoanProductRetired                 error = errors.New("LOAN_PRODUCT_RETIRED")
+	ErrLoanOutsideProductTerms            error = errors.New("LOAN_OUTSIDE_PRODUCT_TERMS")
+	ErrInvalidLoanProductStatusTransition error = errors.New("INVALID_LOAN_PRODUCT_STATUS_TRANSITION")
+
+	ErrBorrowerNotFound           error = errors.New("BORROWER_NOT_FOUND")
+	ErrBorrowerAlreadyExists      error = errors.New("BORROWER_ALREADY_EXISTS")
+	ErrBorrowerNotVerified        error = errors.New("BORROWER_NOT_VERIFIED")
+	ErrInvalidKYCStatusTransition error = errors.New("INVALID_KYC_STATUS_TRANSITION")
+
+	ErrBorrowerDelinquent       error = errors.New("BORROWER_DELINQUENT")
+	ErrActiveLoanLimitExceeded  error = errors.New("ACTIVE_LOAN_LIMIT_EXCEEDED")
+	ErrOutstandingLimitExceeded error = errors.New("OUTSTANDING_LIMIT_EXCEEDED")
+
+	ErrLoanNotPayable              error = errors.New("LOAN_NOT_PAYABLE")
+	ErrInvalidLoanStatusTransition error = errors.New("INVALID_LOAN_STATUS_TRANSITION")
+
+	ErrLoanNotPendingDisbursement    error = errors.New("LOAN_NOT_PENDING_DISBURSEMENT")
+	ErrDisbursementReferenceConflict error = errors.New("DISBURSEMENT_REFERENCE_CONFLICT")
+
+	ErrLoanNotCancellable    error = errors.New("LOAN_NOT_CANCELLABLE")
+	ErrLoanNotRestructurable error = errors.New("LOAN_NOT_RESTRUCTURABLE")
+	ErrLoanNotDeferrable     error = errors.New("LOAN_NOT_DEFERRABLE")
+
+	ErrPayoffQuoteNotFound error = errors.New("PAYOFF_QUOTE_NOT_FOUND")
+	ErrPayoffQuoteNotDue   error = errors.New("PAYOFF_QUOTE_NOT_DUE")
+	ErrPayoffQuoteExpired  error = errors.New("PAYOFF_QUOTE_EXPIRED")
+	ErrPayoffQuoteStale    error = errors.New("PAYOFF_QUOTE_STALE")
+	ErrPayoffQuoteUsed     error = errors.New("PAYOFF_QUOTE_USED")
+
+	ErrIdempotencyKeyNotFound error = errors.New("IDEMPOTENCY_KEY_NOT_FOUND")
+	ErrIdempotencyKeyConflict error = errors.New("IDEMPOTENCY_KEY_CONFLICT")
+	ErrIdempotencyKeyMismatch error = errors.New("IDEMPOTENCY_KEY_MISMATCH")
+
+	ErrLateFeeNotFound    error = errors.New("LATE_FEE_NOT_FOUND")
+	ErrLateFeeNotWaivable error = errors.New("LATE_FEE_NOT_WAIVABLE")
+
+	ErrAdjustmentNotFound      error = errors.New("ADJUSTMENT_NOT_FOUND")
+	ErrAdjustmentNotApplicable error = errors.New("ADJUSTMENT_NOT_APPLICABLE")
+	ErrAdjustmentNotPending    error = errors.New("ADJUSTMENT_NOT_PENDING")
+	ErrAdjustmentNotReversible error = errors.New("ADJUSTMENT_NOT_REVERSIBLE")
+	ErrAdjustmentSelfApproval  error = errors.New("ADJUSTMENT_SELF_APPROVAL")
 )
@@ -0,0 +1,190 @@
+package billing
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultLoanPageSize = 20
+	MaxLoanPageSize     = 100
+)
+
+// BorrowerLoanFilter narrows down a borrower's loans, zero values match everything.
+type BorrowerLoanFilter struct {
+	Statuses []LoanStatus
+	// continue after the last loan of the previous page, nil starts from the newest
+	After *LoanCursor
+	// page size, DefaultLoanPageSize when zero
+	Limit int
+}
+
+// LoanCursor points at the last loan of a page. Loans are listed newest first
+// by when they were made, which unlike their start does not move on
+// disbursement, loans made at the same time are ordered by ID.
+type LoanCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// String encodes the cursor as an opaque token for clients to send back.
+func (c LoanCursor) String() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func (c *LoanCursor) UnmarshalText(text []byte) error {
+	invalid := NewError(
+		ErrValidationError.Error(),
+		"cursor is not valid, pass the next_cursor of a previous page",
+		http.StatusBadRequest,
+	)
+
+	raw, err := base64.RawURLEncoding.DecodeString(string(text))
+	if err != nil {
+		return invalid
+	}
+
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return invalid
+	}
+
+	at, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return invalid
+	}
+
+	*c = LoanCursor{
+		CreatedAt: at,
+		ID:        id,
+	}
+	return nil
+}
+
+// BorrowerLoans is a page of a borrower's loans along with what the borrower
+// owes across all of their loans, whatever the filter.
+type BorrowerLoans struct {
+	BorrowerID string
+	Loans      []Loan
+	// cursor of the next page, nil on the last page
+	NextCursor *LoanCursor
+	Exposure   BorrowerExposure
+}
+
+// BorrowerExposure sums what a borrower owes on their loans that are not
+// closed, loans pending disbursement included.
+type BorrowerExposure struct {
+	// loans the borrower is still repaying or waiting to be paid out
+	ActiveLoans int
+	// of those, the loans that are delinquent, see LoanStore.IsDelinquent
+	DelinquentLoans int
+	// one entry per currency the borrower owes in, ordered by currency
+	Currencies []CurrencyExposure
+	// the loan with the oldest unpaid installment past its due date, nil when
+	// the borrower is up to date
+	WorstDelinquency *LoanDelinquency
+}
+
+type CurrencyExposure struct {
+	// principal left to repay, due or not
+	PrincipalOutstanding Amount
+	// everything left to pay on installments past their due date, late fees
+	// included
+	Overdue Amount
+}
+
+type LoanDelinquency struct {
+	LoanID             string
+	Status             LoanStatus
+	OldestDueDate      time.Time
+	MissedInstallments int
+	DaysPastDue        int
+}
+
+func (s *loanService) ListBorrowerLoans(
+	ctx context.Context,
+	borrowerID string,
+	filter BorrowerLoanFilter,
+) (*BorrowerLoans, error) {
+	for _, status := range filter.Statuses {
+		if !status.IsValid() {
+			return nil, NewError(
+				ErrValidationError.Error(),
+				fmt.Sprintf("LoanStatus should be one of %v", LoanStatuses),
+				http.StatusBadRequest,
+			)
+		}
+	}
+
+	if filter.Limit == 0 {
+		filter.Limit = DefaultLoanPageSize
+	}
+
+	if filter.Limit < 0 || filter.Limit > MaxLoanPageSize {
+		return nil, NewError(
+			ErrValidationError.Error(),
+			fmt.Sprintf("limit must be between 1 and %d", MaxLoanPageSize),
+			http.StatusBadRequest,
+		)
+	}
+
+	// the page and the summary are read from the same snapshot
+	var (
+		loans    []Loan
+		exposure *BorrowerExposure
+	)
+	err := s.txManager.WithinReadOnlyTx(ctx, func(ctx context.Context) error {
+		if _, err := s.borrowerStore.GetBorrowerByID(ctx, borrowerID); err != nil {
+			s.logger.WarnContext(ctx, "failed to get borrower", "error", err)
+			return err
+		}
+
+		// one loan past the page tells whether there is a next page
+		query := filter
+		query.Limit++
+
+		var err error
+		loans, err = s.loanStore.ListLoansByBorrowerID(ctx, borrowerID, query)
+		if err != nil {
+			s.logger.WarnContext(ctx, "failed to list borrower loans", "error", err)
+			return err
+		}
+
+		exposure, err = s.loanStore.GetBorrowerExposure(ctx, borrowerID)
+		if err != nil {
+			s.logger.WarnContext(ctx, "failed to get borrower exposure", "error", err)
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	page := &BorrowerLoans{
+		BorrowerID: borrowerID,
+		Loans:      loans,
+		Exposure:   *exposure,
+	}
+
+	if len(loans) > filter.Limit {
+		page.Loans = loans[:filter.Limit]
+		last := page.Loans[filter.Limit-1]
+		page.NextCursor = &LoanCursor{
+			CreatedAt: last.CreatedAt,
+			ID:        last.ID,
+		}
+	}
+
+	if worst := page.Exposure.WorstDelinquency; worst != nil {
+		worst.DaysPastDue = int(LocalDate(CurrentLocalTime()).Sub(LocalDate(worst.OldestDueDate)).Hours() / 24)
+	}
+
+	return page, nil
+}
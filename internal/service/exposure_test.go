@@ -0,0 +1,182 @@
+package billing_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+
+	billing "github.com/theyudiriski/billing-service/internal/service"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestListBorrowerLoans(t *testing.T) {
+	provideLoanTest(t)
+
+	Convey("ListBorrowerLoans", t, FailureHalts, func() {
+		type (
+			args struct {
+				ctx        context.Context
+				borrowerID string
+				filter     billing.BorrowerLoanFilter
+			}
+		)
+
+		var (
+			ctx        = context.Background()
+			borrowerID = "borrower-id"
+			borrower   = &billing.Borrower{ID: borrowerID, KYCStatus: billing.KYCStatusVerified}
+			createdAt  = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+			// disbursed a few days after they were made
+			loans = []billing.Loan{
+				{ID: "loan-3", BorrowerID: borrowerID, CreatedAt: createdAt.AddDate(0, 2, 0), StartedAt: createdAt.AddDate(0, 2, 3), Status: billing.LoanStatusActive},
+				{ID: "loan-2", BorrowerID: borrowerID, CreatedAt: createdAt.AddDate(0, 1, 0), StartedAt: createdAt.AddDate(0, 1, 3), Status: billing.LoanStatusDelinquent},
+				{ID: "loan-1", BorrowerID: borrowerID, CreatedAt: createdAt, StartedAt: createdAt.AddDate(0, 0, 3), Status: billing.LoanStatusPaidOff},
+			}
+
+			exposure = func() *billing.BorrowerExposure {
+				return &billing.BorrowerExposure{
+					Currencies: []billing.CurrencyExposure{
+						{
+							PrincipalOutstanding: billing.NewAmount(1_500_000),
+							Overdue:              billing.NewAmount(250_000),
+						},
+					},
+					WorstDelinquency: &billing.LoanDelinquency{
+						LoanID:             "loan-2",
+						Status:             billing.LoanStatusDelinquent,
+						OldestDueDate:      billing.LocalDate(billing.CurrentLocalTime()).AddDate(0, 0, -10),
+						MissedInstallments: 3,
+					},
+				}
+			}
+		)
+
+		testCases := []struct {
+			testID       int
+			testDesc     string
+			testType     string
+			args         args
+			mock         func()
+			expectedLen  int
+			expectedNext *billing.LoanCursor
+			expectedErr  error
+		}{
+			{
+				testID:   1,
+				testDesc: "success first page with a next cursor",
+				testType: "P",
+				args: args{
+					ctx:        ctx,
+					borrowerID: borrowerID,
+					filter:     billing.BorrowerLoanFilter{Limit: 2},
+				},
+				mock: func() {
+					mockBorrowerStore.EXPECT().GetBorrowerByID(ctx, borrowerID).Return(borrower, nil)
+					mockLoanStore.EXPECT().ListLoansByBorrowerID(ctx, borrowerID, billing.BorrowerLoanFilter{Limit: 3}).Return(loans, nil)
+					mockLoanStore.EXPECT().GetBorrowerExposure(ctx, borrowerID).Return(exposure(), nil)
+				},
+				expectedLen:  2,
+				expectedNext: &billing.LoanCursor{CreatedAt: loans[1].CreatedAt, ID: "loan-2"},
+			},
+			{
+				testID:   2,
+				testDesc: "success last page with the default page size",
+				testType: "P",
+				args: args{
+					ctx:        ctx,
+					borrowerID: borrowerID,
+					filter: billing.BorrowerLoanFilter{
+						Statuses: []billing.LoanStatus{billing.LoanStatusActive, billing.LoanStatusDelinquent},
+					},
+				},
+				mock: func() {
+					mockBorrowerStore.EXPECT().GetBorrowerByID(ctx, borrowerID).Return(borrower, nil)
+					mockLoanStore.EXPECT().ListLoansByBorrowerID(ctx, borrowerID, gomock.Any()).
+						Do(func(ctx context.Context, borrowerID string, filter billing.BorrowerLoanFilter) {
+							So(filter.Limit, ShouldEqual, billing.DefaultLoanPageSize+1)
+						}).Return(loans[:2], nil)
+					mockLoanStore.EXPECT().GetBorrowerExposure(ctx, borrowerID).Return(exposure(), nil)
+				},
+				expectedLen: 2,
+			},
+			{
+				testID:   3,
+				testDesc: "failed page size above the maximum",
+				testType: "N",
+				args: args{
+					ctx:        ctx,
+					borrowerID: borrowerID,
+					filter:     billing.BorrowerLoanFilter{Limit: billing.MaxLoanPageSize + 1},
+				},
+				mock: func() {},
+			},
+			{
+				testID:   4,
+				testDesc: "failed unknown loan status",
+				testType: "N",
+				args: args{
+					ctx:        ctx,
+					borrowerID: borrowerID,
+					filter:     billing.BorrowerLoanFilter{Statuses: []billing.LoanStatus{"settled"}},
+				},
+				mock: func() {},
+			},
+			{
+				testID:   5,
+				testDesc: "failed borrower not found",
+				testType: "N",
+				args: args{
+					ctx:        ctx,
+					borrowerID: borrowerID,
+				},
+				mock: func() {
+					mockBorrowerStore.EXPECT().GetBorrowerByID(ctx, borrowerID).Return(nil, billing.ErrBorrowerNotFound)
+				},
+				expectedErr: billing.ErrBorrowerNotFound,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Logf("%d - [%s] : %s", tc.testID, tc.testType, tc.testDesc)
+			tc.mock()
+
+			page, err := loanService.ListBorrowerLoans(
+				tc.args.ctx,
+				tc.args.borrowerID,
+				tc.args.filter,
+			)
+
+			if tc.testType == "P" {
+				So(err, ShouldBeNil)
+				So(page.Loans, ShouldHaveLength, tc.expectedLen)
+				So(page.NextCursor, ShouldResemble, tc.expectedNext)
+				So(page.Exposure.WorstDelinquency.DaysPastDue, ShouldEqual, 10)
+			} else {
+				So(err, ShouldNotBeNil)
+				if tc.expectedErr != nil {
+					So(err, ShouldEqual, tc.expectedErr)
+				}
+			}
+		}
+	})
+}
+
+func TestLoanCursor(t *testing.T) {
+	Convey("LoanCursor", t, func() {
+		cursor := billing.LoanCursor{
+			CreatedAt: time.Date(2026, 3, 1, 8, 30, 0, 123, time.UTC),
+			ID:        "loan-id",
+		}
+
+		var decoded billing.LoanCursor
+		So(decoded.UnmarshalText([]byte(cursor.String())), ShouldBeNil)
+		So(decoded.CreatedAt.Equal(cursor.CreatedAt), ShouldBeTrue)
+		So(decoded.ID, ShouldEqual, cursor.ID)
+
+		So(decoded.UnmarshalText([]byte("not-a-cursor")), ShouldNotBeNil)
+	})
+}
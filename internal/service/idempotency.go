@@ -0,0 +1,38 @@
+package billing
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// IdempotencyKey remembers the outcome of a request made with a client supplied
+// key, so a retry of the same request replays the outcome instead of running
+// again.
+type IdempotencyKey struct {
+	Key string
+	// fingerprint of the request the key was first used with
+	RequestHash string
+	// payment made by the original request
+	Payment   *Payment
+	CreatedAt time.Time
+}
+
+// paymentRequestHash fingerprints the parts of a payment request that make it
+// the same request when retried.
+func paymentRequestHash(
+	loanID string,
+	payAmount Amount,
+	channel string,
+	externalReference string,
+) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		loanID,
+		payAmount.String(),
+		payAmount.Currency,
+		channel,
+		externalReference,
+	}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,182 @@
+package billing
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type (
+	InterestModel string
+)
+
+var (
+	// InterestModelFlat charges principal * rate once over the whole term,
+	// spread evenly across installments. The rate is for the whole loan.
+	InterestModelFlat InterestModel = "flat"
+	// InterestModelAnnuity charges equal installments where interest is computed
+	// per period on the remaining principal. The rate is a nominal annual rate.
+	InterestModelAnnuity InterestModel = "annuity"
+	// InterestModelDecliningBalance repays equal principal every period plus the
+	// interest on the remaining principal. The rate is a nominal annual rate.
+	InterestModelDecliningBalance InterestModel = "declining_balance"
+
+	InterestModels = []InterestModel{
+		InterestModelFlat,
+		InterestModelAnnuity,
+		InterestModelDecliningBalance,
+	}
+)
+
+func (m InterestModel) IsValid() bool {
+	for _, model := range InterestModels {
+		if model == m {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *InterestModel) UnmarshalText(text []byte) error {
+	for _, model := range InterestModels {
+		if strings.EqualFold(string(model), string(text)) {
+			*m = model
+			return nil
+		}
+	}
+	return NewError(
+		ErrValidationError.Error(),
+		fmt.Sprintf("InterestModel should be one of %v", InterestModels),
+		http.StatusBadRequest,
+	)
+}
+
+// installmentSplit is the principal, interest and fee components of one installment.
+type installmentSplit struct {
+	Principal Amount
+	Interest  Amount
+	Fee       Amount
+}
+
+func (i installmentSplit) Total() Amount {
+	return i.Principal.Add(i.Interest).Add(i.Fee)
+}
+
+// splitInstallments computes the principal and interest of every installment
+// of a loan. Principal components always add up exactly to the principal.
+func (m InterestModel) splitInstallments(
+	principal Amount,
+	interestRate float64,
+	frequency LoanFrequency,
+	totalPayments int,
+) ([]installmentSplit, error) {
+	rate := ratFromFloat(interestRate)
+
+	switch m {
+	case InterestModelAnnuity:
+		return annuityInstallments(principal, periodicRate(rate, frequency), totalPayments)
+	case InterestModelDecliningBalance:
+		return decliningBalanceInstallments(principal, periodicRate(rate, frequency), totalPayments)
+	default:
+		return flatInstallments(principal, rate, totalPayments)
+	}
+}
+
+// flatInstallments splits principal plus flat interest into near equal
+// installments, the rounding remainder goes to the first installments.
+func flatInstallments(principal Amount, rate *big.Rat, totalPayments int) ([]installmentSplit, error) {
+	interest, err := principal.mulRat(rate)
+	if err != nil {
+		return nil, err
+	}
+	totals := principal.Add(interest).Allocate(totalPayments)
+	interests := interest.Allocate(totalPayments)
+
+	splits := make([]installmentSplit, totalPayments)
+	for i := range splits {
+		splits[i] = installmentSplit{
+			Principal: totals[i].Sub(interests[i]),
+			Interest:  interests[i],
+		}
+	}
+	return splits, nil
+}
+
+// annuityInstallments computes equal installments P * r / (1 - (1 + r)^-n), the
+// last installment absorbs the rounding so the principal is repaid exactly.
+func annuityInstallments(principal Amount, rate *big.Rat, totalPayments int) ([]installmentSplit, error) {
+	if rate.Sign() == 0 {
+		return decliningBalanceInstallments(principal, rate, totalPayments)
+	}
+
+	// (1 + r)^n
+	growth := new(big.Rat).Add(big.NewRat(1, 1), rate)
+	compounded := big.NewRat(1, 1)
+	for i := 0; i < totalPayments; i++ {
+		compounded.Mul(compounded, growth)
+	}
+
+	// P * r * (1 + r)^n / ((1 + r)^n - 1)
+	factor := new(big.Rat).Mul(rate, compounded)
+	factor.Quo(factor, new(big.Rat).Sub(compounded, big.NewRat(1, 1)))
+	payment, err := principal.mulRat(factor)
+	if err != nil {
+		return nil, err
+	}
+
+	splits := make([]installmentSplit, totalPayments)
+	balance := principal
+	for i := range splits {
+		interest, err := balance.mulRat(rate)
+		if err != nil {
+			return nil, err
+		}
+		principalPart := payment.Sub(interest)
+		if i == totalPayments-1 || principalPart.Cmp(balance) > 0 {
+			principalPart = balance
+		}
+
+		splits[i] = installmentSplit{
+			Principal: principalPart,
+			Interest:  interest,
+		}
+		balance = balance.Sub(principalPart)
+	}
+	return splits, nil
+}
+
+// decliningBalanceInstallments repays equal principal parts, each installment
+// carrying the interest of the principal still owed during the period.
+func decliningBalanceInstallments(principal Amount, rate *big.Rat, totalPayments int) ([]installmentSplit, error) {
+	principals := principal.Allocate(totalPayments)
+
+	splits := make([]installmentSplit, totalPayments)
+	balance := principal
+	for i := range splits {
+		interest, err := balance.mulRat(rate)
+		if err != nil {
+			return nil, err
+		}
+
+		splits[i] = installmentSplit{
+			Principal: principals[i],
+			Interest:  interest,
+		}
+		balance = balance.Sub(principals[i])
+	}
+	return splits, nil
+}
+
+// periodicRate converts a nominal annual rate into the rate of one period.
+func periodicRate(annualRate *big.Rat, frequency LoanFrequency) *big.Rat {
+	return new(big.Rat).Quo(annualRate, big.NewRat(int64(frequency.PeriodsPerYear()), 1))
+}
+
+// ratFromFloat takes the shortest decimal representation of f, so 0.1 is
+// exactly one tenth rather than its binary approximation.
+func ratFromFloat(f float64) *big.Rat {
+	r, _ := new(big.Rat).SetString(strconv.FormatFloat(f, 'f', -1, 64))
+	return r
+}
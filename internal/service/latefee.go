@@ -0,0 +1,395 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type LateFeeService interface {
+	// AccrueLateFees charges the late fees due today on every overdue schedule
+	// and marks the loans that became delinquent, it is safe to run more than
+	// once a day.
+	AccrueLateFees(ctx context.Context) error
+	GetLateFees(ctx context.Context, loanID string) ([]LateFee, error)
+	WaiveLateFee(ctx context.Context, loanID string, lateFeeID string) (*LateFee, error)
+}
+
+type LateFeeStore interface {
+	// ListOverdueSchedules returns the unsettled schedules of payable loans past
+	// their grace period on the given day, grouped by loan and oldest due first.
+	// Loans without grace days of their own get defaultGraceDays.
+	ListOverdueSchedules(ctx context.Context, today time.Time, defaultGraceDays int) ([]LoanSchedule, error)
+	ListLateFeesByLoanID(ctx context.Context, loanID string) ([]LateFee, error)
+	// CreateLateFees records the fees and adds them onto their schedules in a
+	// single transaction. A fee already accrued for the same schedule, kind and
+	// day is skipped, a fee on a settled schedule fails with ErrLoanNotPayable.
+	CreateLateFees(ctx context.Context, fees []LateFee) error
+	// WaiveLateFee marks the fee as waived and takes it off its schedule in a
+	// single transaction.
+	WaiveLateFee(ctx context.Context, fee *LateFee) error
+}
+
+func NewLateFeeService(
+	logger Logger,
+	rules LateFeeRules,
+	txManager TxManager,
+	loanStore LoanStore,
+	lateFeeStore LateFeeStore,
+) LateFeeService {
+	return &lateFeeService{
+		logger:       logger,
+		rules:        rules,
+		txManager:    txManager,
+		loanStore:    loanStore,
+		lateFeeStore: lateFeeStore,
+	}
+}
+
+type lateFeeService struct {
+	logger       Logger
+	rules        LateFeeRules
+	txManager    TxManager
+	loanStore    LoanStore
+	lateFeeStore LateFeeStore
+}
+
+type LateFee struct {
+	ID         string
+	LoanID     string
+	ScheduleID string
+	Kind       LateFeeKind
+	Amount     Amount
+	// local calendar day the fee was charged for
+	AccruedOn time.Time
+	Status    LateFeeStatus
+	WaivedAt  *time.Time
+}
+
+type (
+	LateFeeKind   string
+	LateFeeStatus string
+)
+
+var (
+	// charged once when an installment becomes overdue
+	LateFeeKindFlat LateFeeKind = "flat"
+	// charged for every day an installment stays overdue
+	LateFeeKindDaily LateFeeKind = "daily"
+
+	LateFeeStatusAccrued LateFeeStatus = "accrued"
+	LateFeeStatusWaived  LateFeeStatus = "waived"
+)
+
+// LateFeeRules configures how late fees accrue on overdue schedules. A zero
+// value charges nothing.
+type LateFeeRules struct {
+	// days after the due date before an installment is charged
+	GraceDays int
+	// charged once per missed installment, keyed by currency code
+	FlatAmounts map[string]Amount
+	// charged per day overdue on what is left of the installment, late fees
+	// excluded
+	DailyRate float64
+	// total late fees a loan can carry as a share of its principal, 0 means
+	// uncapped
+	CapRate float64
+}
+
+// NewLateFeeRules builds the rules from plain config values, flatAmounts maps a
+// currency code to a decimal amount.
+func NewLateFeeRules(
+	graceDays int,
+	flatAmounts map[string]string,
+	dailyRate float64,
+	capRate float64,
+) (LateFeeRules, error) {
+	if graceDays < 0 || dailyRate < 0 || capRate < 0 {
+		return LateFeeRules{}, errors.New("late fee grace days and rates must not be negative")
+	}
+
+	rules := LateFeeRules{
+		GraceDays:   graceDays,
+		FlatAmounts: map[string]Amount{},
+		DailyRate:   dailyRate,
+		CapRate:     capRate,
+	}
+
+	for currency, value := range flatAmounts {
+		amount, err := ParseAmount(value, currency)
+		if err != nil {
+			return LateFeeRules{}, fmt.Errorf("late fee flat amount for %s: %w", currency, err)
+		}
+		rules.FlatAmounts[currency] = amount
+	}
+
+	return rules, nil
+}
+
+// accrue returns the late fees to charge on the overdue schedules of a loan up to
+// and including today, given the fees the loan has already accrued. Days already
+// charged are skipped, so missed runs are caught up.
+func (r LateFeeRules) accrue(
+	loan *Loan,
+	schedules []LoanSchedule,
+	accrued []LateFee,
+	today time.Time,
+) ([]LateFee, error) {
+	flatCharged := map[string]bool{}
+	lastCharged := map[string]time.Time{}
+	total := loan.PrincipalAmount.ZeroLike()
+	for _, fee := range accrued {
+		switch fee.Kind {
+		case LateFeeKindFlat:
+			flatCharged[fee.ScheduleID] = true
+		case LateFeeKindDaily:
+			if day := LocalDate(fee.AccruedOn); day.After(lastCharged[fee.ScheduleID]) {
+				lastCharged[fee.ScheduleID] = day
+			}
+		}
+
+		if fee.Status == LateFeeStatusAccrued {
+			total = total.Add(fee.Amount)
+		}
+	}
+
+	capped := r.CapRate > 0
+	limit, err := loan.PrincipalAmount.MulRate(r.CapRate)
+	if err != nil {
+		return nil, err
+	}
+	headroom := limit.Sub(total)
+
+	fees := []LateFee{}
+	charge := func(schedule LoanSchedule, kind LateFeeKind, amount Amount, day time.Time) {
+		if capped {
+			amount = amount.Min(headroom)
+			headroom = headroom.Sub(amount)
+		}
+		if amount.Cmp(amount.ZeroLike()) <= 0 {
+			return
+		}
+
+		fees = append(fees, LateFee{
+			ID:         UUID(),
+			LoanID:     loan.ID,
+			ScheduleID: schedule.ID,
+			Kind:       kind,
+			Amount:     amount,
+			AccruedOn:  day,
+			Status:     LateFeeStatusAccrued,
+		})
+	}
+
+	// the grace days of the product the loan was made from win over the rules
+	graceDays := r.GraceDays
+	if loan.GraceDays != nil {
+		graceDays = *loan.GraceDays
+	}
+
+	flatAmount, hasFlat := r.FlatAmounts[loan.PrincipalAmount.Currency]
+	for _, schedule := range schedules {
+		overdueOn := LocalDate(schedule.DueDate).AddDate(0, 0, graceDays+1)
+		if today.Before(overdueOn) {
+			continue
+		}
+
+		if hasFlat && !flatCharged[schedule.ID] {
+			charge(schedule, LateFeeKindFlat, flatAmount, overdueOn)
+		}
+
+		if r.DailyRate > 0 {
+			from := overdueOn
+			if last, ok := lastCharged[schedule.ID]; ok {
+				from = last.AddDate(0, 0, 1)
+			}
+
+			// payments settle late fees first, what is left of the balance
+			// beyond them is the installment itself
+			base := schedule.Balance().Min(schedule.AmountDue.Sub(schedule.LateFeeDue))
+			daily, err := base.MulRate(r.DailyRate)
+			if err != nil {
+				return nil, err
+			}
+			for day := from; !day.After(today); day = day.AddDate(0, 0, 1) {
+				charge(schedule, LateFeeKindDaily, daily, day)
+			}
+		}
+	}
+
+	return fees, nil
+}
+
+func (s *lateFeeService) AccrueLateFees(ctx context.Context) error {
+	today := LocalDate(CurrentLocalTime())
+
+	schedules, err := s.lateFeeStore.ListOverdueSchedules(ctx, today, s.rules.GraceDays)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to list overdue schedules", "error", err)
+		return err
+	}
+
+	// a failing loan should not hold back the others
+	var errs []error
+	for start := 0; start < len(schedules); {
+		end := start
+		for end < len(schedules) && schedules[end].LoanID == schedules[start].LoanID {
+			end++
+		}
+
+		loanID := schedules[start].LoanID
+		if err := s.accrueLoan(ctx, loanID, today); err != nil {
+			s.logger.WarnContext(ctx, "failed to accrue late fees", "loanID", loanID, "error", err)
+			errs = append(errs, fmt.Errorf("loan %s: %w", loanID, err))
+		}
+
+		start = end
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *lateFeeService) accrueLoan(
+	ctx context.Context,
+	loanID string,
+	today time.Time,
+) error {
+	// the loan lock keeps payments from settling the schedules while fees are
+	// charged on their balance, the overdue listing only tells which loans to
+	// look at, the schedules are read again under the lock
+	return s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		loan, err := s.loanStore.GetLoanByIDForUpdate(ctx, loanID)
+		if err != nil {
+			return err
+		}
+
+		// paid off, cancelled or restructured away since it was listed
+		if !loan.Status.IsPayable() {
+			return nil
+		}
+
+		schedules, err := s.loanStore.GetUnsettledSchedules(ctx, loanID)
+		if err != nil {
+			return err
+		}
+
+		accrued, err := s.lateFeeStore.ListLateFeesByLoanID(ctx, loanID)
+		if err != nil {
+			return err
+		}
+
+		fees, err := s.rules.accrue(loan, schedules, accrued, today)
+		if err != nil {
+			return err
+		}
+		if len(fees) > 0 {
+			if err := s.lateFeeStore.CreateLateFees(ctx, fees); err != nil {
+				return err
+			}
+		}
+
+		// the loan is looked at because it is overdue, it may have become
+		// delinquent
+		return syncDelinquency(ctx, s.logger, s.loanStore, loan)
+	})
+}
+
+func (s *lateFeeService) GetLateFees(
+	ctx context.Context,
+	loanID string,
+) ([]LateFee, error) {
+	var fees []LateFee
+	err := s.txManager.WithinReadOnlyTx(ctx, func(ctx context.Context) error {
+		if _, err := s.loanStore.GetLoanByID(ctx, loanID); err != nil {
+			s.logger.WarnContext(ctx, "failed to get loan", "error", err)
+			return err
+		}
+
+		var err error
+		fees, err = s.lateFeeStore.ListLateFeesByLoanID(ctx, loanID)
+		if err != nil {
+			s.logger.WarnContext(ctx, "failed to list late fees", "error", err)
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return fees, nil
+}
+
+func (s *lateFeeService) WaiveLateFee(
+	ctx context.Context,
+	loanID string,
+	lateFeeID string,
+) (*LateFee, error) {
+	var fee *LateFee
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		fee, err = s.waiveLateFee(ctx, loanID, lateFeeID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return fee, nil
+}
+
+func (s *lateFeeService) waiveLateFee(
+	ctx context.Context,
+	loanID string,
+	lateFeeID string,
+) (*LateFee, error) {
+	loan, err := s.loanStore.GetLoanByIDForUpdate(ctx, loanID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to get loan", "error", err)
+		return nil, err
+	}
+
+	fees, err := s.lateFeeStore.ListLateFeesByLoanID(ctx, loanID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to list late fees", "error", err)
+		return nil, err
+	}
+
+	var fee *LateFee
+	for i := range fees {
+		if fees[i].ID == lateFeeID {
+			fee = &fees[i]
+			break
+		}
+	}
+	if fee == nil {
+		return nil, ErrLateFeeNotFound
+	}
+
+	if fee.Status != LateFeeStatusAccrued {
+		return nil, NewError(
+			ErrLateFeeNotWaivable.Error(),
+			fmt.Sprintf("late fee is already %s", fee.Status),
+			http.StatusUnprocessableEntity,
+		)
+	}
+
+	waivedAt := CurrentLocalTime()
+	fee.Status = LateFeeStatusWaived
+	fee.WaivedAt = &waivedAt
+
+	if err := s.lateFeeStore.WaiveLateFee(ctx, fee); err != nil {
+		s.logger.WarnContext(ctx, "failed to waive late fee", "error", err)
+		return nil, err
+	}
+
+	// the waived fee may have been all that was left on the loan
+	if err := settleLoanIfPaidOff(ctx, s.logger, s.loanStore, loan); err != nil {
+		return nil, err
+	}
+
+	return fee, nil
+}
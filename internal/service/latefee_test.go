@@ -0,0 +1,505 @@
+package billing_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mock_billing "github.com/theyudiriski/billing-service/internal/service/mock"
+
+	billing "github.com/theyudiriski/billing-service/internal/service"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var (
+	mockLateFeeStore *mock_billing.MockLateFeeStore
+)
+
+func provideLateFeeTest(t *testing.T, rules billing.LateFeeRules) billing.LateFeeService {
+	ctrl := gomock.NewController(t)
+
+	mockTxManager = newMockTxManager(ctrl)
+	mockLoanStore = mock_billing.NewMockLoanStore(ctrl)
+	mockLateFeeStore = mock_billing.NewMockLateFeeStore(ctrl)
+
+	return billing.NewLateFeeService(
+		billing.NewLogger(),
+		rules,
+		mockTxManager,
+		mockLoanStore,
+		mockLateFeeStore,
+	)
+}
+
+func TestAccrueLateFees(t *testing.T) {
+	Convey("AccrueLateFees", t, FailureHalts, func() {
+		type (
+			args struct {
+				ctx   context.Context
+				rules billing.LateFeeRules
+			}
+		)
+
+		var (
+			ctx   = context.Background()
+			today = billing.LocalDate(billing.CurrentLocalTime())
+			loan  = &billing.Loan{
+				ID:              "loan-id",
+				PrincipalAmount: billing.NewAmount(5_000_000),
+				Status:          billing.LoanStatusActive,
+			}
+			// three days overdue today
+			schedule = billing.LoanSchedule{
+				ID:         "schedule-id",
+				LoanID:     loan.ID,
+				Seq:        1,
+				DueDate:    today.AddDate(0, 0, -3).Add(10 * time.Hour),
+				AmountDue:  billing.NewAmount(1_000_000),
+				PaidAmount: billing.NewAmount(0),
+				LateFeeDue: billing.NewAmount(0),
+				Status:     billing.LoanScheduleStatusUnpaid,
+			}
+			rules = billing.LateFeeRules{
+				FlatAmounts: map[string]billing.Amount{
+					billing.CurrencyIDR: billing.NewAmount(50_000),
+				},
+				DailyRate: 0.001,
+			}
+		)
+
+		testCases := []struct {
+			testID   int
+			testDesc string
+			testType string
+			args     args
+			mock     func()
+		}{
+			{
+				testID:   1,
+				testDesc: "success accrue flat fee and a daily fee for every day overdue",
+				testType: "P",
+				args: args{
+					ctx:   ctx,
+					rules: rules,
+				},
+				mock: func() {
+					mockLateFeeStore.EXPECT().ListOverdueSchedules(ctx, today, 0).
+						Return([]billing.LoanSchedule{schedule}, nil)
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loan.ID).Return(loan, nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loan.ID).
+						Return([]billing.LoanSchedule{schedule}, nil)
+					mockLateFeeStore.EXPECT().ListLateFeesByLoanID(ctx, loan.ID).Return([]billing.LateFee{}, nil)
+					mockLateFeeStore.EXPECT().CreateLateFees(ctx, gomock.Any()).
+						Do(func(ctx context.Context, fees []billing.LateFee) {
+							So(fees, ShouldHaveLength, 4)
+
+							So(fees[0].Kind, ShouldEqual, billing.LateFeeKindFlat)
+							So(fees[0].Amount, ShouldEqual, billing.NewAmount(50_000))
+							So(fees[0].AccruedOn, ShouldEqual, today.AddDate(0, 0, -2))
+
+							for i, fee := range fees[1:] {
+								So(fee.Kind, ShouldEqual, billing.LateFeeKindDaily)
+								So(fee.Amount, ShouldEqual, billing.NewAmount(1_000))
+								So(fee.AccruedOn, ShouldEqual, today.AddDate(0, 0, i-2))
+								So(fee.ScheduleID, ShouldEqual, schedule.ID)
+								So(fee.Status, ShouldEqual, billing.LateFeeStatusAccrued)
+							}
+						}).Return(nil)
+					mockLoanStore.EXPECT().IsDelinquent(ctx, loan.ID).Return(false, nil)
+				},
+			},
+			{
+				testID:   2,
+				testDesc: "success accrue only the days not charged yet",
+				testType: "P",
+				args: args{
+					ctx:   ctx,
+					rules: rules,
+				},
+				mock: func() {
+					mockLateFeeStore.EXPECT().ListOverdueSchedules(ctx, today, 0).
+						Return([]billing.LoanSchedule{schedule}, nil)
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loan.ID).Return(loan, nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loan.ID).
+						Return([]billing.LoanSchedule{schedule}, nil)
+					mockLateFeeStore.EXPECT().ListLateFeesByLoanID(ctx, loan.ID).Return([]billing.LateFee{
+						{
+							ScheduleID: schedule.ID,
+							Kind:       billing.LateFeeKindFlat,
+							Amount:     billing.NewAmount(50_000),
+							AccruedOn:  today.AddDate(0, 0, -2),
+							Status:     billing.LateFeeStatusWaived,
+						},
+						{
+							ScheduleID: schedule.ID,
+							Kind:       billing.LateFeeKindDaily,
+							Amount:     billing.NewAmount(1_000),
+							AccruedOn:  today.AddDate(0, 0, -1),
+							Status:     billing.LateFeeStatusAccrued,
+						},
+					}, nil)
+					mockLateFeeStore.EXPECT().CreateLateFees(ctx, gomock.Any()).
+						Do(func(ctx context.Context, fees []billing.LateFee) {
+							So(fees, ShouldHaveLength, 1)
+							So(fees[0].Kind, ShouldEqual, billing.LateFeeKindDaily)
+							So(fees[0].AccruedOn, ShouldEqual, today)
+						}).Return(nil)
+					mockLoanStore.EXPECT().IsDelinquent(ctx, loan.ID).Return(false, nil)
+				},
+			},
+			{
+				testID:   3,
+				testDesc: "success stop accruing once the loan cap is reached",
+				testType: "P",
+				args: args{
+					ctx: ctx,
+					rules: billing.LateFeeRules{
+						FlatAmounts: rules.FlatAmounts,
+						DailyRate:   rules.DailyRate,
+						CapRate:     0.0102,
+					},
+				},
+				mock: func() {
+					mockLateFeeStore.EXPECT().ListOverdueSchedules(ctx, today, 0).
+						Return([]billing.LoanSchedule{schedule}, nil)
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loan.ID).Return(loan, nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loan.ID).
+						Return([]billing.LoanSchedule{schedule}, nil)
+					mockLateFeeStore.EXPECT().ListLateFeesByLoanID(ctx, loan.ID).Return([]billing.LateFee{}, nil)
+					mockLateFeeStore.EXPECT().CreateLateFees(ctx, gomock.Any()).
+						Do(func(ctx context.Context, fees []billing.LateFee) {
+							// 51,000 cap: filled by the flat fee and a single daily fee
+							So(fees, ShouldHaveLength, 2)
+							So(fees[1].Amount, ShouldEqual, billing.NewAmount(1_000))
+						}).Return(nil)
+					mockLoanStore.EXPECT().IsDelinquent(ctx, loan.ID).Return(false, nil)
+				},
+			},
+			{
+				testID:   4,
+				testDesc: "success nothing to accrue within the grace period",
+				testType: "P",
+				args: args{
+					ctx: ctx,
+					rules: billing.LateFeeRules{
+						GraceDays:   5,
+						FlatAmounts: rules.FlatAmounts,
+						DailyRate:   rules.DailyRate,
+					},
+				},
+				mock: func() {
+					mockLateFeeStore.EXPECT().ListOverdueSchedules(ctx, today, 5).
+						Return([]billing.LoanSchedule{}, nil)
+				},
+			},
+			{
+				testID:   5,
+				testDesc: "failed list overdue schedules",
+				testType: "N",
+				args: args{
+					ctx:   ctx,
+					rules: rules,
+				},
+				mock: func() {
+					mockLateFeeStore.EXPECT().ListOverdueSchedules(ctx, today, 0).Return(nil, errMock)
+				},
+			},
+			{
+				testID:   6,
+				testDesc: "failed create late fees",
+				testType: "N",
+				args: args{
+					ctx:   ctx,
+					rules: rules,
+				},
+				mock: func() {
+					mockLateFeeStore.EXPECT().ListOverdueSchedules(ctx, today, 0).
+						Return([]billing.LoanSchedule{schedule}, nil)
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loan.ID).Return(loan, nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loan.ID).
+						Return([]billing.LoanSchedule{schedule}, nil)
+					mockLateFeeStore.EXPECT().ListLateFeesByLoanID(ctx, loan.ID).Return([]billing.LateFee{}, nil)
+					mockLateFeeStore.EXPECT().CreateLateFees(ctx, gomock.Any()).Return(errMock)
+				},
+			},
+			{
+				testID:   7,
+				testDesc: "success product grace days win over the rules",
+				testType: "P",
+				args: args{
+					ctx:   ctx,
+					rules: rules,
+				},
+				mock: func() {
+					graceDays := 2
+					productLoan := *loan
+					productLoan.GraceDays = &graceDays
+
+					mockLateFeeStore.EXPECT().ListOverdueSchedules(ctx, today, 0).
+						Return([]billing.LoanSchedule{schedule}, nil)
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loan.ID).Return(&productLoan, nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loan.ID).
+						Return([]billing.LoanSchedule{schedule}, nil)
+					mockLateFeeStore.EXPECT().ListLateFeesByLoanID(ctx, loan.ID).Return([]billing.LateFee{}, nil)
+					mockLateFeeStore.EXPECT().CreateLateFees(ctx, gomock.Any()).
+						Do(func(ctx context.Context, fees []billing.LateFee) {
+							// overdue from today, after two days of grace
+							So(fees, ShouldHaveLength, 2)
+							So(fees[0].Kind, ShouldEqual, billing.LateFeeKindFlat)
+							So(fees[0].AccruedOn, ShouldEqual, today)
+							So(fees[1].AccruedOn, ShouldEqual, today)
+						}).Return(nil)
+					mockLoanStore.EXPECT().IsDelinquent(ctx, loan.ID).Return(false, nil)
+				},
+			},
+			{
+				testID:   8,
+				testDesc: "success skip a schedule paid since it was listed",
+				testType: "P",
+				args: args{
+					ctx:   ctx,
+					rules: rules,
+				},
+				mock: func() {
+					mockLateFeeStore.EXPECT().ListOverdueSchedules(ctx, today, 0).
+						Return([]billing.LoanSchedule{schedule}, nil)
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loan.ID).Return(loan, nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loan.ID).Return(nil, nil)
+					mockLateFeeStore.EXPECT().ListLateFeesByLoanID(ctx, loan.ID).Return([]billing.LateFee{}, nil)
+					mockLoanStore.EXPECT().IsDelinquent(ctx, loan.ID).Return(false, nil)
+				},
+			},
+			{
+				testID:   9,
+				testDesc: "success skip a loan paid off since it was listed",
+				testType: "P",
+				args: args{
+					ctx:   ctx,
+					rules: rules,
+				},
+				mock: func() {
+					paidOff := *loan
+					paidOff.Status = billing.LoanStatusPaidOff
+
+					mockLateFeeStore.EXPECT().ListOverdueSchedules(ctx, today, 0).
+						Return([]billing.LoanSchedule{schedule}, nil)
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loan.ID).Return(&paidOff, nil)
+				},
+			},
+			{
+				testID:   10,
+				testDesc: "success mark the loan delinquent",
+				testType: "P",
+				args: args{
+					ctx:   ctx,
+					rules: billing.LateFeeRules{},
+				},
+				mock: func() {
+					activeLoan := *loan
+
+					mockLateFeeStore.EXPECT().ListOverdueSchedules(ctx, today, 0).
+						Return([]billing.LoanSchedule{schedule}, nil)
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loan.ID).Return(&activeLoan, nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loan.ID).
+						Return([]billing.LoanSchedule{schedule}, nil)
+					mockLateFeeStore.EXPECT().ListLateFeesByLoanID(ctx, loan.ID).Return([]billing.LateFee{}, nil)
+					mockLoanStore.EXPECT().IsDelinquent(ctx, loan.ID).Return(true, nil)
+					mockLoanStore.EXPECT().
+						UpdateLoanStatus(ctx, loan.ID, billing.LoanStatusActive, billing.LoanStatusDelinquent).
+						Return(nil)
+				},
+			},
+			{
+				testID:   11,
+				testDesc: "success a delinquent loan under a deferral is active again",
+				testType: "P",
+				args: args{
+					ctx:   ctx,
+					rules: billing.LateFeeRules{},
+				},
+				mock: func() {
+					delinquentLoan := *loan
+					delinquentLoan.Status = billing.LoanStatusDelinquent
+
+					mockLateFeeStore.EXPECT().ListOverdueSchedules(ctx, today, 0).
+						Return([]billing.LoanSchedule{schedule}, nil)
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loan.ID).Return(&delinquentLoan, nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loan.ID).
+						Return([]billing.LoanSchedule{schedule}, nil)
+					mockLateFeeStore.EXPECT().ListLateFeesByLoanID(ctx, loan.ID).Return([]billing.LateFee{}, nil)
+					mockLoanStore.EXPECT().IsDelinquent(ctx, loan.ID).Return(false, nil)
+					mockLoanStore.EXPECT().
+						UpdateLoanStatus(ctx, loan.ID, billing.LoanStatusDelinquent, billing.LoanStatusActive).
+						Return(nil)
+				},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Logf("%d - [%s] : %s", tc.testID, tc.testType, tc.testDesc)
+			lateFeeService := provideLateFeeTest(t, tc.args.rules)
+			tc.mock()
+
+			err := lateFeeService.AccrueLateFees(tc.args.ctx)
+
+			if tc.testType == "P" {
+				So(err, ShouldBeNil)
+			} else {
+				So(err, ShouldNotBeNil)
+			}
+		}
+	})
+}
+
+func TestWaiveLateFee(t *testing.T) {
+	Convey("WaiveLateFee", t, FailureHalts, func() {
+		type (
+			args struct {
+				ctx       context.Context
+				loanID    string
+				lateFeeID string
+			}
+		)
+
+		var (
+			ctx  = context.Background()
+			loan = &billing.Loan{
+				ID:              "loan-id",
+				PrincipalAmount: billing.NewAmount(5_000_000),
+				Status:          billing.LoanStatusActive,
+			}
+			fee = billing.LateFee{
+				ID:         "late-fee-id",
+				LoanID:     loan.ID,
+				ScheduleID: "schedule-id",
+				Kind:       billing.LateFeeKindFlat,
+				Amount:     billing.NewAmount(50_000),
+				Status:     billing.LateFeeStatusAccrued,
+			}
+			waivedFee = billing.LateFee{
+				ID:     "waived-late-fee-id",
+				LoanID: loan.ID,
+				Kind:   billing.LateFeeKindDaily,
+				Amount: billing.NewAmount(1_000),
+				Status: billing.LateFeeStatusWaived,
+			}
+		)
+
+		testCases := []struct {
+			testID   int
+			testDesc string
+			testType string
+			args     args
+			mock     func()
+		}{
+			{
+				testID:   1,
+				testDesc: "success waive late fee",
+				testType: "P",
+				args: args{
+					ctx:       ctx,
+					loanID:    loan.ID,
+					lateFeeID: fee.ID,
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loan.ID).Return(loan, nil)
+					mockLateFeeStore.EXPECT().ListLateFeesByLoanID(ctx, loan.ID).
+						Return([]billing.LateFee{fee, waivedFee}, nil)
+					mockLateFeeStore.EXPECT().WaiveLateFee(ctx, gomock.Any()).
+						Do(func(ctx context.Context, waived *billing.LateFee) {
+							So(waived.ID, ShouldEqual, fee.ID)
+							So(waived.Status, ShouldEqual, billing.LateFeeStatusWaived)
+							So(waived.WaivedAt, ShouldNotBeNil)
+						}).Return(nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loan.ID).
+						Return([]billing.LoanSchedule{{ID: fee.ScheduleID}}, nil)
+					mockLoanStore.EXPECT().IsDelinquent(ctx, loan.ID).Return(false, nil)
+				},
+			},
+			{
+				testID:   2,
+				testDesc: "success waive last late fee pays off the loan",
+				testType: "P",
+				args: args{
+					ctx:       ctx,
+					loanID:    loan.ID,
+					lateFeeID: fee.ID,
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loan.ID).Return(loan, nil)
+					mockLateFeeStore.EXPECT().ListLateFeesByLoanID(ctx, loan.ID).
+						Return([]billing.LateFee{fee}, nil)
+					mockLateFeeStore.EXPECT().WaiveLateFee(ctx, gomock.Any()).Return(nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loan.ID).
+						Return([]billing.LoanSchedule{}, nil)
+					mockLoanStore.EXPECT().
+						UpdateLoanStatus(ctx, loan.ID, billing.LoanStatusActive, billing.LoanStatusPaidOff).
+						Return(nil)
+				},
+			},
+			{
+				testID:   3,
+				testDesc: "failed late fee not found",
+				testType: "N",
+				args: args{
+					ctx:       ctx,
+					loanID:    loan.ID,
+					lateFeeID: "unknown-late-fee-id",
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loan.ID).Return(loan, nil)
+					mockLateFeeStore.EXPECT().ListLateFeesByLoanID(ctx, loan.ID).
+						Return([]billing.LateFee{fee}, nil)
+				},
+			},
+			{
+				testID:   4,
+				testDesc: "failed late fee already waived",
+				testType: "N",
+				args: args{
+					ctx:       ctx,
+					loanID:    loan.ID,
+					lateFeeID: waivedFee.ID,
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loan.ID).Return(loan, nil)
+					mockLateFeeStore.EXPECT().ListLateFeesByLoanID(ctx, loan.ID).
+						Return([]billing.LateFee{fee, waivedFee}, nil)
+				},
+			},
+			{
+				testID:   5,
+				testDesc: "failed waive late fee",
+				testType: "N",
+				args: args{
+					ctx:       ctx,
+					loanID:    loan.ID,
+					lateFeeID: fee.ID,
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loan.ID).Return(loan, nil)
+					mockLateFeeStore.EXPECT().ListLateFeesByLoanID(ctx, loan.ID).
+						Return([]billing.LateFee{fee}, nil)
+					mockLateFeeStore.EXPECT().WaiveLateFee(ctx, gomock.Any()).
+						Return(billing.ErrLateFeeNotWaivable)
+				},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Logf("%d - [%s] : %s", tc.testID, tc.testType, tc.testDesc)
+			lateFeeService := provideLateFeeTest(t, billing.LateFeeRules{})
+			tc.mock()
+
+			_, err := lateFeeService.WaiveLateFee(tc.args.ctx, tc.args.loanID, tc.args.lateFeeID)
+
+			if tc.testType == "P" {
+				So(err, ShouldBeNil)
+			} else {
+				So(err, ShouldNotBeNil)
+			}
+		}
+	})
+}
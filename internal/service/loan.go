@@ -2,6 +2,7 @@ package billing
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -9,59 +10,243 @@ import (
 )
 
 type LoanService interface {
+	// CreateLoan makes a loan from a product, the application fields left out
+	// are taken from the product when it allows a single value.
 	CreateLoan(
 		ctx context.Context,
 		borrowerID string,
-		principalAmount Amount,
-		interestRate float64,
-		paymentFrequency LoanFrequency,
-		totalPayments int,
+		productID string,
+		application LoanApplication,
 	) (*Loan, error)
+	// DisburseLoan records the payout of a loan pending disbursement and starts
+	// its repayment.
+	DisburseLoan(ctx context.Context, loanID string, disbursement LoanDisbursement) (*Loan, error)
+	// CancelLoan cancels a loan before disbursement or within the cooling-off
+	// period after it.
+	CancelLoan(ctx context.Context, loanID string, reason string) (*Loan, error)
+	// RestructureLoan supersedes the unsettled schedules of a loan with a new
+	// version of its schedule on the agreed terms.
+	RestructureLoan(ctx context.Context, loanID string, terms LoanRestructureTerms) (*LoanRestructure, error)
+	// DeferInstallments skips the next upcoming installments of a loan, pushing
+	// them to the end of its schedule. Overdue installments stay due and keep
+	// counting towards delinquency.
+	DeferInstallments(ctx context.Context, loanID string, terms LoanDeferralTerms) (*LoanDeferral, error)
+	// WriteOffLoan closes an active or delinquent loan as unrecoverable.
+	WriteOffLoan(ctx context.Context, loanID string, reason string) (*Loan, error)
 	GetOutstanding(ctx context.Context, loanID string) (*OutstandingLoan, error)
 	IsDelinquent(ctx context.Context, loanID string) (bool, error)
 	GetTotalPending(ctx context.Context, loanID string) (*PendingLoan, error)
-	PayLoan(ctx context.Context, loanID string, payAmount Amount) error
+	PayLoan(
+		ctx context.Context,
+		loanID string,
+		payAmount Amount,
+		channel string,
+		externalReference string,
+		idempotencyKey string,
+	) (*Payment, error)
+	GetPayments(ctx context.Context, loanID string) ([]Payment, error)
+	GetSchedules(ctx context.Context, loanID string, filter LoanScheduleFilter) ([]LoanSchedule, error)
+	ListBorrowerLoans(ctx context.Context, borrowerID string, filter BorrowerLoanFilter) (*BorrowerLoans, error)
 }
 
 type LoanStore interface {
 	CreateLoan(ctx context.Context, loan *Loan) error
 	GetLoanByID(ctx context.Context, loanID string) (*Loan, error)
-	GetOutstanding(ctx context.Context, loanID string) (*Amount, error)
+	// GetLoanByIDForUpdate returns the loan and locks it until the transaction
+	// ends.
+	GetLoanByIDForUpdate(ctx context.Context, loanID string) (*Loan, error)
+	GetOutstanding(ctx context.Context, loanID string) (*AmountBreakdown, error)
 	IsDelinquent(ctx context.Context, userID string) (bool, error)
-	GetTotalPending(ctx context.Context, loanID string) (*Amount, error)
-	MarkPendingAsPaid(ctx context.Context, loanID string) error
+	GetTotalPending(ctx context.Context, loanID string) (*AmountBreakdown, error)
+	GetUnsettledSchedules(ctx context.Context, loanID string) ([]LoanSchedule, error)
+	ListSchedules(ctx context.Context, loanID string, filter LoanScheduleFilter) ([]LoanSchedule, error)
+	// HasPendingAdjustments tells whether the loan has adjustments waiting for
+	// approval.
+	HasPendingAdjustments(ctx context.Context, loanID string) (bool, error)
+	UpdateLoanStatus(ctx context.Context, loanID string, from, to LoanStatus) error
+	// DisburseLoan activates a loan pending disbursement, recording its
+	// disbursement, dates and schedule due dates and amounts. It fails with
+	// ErrDisbursementReferenceConflict when another loan has the reference.
+	DisburseLoan(ctx context.Context, loan *Loan) error
+	// CancelLoan moves the loan from the given status to cancelled, recording its
+	// cancellation, and voids its schedules.
+	CancelLoan(ctx context.Context, loan *Loan, from LoanStatus) error
+	// RestructureLoan records the restructure, marks the unsettled schedules of
+	// the loan superseded and adds the restructure schedules, then moves the loan
+	// from the given status to the one on the loan along with its end date and
+	// number of payments.
+	RestructureLoan(ctx context.Context, loan *Loan, restructure *LoanRestructure, from LoanStatus) error
+	// DeferInstallments records the deferral and moves the deferred schedules to
+	// their new due dates, along with the end date of the loan.
+	DeferInstallments(ctx context.Context, loan *Loan, deferral *LoanDeferral) error
+	// WriteOffLoan moves the loan from the given status to written off,
+	// recording its write-off.
+	WriteOffLoan(ctx context.Context, loan *Loan, from LoanStatus) error
+	// ListLoansByBorrowerID returns up to filter.Limit loans of the borrower
+	// matching the filter, newest first.
+	ListLoansByBorrowerID(ctx context.Context, borrowerID string, filter BorrowerLoanFilter) ([]Loan, error)
+	GetBorrowerExposure(ctx context.Context, borrowerID string) (*BorrowerExposure, error)
 }
 
-func NewLoanService(logger Logger, loanStore LoanStore) LoanService {
+func NewLoanService(
+	logger Logger,
+	creditPolicy CreditPolicy,
+	coolOffDays int,
+	txManager TxManager,
+	borrowerStore BorrowerStore,
+	productStore LoanProductStore,
+	loanStore LoanStore,
+	paymentStore PaymentStore,
+) LoanService {
 	return &loanService{
-		logger:    logger,
-		loanStore: loanStore,
+		logger:        logger,
+		creditPolicy:  creditPolicy,
+		coolOffDays:   coolOffDays,
+		txManager:     txManager,
+		borrowerStore: borrowerStore,
+		productStore:  productStore,
+		loanStore:     loanStore,
+		paymentStore:  paymentStore,
 	}
 }
 
 type loanService struct {
-	logger    Logger
-	loanStore LoanStore
+	logger        Logger
+	creditPolicy  CreditPolicy
+	coolOffDays   int
+	txManager     TxManager
+	borrowerStore BorrowerStore
+	productStore  LoanProductStore
+	loanStore     LoanStore
+	paymentStore  PaymentStore
 }
 
 type Loan struct {
-	ID               string
-	BorrowerID       string
+	ID         string
+	BorrowerID string
+	// product the loan was made from, nil for loans made before products
+	ProductID        *string
 	PrincipalAmount  Amount
 	InterestRate     float64
+	InterestModel    InterestModel
+	FeeAmount        Amount
 	StartedAt        time.Time
 	EndedAt          time.Time
 	PaymentFrequency LoanFrequency
 	TotalPayments    int
-
-	// for schedules
-	LoanTermDays int
-	TermAmount   Amount
+	Status           LoanStatus
+	// days after a due date before late fees accrue, nil follows the late fee
+	// rules
+	GraceDays *int
+	// nil until the loan is disbursed, StartedAt is when the loan was made until
+	// then and the disbursement date after
+	Disbursement *LoanDisbursement
+	// nil unless the loan is cancelled
+	Cancellation *LoanCancellation
+	// nil unless the loan is written off
+	WriteOff *LoanWriteOff
+	// when the loan was made, it does not move on disbursement like StartedAt
+	CreatedAt time.Time
+
+	Schedules []LoanSchedule
 }
 
 type LoanSchedule struct {
-	ID        string
-	AmountDue Amount
+	ID         string
+	LoanID     string
+	Seq        int
+	DueDate    time.Time
+	AmountDue  Amount
+	PaidAmount Amount
+	Status     LoanScheduleStatus
+	PaidAt     *time.Time
+
+	// components of AmountDue
+	PrincipalDue Amount
+	InterestDue  Amount
+	FeeDue       Amount
+	// late fees accrued while the schedule was overdue, waived ones excluded
+	LateFeeDue Amount
+
+	// schedule version the installment belongs to, starting from 1, a
+	// restructure supersedes the unsettled installments with a new version
+	Version int
+	// date the due dates of the version count their periods from, the start
+	// of the loan or the day it was restructured
+	AnchoredAt time.Time
+}
+
+// Balance returns the amount left to settle the schedule.
+func (s LoanSchedule) Balance() Amount {
+	return s.AmountDue.Sub(s.PaidAmount)
+}
+
+// Remaining splits what is left to settle the schedule into its components,
+// what has been paid settles the late fee first, then fee, then interest, then
+// principal.
+func (s LoanSchedule) Remaining() AmountBreakdown {
+	paid := s.PaidAmount
+	settle := func(due Amount) Amount {
+		settled := paid.Min(due)
+		paid = paid.Sub(settled)
+		return due.Sub(settled)
+	}
+
+	lateFee := settle(s.LateFeeDue)
+	fee := settle(s.FeeDue)
+	interest := settle(s.InterestDue)
+	principal := settle(s.PrincipalDue)
+
+	return AmountBreakdown{
+		Principal: principal,
+		Interest:  interest,
+		Fee:       fee,
+		LateFee:   lateFee,
+	}
+}
+
+type (
+	LoanScheduleStatus string
+)
+
+var (
+	LoanScheduleStatusUnpaid        LoanScheduleStatus = "unpaid"
+	LoanScheduleStatusPartiallyPaid LoanScheduleStatus = "partially_paid"
+	LoanScheduleStatusPaid          LoanScheduleStatus = "paid"
+	// the loan was cancelled, nothing is due on the schedule anymore
+	LoanScheduleStatusVoid LoanScheduleStatus = "void"
+	// replaced by a restructured schedule, kept for audit
+	LoanScheduleStatusSuperseded LoanScheduleStatus = "superseded"
+
+	LoanScheduleStatuses = []LoanScheduleStatus{
+		LoanScheduleStatusUnpaid,
+		LoanScheduleStatusPartiallyPaid,
+		LoanScheduleStatusPaid,
+		LoanScheduleStatusVoid,
+		LoanScheduleStatusSuperseded,
+	}
+)
+
+func (l *LoanScheduleStatus) UnmarshalText(text []byte) error {
+	for _, status := range LoanScheduleStatuses {
+		if strings.EqualFold(string(status), string(text)) {
+			*l = status
+			return nil
+		}
+	}
+	return NewError(
+		ErrValidationError.Error(),
+		fmt.Sprintf("LoanScheduleStatus should be one of %v", LoanScheduleStatuses),
+		http.StatusBadRequest,
+	)
+}
+
+// LoanScheduleFilter narrows down a schedule listing, zero values match everything.
+type LoanScheduleFilter struct {
+	Statuses []LoanScheduleStatus
+	// due dates within [DueFrom, DueTo)
+	DueFrom *time.Time
+	DueTo   *time.Time
 }
 
 type (
@@ -69,13 +254,30 @@ type (
 )
 
 var (
-	LoanFrequencyWeekly LoanFrequency = "weekly"
+	LoanFrequencyDaily       LoanFrequency = "daily"
+	LoanFrequencyWeekly      LoanFrequency = "weekly"
+	LoanFrequencyBiWeekly    LoanFrequency = "bi_weekly"
+	LoanFrequencySemiMonthly LoanFrequency = "semi_monthly"
+	LoanFrequencyMonthly     LoanFrequency = "monthly"
 
 	LoanFrequencies = []LoanFrequency{
+		LoanFrequencyDaily,
 		LoanFrequencyWeekly,
+		LoanFrequencyBiWeekly,
+		LoanFrequencySemiMonthly,
+		LoanFrequencyMonthly,
 	}
 )
 
+func (l LoanFrequency) IsValid() bool {
+	for _, frequency := range LoanFrequencies {
+		if frequency == l {
+			return true
+		}
+	}
+	return false
+}
+
 func (l *LoanFrequency) UnmarshalText(text []byte) error {
 	for _, frequency := range LoanFrequencies {
 		if strings.EqualFold(string(frequency), string(text)) {
@@ -90,52 +292,216 @@ func (l *LoanFrequency) UnmarshalText(text []byte) error {
 	)
 }
 
+type (
+	LoanStatus string
+)
+
+var (
+	LoanStatusPendingDisbursement LoanStatus = "pending_disbursement"
+	LoanStatusActive              LoanStatus = "active"
+	LoanStatusDelinquent          LoanStatus = "delinquent"
+	LoanStatusPaidOff             LoanStatus = "paid_off"
+	LoanStatusWrittenOff          LoanStatus = "written_off"
+	LoanStatusCancelled           LoanStatus = "cancelled"
+
+	LoanStatuses = []LoanStatus{
+		LoanStatusPendingDisbursement,
+		LoanStatusActive,
+		LoanStatusDelinquent,
+		LoanStatusPaidOff,
+		LoanStatusWrittenOff,
+		LoanStatusCancelled,
+	}
+
+	// loanStatusTransitions lists the statuses a loan may move to from each status,
+	// closed statuses have no way out.
+	loanStatusTransitions = map[LoanStatus][]LoanStatus{
+		LoanStatusPendingDisbursement: {LoanStatusActive, LoanStatusCancelled},
+		LoanStatusActive:              {LoanStatusDelinquent, LoanStatusPaidOff, LoanStatusWrittenOff, LoanStatusCancelled},
+		LoanStatusDelinquent:          {LoanStatusActive, LoanStatusPaidOff, LoanStatusWrittenOff},
+	}
+)
+
+func (l LoanStatus) IsValid() bool {
+	for _, status := range LoanStatuses {
+		if status == l {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *LoanStatus) UnmarshalText(text []byte) error {
+	for _, status := range LoanStatuses {
+		if strings.EqualFold(string(status), string(text)) {
+			*l = status
+			return nil
+		}
+	}
+	return NewError(
+		ErrValidationError.Error(),
+		fmt.Sprintf("LoanStatus should be one of %v", LoanStatuses),
+		http.StatusBadRequest,
+	)
+}
+
+func (l LoanStatus) CanTransitionTo(next LoanStatus) bool {
+	for _, status := range loanStatusTransitions[l] {
+		if status == next {
+			return true
+		}
+	}
+	return false
+}
+
+// IsClosed reports whether the loan has reached a terminal status.
+func (l LoanStatus) IsClosed() bool {
+	return len(loanStatusTransitions[l]) == 0
+}
+
+// IsPayable reports whether the loan accepts repayments in this status.
+func (l LoanStatus) IsPayable() bool {
+	return l == LoanStatusActive || l == LoanStatusDelinquent
+}
+
+// AmountBreakdown splits an amount owed into its components. Payments on a
+// schedule settle its late fee first, then fee, then interest, then principal.
+type AmountBreakdown struct {
+	Principal Amount
+	Interest  Amount
+	Fee       Amount
+	LateFee   Amount
+}
+
+func (b AmountBreakdown) Total() Amount {
+	return b.Principal.Add(b.Interest).Add(b.Fee).Add(b.LateFee)
+}
+
+func (b AmountBreakdown) add(other AmountBreakdown) AmountBreakdown {
+	return AmountBreakdown{
+		Principal: b.Principal.Add(other.Principal),
+		Interest:  b.Interest.Add(other.Interest),
+		Fee:       b.Fee.Add(other.Fee),
+		LateFee:   b.LateFee.Add(other.LateFee),
+	}
+}
+
 type OutstandingLoan struct {
-	ID     string `json:"id"`
-	Amount string `json:"outstanding_amount"`
+	ID        string `json:"id"`
+	Amount    string `json:"outstanding_amount"`
+	Principal string `json:"principal_amount"`
+	Interest  string `json:"interest_amount"`
+	Fee       string `json:"fee_amount"`
+	LateFee   string `json:"late_fee_amount"`
+	Currency  string `json:"currency"`
 }
 
 type PendingLoan struct {
-	ID     string `json:"id"`
-	Amount string `json:"pending_amount"`
+	ID        string `json:"id"`
+	Amount    string `json:"pending_amount"`
+	Principal string `json:"principal_amount"`
+	Interest  string `json:"interest_amount"`
+	Fee       string `json:"fee_amount"`
+	LateFee   string `json:"late_fee_amount"`
+	Currency  string `json:"currency"`
 }
 
-// ! For now we always assume borrower_id is valid, despite it is random UUID
-
 func (s *loanService) CreateLoan(
 	ctx context.Context,
 	borrowerID string,
-	principalAmount Amount,
-	interestRate float64,
-	paymentFrequency LoanFrequency,
-	totalPayments int,
+	productID string,
+	application LoanApplication,
 ) (*Loan, error) {
-	principalFloat := principalAmount.ToFloat64()
+	if application.PrincipalAmount.Cmp(application.PrincipalAmount.ZeroLike()) <= 0 {
+		return nil, NewError(
+			ErrValidationError.Error(),
+			"principal amount must be greater than 0",
+			http.StatusBadRequest,
+		)
+	}
 
-	// calculate total amount & term amount
-	totalAmount := principalFloat * (1 + interestRate)
-	payment := totalAmount / float64(totalPayments)
-	termAmount := NewAmount(payment)
+	if application.PaymentFrequency != "" && !application.PaymentFrequency.IsValid() {
+		return nil, NewError(
+			ErrValidationError.Error(),
+			fmt.Sprintf("LoanFrequency should be one of %v", LoanFrequencies),
+			http.StatusBadRequest,
+		)
+	}
 
-	// calculate loan term in days
-	loanTermDays := convertLoanTermToDays(paymentFrequency, totalPayments)
+	if application.InterestRate < 0 || application.TotalPayments < 0 {
+		return nil, NewError(
+			ErrValidationError.Error(),
+			"interest rate and total payments must not be negative",
+			http.StatusBadRequest,
+		)
+	}
 
-	// calculate start and end date
-	start := CurrentLocalTime()
-	end := start.AddDate(0, 0, loanTermDays)
+	// the borrower stays locked from checking the credit policy until the loan
+	// is created, concurrent applications by the same borrower wait their turn
+	var loan *Loan
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		loan, err = s.createLoan(ctx, borrowerID, productID, application)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	loan := &Loan{
-		ID:               UUID(),
-		BorrowerID:       borrowerID,
-		PrincipalAmount:  principalAmount,
-		InterestRate:     interestRate,
-		StartedAt:        start,
-		EndedAt:          end,
-		PaymentFrequency: paymentFrequency,
-		TotalPayments:    totalPayments,
+	return loan, nil
+}
+
+func (s *loanService) createLoan(
+	ctx context.Context,
+	borrowerID string,
+	productID string,
+	application LoanApplication,
+) (*Loan, error) {
+	product, err := s.productStore.GetProductByID(ctx, productID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to get loan product", "error", err)
+		return nil, err
+	}
+
+	if product.Status != LoanProductStatusActive {
+		s.logger.WarnContext(ctx, "loan product is not active", "status", product.Status)
+		return nil, ErrLoanProductRetired
+	}
+
+	application, err = product.resolve(application)
+	if err != nil {
+		s.logger.WarnContext(ctx, "loan outside product terms", "error", err)
+		return nil, err
+	}
 
-		LoanTermDays: loanTermDays,
-		TermAmount:   termAmount,
+	borrower, err := s.borrowerStore.GetBorrowerByIDForUpdate(ctx, borrowerID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to get borrower", "error", err)
+		return nil, err
+	}
+
+	// only borrowers who passed KYC can take a loan
+	if borrower.KYCStatus != KYCStatusVerified {
+		s.logger.WarnContext(ctx, "borrower is not verified", "kycStatus", borrower.KYCStatus)
+		return nil, ErrBorrowerNotVerified
+	}
+
+	exposure, err := s.loanStore.GetBorrowerExposure(ctx, borrowerID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to get borrower exposure", "error", err)
+		return nil, err
+	}
+
+	if err := s.creditPolicy.check(exposure, application.PrincipalAmount); err != nil {
+		s.logger.WarnContext(ctx, "loan refused by credit policy", "error", err,
+			"activeLoans", exposure.ActiveLoans, "delinquentLoans", exposure.DelinquentLoans)
+		return nil, err
+	}
+
+	loan, err := newLoan(borrowerID, product, application)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to build loan", "error", err)
+		return nil, err
 	}
 
 	if err := s.loanStore.CreateLoan(ctx, loan); err != nil {
@@ -146,38 +512,95 @@ func (s *loanService) CreateLoan(
 	return loan, nil
 }
 
-func convertLoanTermToDays(
-	paymentFrequency LoanFrequency,
-	totalPayments int,
-) int {
-	// currently only support weekly
-	switch paymentFrequency {
-	case LoanFrequencyWeekly:
-		return totalPayments * 7
-	default:
-		return 0
+// newLoan builds the loan and its schedules for an application resolved against
+// the product terms.
+func newLoan(
+	borrowerID string,
+	product *LoanProduct,
+	application LoanApplication,
+) (*Loan, error) {
+	principalAmount := application.PrincipalAmount
+	paymentFrequency := application.PaymentFrequency
+	totalPayments := application.TotalPayments
+
+	// split every installment into its principal and interest components, the
+	// fee is spread evenly on top of them
+	installments, err := product.InterestModel.splitInstallments(
+		principalAmount,
+		application.InterestRate,
+		paymentFrequency,
+		totalPayments,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	feeAmount, err := product.Fee(principalAmount)
+	if err != nil {
+		return nil, err
+	}
+	for i, fee := range feeAmount.Allocate(totalPayments) {
+		installments[i].Fee = fee
+	}
+
+	// the dates are tentative until the loan is disbursed, the loan ends on the
+	// due date of its last installment
+	start := CurrentLocalTime()
+	end := paymentFrequency.DueDate(start, totalPayments)
+
+	graceDays := product.GraceDays
+	loan := &Loan{
+		ID:               UUID(),
+		BorrowerID:       borrowerID,
+		ProductID:        &product.ID,
+		PrincipalAmount:  principalAmount,
+		InterestRate:     application.InterestRate,
+		InterestModel:    product.InterestModel,
+		FeeAmount:        feeAmount,
+		StartedAt:        start,
+		EndedAt:          end,
+		PaymentFrequency: paymentFrequency,
+		TotalPayments:    totalPayments,
+		Status:           LoanStatusPendingDisbursement,
+		GraceDays:        &graceDays,
+		CreatedAt:        start,
 	}
+	loan.Schedules = buildSchedules(loan, installments)
+
+	return loan, nil
 }
 
 func (s *loanService) GetOutstanding(
 	ctx context.Context,
 	loanID string,
 ) (*OutstandingLoan, error) {
-	_, err := s.loanStore.GetLoanByID(ctx, loanID)
-	if err != nil {
-		s.logger.WarnContext(ctx, "failed to get loan", "error", err)
-		return nil, err
-	}
+	var outstandingAmount *AmountBreakdown
+	err := s.txManager.WithinReadOnlyTx(ctx, func(ctx context.Context) error {
+		if err := s.ensureLoanExists(ctx, loanID); err != nil {
+			return err
+		}
 
-	outstandingAmount, err := s.loanStore.GetOutstanding(ctx, loanID)
+		var err error
+		outstandingAmount, err = s.loanStore.GetOutstanding(ctx, loanID)
+		if err != nil {
+			s.logger.WarnContext(ctx, "failed to get outstanding loan", "error", err)
+			return err
+		}
+
+		return nil
+	})
 	if err != nil {
-		s.logger.WarnContext(ctx, "failed to get outstanding loan", "error", err)
 		return nil, err
 	}
 
 	return &OutstandingLoan{
-		ID:     loanID,
-		Amount: outstandingAmount.String(),
+		ID:        loanID,
+		Amount:    outstandingAmount.Total().String(),
+		Principal: outstandingAmount.Principal.String(),
+		Interest:  outstandingAmount.Interest.String(),
+		Fee:       outstandingAmount.Fee.String(),
+		LateFee:   outstandingAmount.LateFee.String(),
+		Currency:  outstandingAmount.Principal.Currency,
 	}, nil
 }
 
@@ -185,70 +608,337 @@ func (s *loanService) IsDelinquent(
 	ctx context.Context,
 	loanID string,
 ) (bool, error) {
-	_, err := s.loanStore.GetLoanByID(ctx, loanID)
-	if err != nil {
-		s.logger.WarnContext(ctx, "failed to get loan", "error", err)
-		return false, err
-	}
+	var delinquent bool
+	err := s.txManager.WithinReadOnlyTx(ctx, func(ctx context.Context) error {
+		if err := s.ensureLoanExists(ctx, loanID); err != nil {
+			return err
+		}
 
-	return s.loanStore.IsDelinquent(ctx, loanID)
+		var err error
+		delinquent, err = s.loanStore.IsDelinquent(ctx, loanID)
+		return err
+	})
+
+	return delinquent, err
 }
 
 func (s *loanService) GetTotalPending(
 	ctx context.Context,
 	loanID string,
 ) (*PendingLoan, error) {
-	_, err := s.loanStore.GetLoanByID(ctx, loanID)
+	var pendingAmount *AmountBreakdown
+	err := s.txManager.WithinReadOnlyTx(ctx, func(ctx context.Context) error {
+		if err := s.ensureLoanExists(ctx, loanID); err != nil {
+			return err
+		}
+
+		var err error
+		pendingAmount, err = s.loanStore.GetTotalPending(ctx, loanID)
+		if err != nil {
+			s.logger.WarnContext(ctx, "failed to get pending loan", "error", err)
+			return err
+		}
+
+		return nil
+	})
 	if err != nil {
-		s.logger.WarnContext(ctx, "failed to get loan", "error", err)
 		return nil, err
 	}
 
-	pendingAmount, err := s.loanStore.GetTotalPending(ctx, loanID)
+	return &PendingLoan{
+		ID:        loanID,
+		Amount:    pendingAmount.Total().String(),
+		Principal: pendingAmount.Principal.String(),
+		Interest:  pendingAmount.Interest.String(),
+		Fee:       pendingAmount.Fee.String(),
+		LateFee:   pendingAmount.LateFee.String(),
+		Currency:  pendingAmount.Principal.Currency,
+	}, nil
+}
+
+func (s *loanService) PayLoan(
+	ctx context.Context,
+	loanID string,
+	payAmount Amount,
+	channel string,
+	externalReference string,
+	idempotencyKey string,
+) (*Payment, error) {
+	// a retried request gets the outcome of the original one
+	var idempotency *IdempotencyKey
+	if idempotencyKey != "" {
+		idempotency = &IdempotencyKey{
+			Key:         idempotencyKey,
+			RequestHash: paymentRequestHash(loanID, payAmount, channel, externalReference),
+		}
+
+		payment, err := s.replayPayment(ctx, idempotency)
+		if !errors.Is(err, ErrIdempotencyKeyNotFound) {
+			return payment, err
+		}
+	}
+
+	// the loan and its schedules stay locked from reading what is owed until the
+	// payment is applied, concurrent payments on the loan wait their turn
+	var payment *Payment
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		payment, err = s.payLoan(ctx, loanID, payAmount, channel, externalReference, idempotency)
+		return err
+	})
 	if err != nil {
-		s.logger.WarnContext(ctx, "failed to get pending loan", "error", err)
+		// a concurrent request with the same key got there first
+		if errors.Is(err, ErrIdempotencyKeyConflict) {
+			return s.replayPayment(ctx, idempotency)
+		}
 		return nil, err
 	}
 
-	return &PendingLoan{
-		ID:     loanID,
-		Amount: pendingAmount.String(),
-	}, nil
+	return payment, nil
 }
 
-func (s *loanService) PayLoan(ctx context.Context, loanID string, payAmount Amount) error {
-	loan, err := s.loanStore.GetLoanByID(ctx, loanID)
+func (s *loanService) payLoan(
+	ctx context.Context,
+	loanID string,
+	payAmount Amount,
+	channel string,
+	externalReference string,
+	idempotency *IdempotencyKey,
+) (*Payment, error) {
+	loan, err := s.loanStore.GetLoanByIDForUpdate(ctx, loanID)
 	if err != nil {
 		s.logger.WarnContext(ctx, "failed to get loan", "error", err)
+		return nil, err
+	}
+
+	if !loan.Status.IsPayable() {
+		s.logger.WarnContext(ctx, "loan is not payable", "status", loan.Status)
+		return nil, ErrLoanNotPayable
+	}
+
+	// payments must be made in the loan currency
+	if _, err := payAmount.EqualTo(loan.PrincipalAmount); err != nil {
+		s.logger.WarnContext(ctx, "payment currency mismatch", "payAmount", payAmount, "loanCurrency", loan.PrincipalAmount.Currency)
+		return nil, err
+	}
+
+	schedules, err := s.loanStore.GetUnsettledSchedules(ctx, loan.ID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to get unsettled schedules", "error", err)
+		return nil, err
+	}
+
+	allocations, unapplied := allocatePayment(schedules, payAmount)
+
+	payment := &Payment{
+		ID:                UUID(),
+		LoanID:            loan.ID,
+		Amount:            payAmount,
+		Channel:           channel,
+		ExternalReference: externalReference,
+		PaidAt:            CurrentLocalTime(),
+
+		Allocations:     allocations,
+		UnappliedAmount: unapplied,
+	}
+
+	if idempotency != nil {
+		idempotency.Payment = payment
+		idempotency.CreatedAt = payment.PaidAt
+	}
+
+	if err := s.paymentStore.CreatePayment(ctx, payment, idempotency); err != nil {
+		if !errors.Is(err, ErrIdempotencyKeyConflict) {
+			s.logger.WarnContext(ctx, "failed to create payment", "error", err)
+		}
+		return nil, err
+	}
+
+	settled := 0
+	for _, allocation := range allocations {
+		if allocation.ScheduleStatus == LoanScheduleStatusPaid {
+			settled++
+		}
+	}
+
+	if settled == len(schedules) {
+		if err := s.transitionLoanStatus(ctx, loan, LoanStatusPaidOff); err != nil {
+			return nil, err
+		}
+	} else if err := syncDelinquency(ctx, s.logger, s.loanStore, loan); err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+// replayPayment returns the payment stored for the idempotency key, refusing
+// requests that reuse the key with a different payload.
+func (s *loanService) replayPayment(
+	ctx context.Context,
+	idempotency *IdempotencyKey,
+) (*Payment, error) {
+	stored, err := s.paymentStore.GetIdempotencyKey(ctx, idempotency.Key)
+	if err != nil {
+		if !errors.Is(err, ErrIdempotencyKeyNotFound) {
+			s.logger.WarnContext(ctx, "failed to get idempotency key", "error", err)
+		}
+		return nil, err
+	}
+
+	if stored.RequestHash != idempotency.RequestHash {
+		s.logger.WarnContext(ctx, "idempotency key reused with a different request", "key", idempotency.Key)
+		return nil, ErrIdempotencyKeyMismatch
+	}
+
+	return stored.Payment, nil
+}
+
+// transitionLoanStatus moves the loan to the next status if the transition is allowed.
+func (s *loanService) transitionLoanStatus(
+	ctx context.Context,
+	loan *Loan,
+	next LoanStatus,
+) error {
+	if !loan.Status.CanTransitionTo(next) {
+		s.logger.WarnContext(ctx, "invalid loan status transition", "from", loan.Status, "to", next)
+		return ErrInvalidLoanStatusTransition
+	}
+
+	if err := s.loanStore.UpdateLoanStatus(ctx, loan.ID, loan.Status, next); err != nil {
+		s.logger.WarnContext(ctx, "failed to update loan status", "error", err)
 		return err
 	}
 
-	pendingAmount, err := s.loanStore.GetTotalPending(ctx, loan.ID)
+	loan.Status = next
+	return nil
+}
+
+// settleLoanIfPaidOff marks a payable loan as paid off once none of its
+// schedules is left unsettled, for changes that can settle a schedule without a
+// payment. A loan still owing has its delinquency brought up to date instead.
+func settleLoanIfPaidOff(
+	ctx context.Context,
+	logger Logger,
+	loanStore LoanStore,
+	loan *Loan,
+) error {
+	if !loan.Status.IsPayable() {
+		return nil
+	}
+
+	unsettled, err := loanStore.GetUnsettledSchedules(ctx, loan.ID)
 	if err != nil {
-		s.logger.WarnContext(ctx, "failed to get pending loan", "error", err)
+		logger.WarnContext(ctx, "failed to get unsettled schedules", "error", err)
+		return err
+	}
+
+	if len(unsettled) > 0 {
+		return syncDelinquency(ctx, logger, loanStore, loan)
+	}
+
+	if err := loanStore.UpdateLoanStatus(ctx, loan.ID, loan.Status, LoanStatusPaidOff); err != nil {
+		logger.WarnContext(ctx, "failed to mark loan as paid off", "error", err)
 		return err
 	}
 
-	isEqual, err := payAmount.EqualTo(*pendingAmount)
+	loan.Status = LoanStatusPaidOff
+	return nil
+}
+
+// syncDelinquency moves a payable loan between active and delinquent so its
+// status agrees with LoanStore.IsDelinquent.
+func syncDelinquency(
+	ctx context.Context,
+	logger Logger,
+	loanStore LoanStore,
+	loan *Loan,
+) error {
+	if !loan.Status.IsPayable() {
+		return nil
+	}
+
+	delinquent, err := loanStore.IsDelinquent(ctx, loan.ID)
 	if err != nil {
-		s.logger.WarnContext(ctx, "failed to compare amount", "error", err)
+		logger.WarnContext(ctx, "failed to check delinquency", "error", err)
 		return err
 	}
 
-	if !isEqual {
-		s.logger.WarnContext(ctx, "payment amount mismatch", "payAmount", payAmount, "pendingAmount", pendingAmount)
-		return ErrPaymentAmountMismatch
+	next := LoanStatusActive
+	if delinquent {
+		next = LoanStatusDelinquent
+	}
+	if next == loan.Status {
+		return nil
 	}
 
-	if err := s.loanStore.MarkPendingAsPaid(ctx, loan.ID); err != nil {
-		s.logger.WarnContext(ctx, "failed to mark loan as paid", "error", err)
+	if err := loanStore.UpdateLoanStatus(ctx, loan.ID, loan.Status, next); err != nil {
+		logger.WarnContext(ctx, "failed to update loan delinquency", "error", err)
 		return err
 	}
 
-	// TODO #1: implement create payment record to save payment history
-	// related with payment amount, payment date, 3rd party payment id, etc.
+	loan.Status = next
+	return nil
+}
 
-	// TODO #2: change loan status to paid if all pending payment has been paid.
+func (s *loanService) GetPayments(
+	ctx context.Context,
+	loanID string,
+) ([]Payment, error) {
+	var payments []Payment
+	err := s.txManager.WithinReadOnlyTx(ctx, func(ctx context.Context) error {
+		if err := s.ensureLoanExists(ctx, loanID); err != nil {
+			return err
+		}
+
+		var err error
+		payments, err = s.paymentStore.ListPaymentsByLoanID(ctx, loanID)
+		if err != nil {
+			s.logger.WarnContext(ctx, "failed to list payments", "error", err)
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
+	return payments, nil
+}
+
+func (s *loanService) GetSchedules(
+	ctx context.Context,
+	loanID string,
+	filter LoanScheduleFilter,
+) ([]LoanSchedule, error) {
+	var schedules []LoanSchedule
+	err := s.txManager.WithinReadOnlyTx(ctx, func(ctx context.Context) error {
+		if err := s.ensureLoanExists(ctx, loanID); err != nil {
+			return err
+		}
+
+		var err error
+		schedules, err = s.loanStore.ListSchedules(ctx, loanID, filter)
+		if err != nil {
+			s.logger.WarnContext(ctx, "failed to list schedules", "error", err)
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return schedules, nil
+}
+
+// ensureLoanExists fails with ErrLoanNotFound for an unknown loan.
+func (s *loanService) ensureLoanExists(ctx context.Context, loanID string) error {
+	if _, err := s.loanStore.GetLoanByID(ctx, loanID); err != nil {
+		s.logger.WarnContext(ctx, "failed to get loan", "error", err)
+		return err
+	}
 	return nil
 }
@@ -3,6 +3,7 @@ package billing_test
 import (
 	"context"
 	"errors"
+	"net/http"
 	"testing"
 
 	"github.com/golang/mock/gomock"
@@ -14,7 +15,11 @@ import (
 )
 
 var (
-	mockLoanStore *mock_billing.MockLoanStore
+	mockTxManager     *mock_billing.MockTxManager
+	mockBorrowerStore *mock_billing.MockBorrowerStore
+	mockProductStore  *mock_billing.MockLoanProductStore
+	mockLoanStore     *mock_billing.MockLoanStore
+	mockPaymentStore  *mock_billing.MockPaymentStore
 
 	loanService billing.LoanService
 	errMock     error = errors.New("mock error")
@@ -24,16 +29,45 @@ func provideLoanTest(t *testing.T) func() {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
+	mockTxManager = newMockTxManager(ctrl)
+	mockBorrowerStore = mock_billing.NewMockBorrowerStore(ctrl)
+	mockProductStore = mock_billing.NewMockLoanProductStore(ctrl)
 	mockLoanStore = mock_billing.NewMockLoanStore(ctrl)
+	mockPaymentStore = mock_billing.NewMockPaymentStore(ctrl)
 
 	loanService = billing.NewLoanService(
 		billing.NewLogger(),
+		billing.CreditPolicy{
+			MaxActiveLoans: 2,
+			MaxOutstandingPrincipal: map[string]billing.Amount{
+				billing.DefaultCurrency: billing.NewAmount(10_000_000),
+			},
+			BlockDelinquent: true,
+		},
+		14,
+		mockTxManager,
+		mockBorrowerStore,
+		mockProductStore,
 		mockLoanStore,
+		mockPaymentStore,
 	)
 
 	return func() {}
 }
 
+// newMockTxManager returns a TxManager that runs fn straight away, the
+// transaction is the store's concern and the service only needs fn to run.
+func newMockTxManager(ctrl *gomock.Controller) *mock_billing.MockTxManager {
+	runFn := func(ctx context.Context, fn func(ctx context.Context) error) error {
+		return fn(ctx)
+	}
+
+	txManager := mock_billing.NewMockTxManager(ctrl)
+	txManager.EXPECT().WithinTx(gomock.Any(), gomock.Any()).DoAndReturn(runFn).AnyTimes()
+	txManager.EXPECT().WithinReadOnlyTx(gomock.Any(), gomock.Any()).DoAndReturn(runFn).AnyTimes()
+	return txManager
+}
+
 func TestCreateLoan(t *testing.T) {
 	finish := provideLoanTest(t)
 	defer finish()
@@ -53,23 +87,62 @@ func TestCreateLoan(t *testing.T) {
 		var (
 			ctx              = context.Background()
 			borrowerID       = "borrower-id"
+			productID        = "product-id"
 			principalAmount  = billing.NewAmount(5_000_000)
 			interestRate     = 0.1
+			interestModel    = billing.InterestModelFlat
 			paymentFrequency = billing.LoanFrequencyWeekly
 			totalPayments    = 50
+
+			borrower = &billing.Borrower{
+				ID:        borrowerID,
+				KYCStatus: billing.KYCStatusVerified,
+			}
+
+			// an active product wide enough for every case, the case picks the
+			// interest model and the fee
+			newProduct = func(interestModel billing.InterestModel, feeAmount billing.Amount) *billing.LoanProduct {
+				return &billing.LoanProduct{
+					ID: productID,
+					LoanProductTerms: billing.LoanProductTerms{
+						MinPrincipal:    billing.NewAmount(1),
+						MaxPrincipal:    billing.NewAmount(100_000_000),
+						MinInterestRate: 0.01,
+						MaxInterestRate: 0.5,
+						InterestModel:   interestModel,
+						Frequencies:     []billing.LoanFrequency{billing.LoanFrequencyWeekly, billing.LoanFrequencyMonthly},
+						Terms:           []int{3, 12, 50},
+						FeeAmount:       feeAmount,
+					},
+					Status: billing.LoanProductStatusActive,
+				}
+			}
+
+			exposure = func(activeLoans, delinquentLoans int, principalOutstanding float64) *billing.BorrowerExposure {
+				return &billing.BorrowerExposure{
+					ActiveLoans:     activeLoans,
+					DelinquentLoans: delinquentLoans,
+					Currencies: []billing.CurrencyExposure{
+						{PrincipalOutstanding: billing.NewAmount(principalOutstanding)},
+					},
+				}
+			}
 		)
 
 		testCases := []struct {
-			testID   int
-			testDesc string
-			testType string
-			args     args
-			mock     func()
+			testID      int
+			testDesc    string
+			testType    string
+			product     *billing.LoanProduct
+			args        args
+			mock        func()
+			expectedErr error
 		}{
 			{
 				testID:   1,
 				testDesc: "success create loan",
 				testType: "P",
+				product:  newProduct(interestModel, billing.NewAmount(0)),
 				args: args{
 					ctx:              ctx,
 					borrowerID:       borrowerID,
@@ -79,6 +152,8 @@ func TestCreateLoan(t *testing.T) {
 					totalPayments:    totalPayments,
 				},
 				mock: func() {
+					mockBorrowerStore.EXPECT().GetBorrowerByIDForUpdate(ctx, borrowerID).Return(borrower, nil)
+					mockLoanStore.EXPECT().GetBorrowerExposure(ctx, borrowerID).Return(exposure(0, 0, 0), nil)
 					mockLoanStore.EXPECT().CreateLoan(ctx, gomock.Any()).
 						Do(func(ctx context.Context, loan *billing.Loan) {
 							So(loan.BorrowerID, ShouldEqual, borrowerID)
@@ -86,9 +161,18 @@ func TestCreateLoan(t *testing.T) {
 							So(loan.InterestRate, ShouldEqual, interestRate)
 							So(loan.PaymentFrequency, ShouldEqual, paymentFrequency)
 							So(loan.TotalPayments, ShouldEqual, totalPayments)
+							So(loan.Status, ShouldEqual, billing.LoanStatusPendingDisbursement)
+							So(loan.CreatedAt, ShouldEqual, loan.StartedAt)
+							So(loan.Disbursement, ShouldBeNil)
 
-							So(loan.LoanTermDays, ShouldEqual, 7*totalPayments)
-							So(loan.TermAmount, ShouldEqual, billing.NewAmount(110_000))
+							So(loan.InterestModel, ShouldEqual, interestModel)
+							So(loan.Schedules[0].AmountDue, ShouldEqual, billing.NewAmount(110_000))
+							So(loan.Schedules[0].PrincipalDue, ShouldEqual, billing.NewAmount(100_000))
+							So(loan.Schedules[0].InterestDue, ShouldEqual, billing.NewAmount(10_000))
+							So(loan.Schedules, ShouldHaveLength, totalPayments)
+							So(loan.Schedules[0].DueDate, ShouldEqual, loan.StartedAt.AddDate(0, 0, 7))
+							So(loan.Schedules[totalPayments-1].DueDate, ShouldEqual, loan.EndedAt)
+							So(loan.EndedAt, ShouldEqual, loan.StartedAt.AddDate(0, 0, 7*totalPayments))
 						}).Return(nil)
 				},
 			},
@@ -96,6 +180,7 @@ func TestCreateLoan(t *testing.T) {
 				testID:   2,
 				testDesc: "failed create loan",
 				testType: "N",
+				product:  newProduct(interestModel, billing.NewAmount(0)),
 				args: args{
 					ctx:              ctx,
 					borrowerID:       borrowerID,
@@ -105,28 +190,360 @@ func TestCreateLoan(t *testing.T) {
 					totalPayments:    totalPayments,
 				},
 				mock: func() {
+					mockBorrowerStore.EXPECT().GetBorrowerByIDForUpdate(ctx, borrowerID).Return(borrower, nil)
+					mockLoanStore.EXPECT().GetBorrowerExposure(ctx, borrowerID).Return(exposure(0, 0, 0), nil)
 					mockLoanStore.EXPECT().CreateLoan(ctx, gomock.Any()).Return(errMock)
 				},
 			},
+			{
+				testID:   3,
+				testDesc: "success create loan: rounding remainder is spread over installments",
+				testType: "P",
+				product:  newProduct(interestModel, billing.NewAmount(0)),
+				args: args{
+					ctx:              ctx,
+					borrowerID:       borrowerID,
+					principalAmount:  billing.NewAmount(1_000),
+					interestRate:     interestRate,
+					paymentFrequency: paymentFrequency,
+					totalPayments:    3,
+				},
+				mock: func() {
+					mockBorrowerStore.EXPECT().GetBorrowerByIDForUpdate(ctx, borrowerID).Return(borrower, nil)
+					mockLoanStore.EXPECT().GetBorrowerExposure(ctx, borrowerID).Return(exposure(0, 0, 0), nil)
+					mockLoanStore.EXPECT().CreateLoan(ctx, gomock.Any()).
+						Do(func(ctx context.Context, loan *billing.Loan) {
+							So(loan.Schedules, ShouldHaveLength, 3)
+							So(loan.Schedules[0].AmountDue, ShouldEqual, billing.NewAmount(367))
+							So(loan.Schedules[1].AmountDue, ShouldEqual, billing.NewAmount(367))
+							So(loan.Schedules[2].AmountDue, ShouldEqual, billing.NewAmount(366))
+
+							principal := billing.NewAmount(0)
+							for _, schedule := range loan.Schedules {
+								principal = principal.Add(schedule.PrincipalDue)
+							}
+							So(principal, ShouldEqual, billing.NewAmount(1_000))
+						}).Return(nil)
+				},
+			},
+			{
+				testID:   4,
+				testDesc: "success create loan: annuity with amortizing interest",
+				testType: "P",
+				product:  newProduct(billing.InterestModelAnnuity, billing.NewAmount(0)),
+				args: args{
+					ctx:              ctx,
+					borrowerID:       borrowerID,
+					principalAmount:  billing.NewAmount(1_200_000),
+					interestRate:     0.12,
+					paymentFrequency: billing.LoanFrequencyMonthly,
+					totalPayments:    12,
+				},
+				mock: func() {
+					mockBorrowerStore.EXPECT().GetBorrowerByIDForUpdate(ctx, borrowerID).Return(borrower, nil)
+					mockLoanStore.EXPECT().GetBorrowerExposure(ctx, borrowerID).Return(exposure(0, 0, 0), nil)
+					mockLoanStore.EXPECT().CreateLoan(ctx, gomock.Any()).
+						Do(func(ctx context.Context, loan *billing.Loan) {
+							So(loan.Schedules, ShouldHaveLength, 12)
+
+							// 1% monthly on 1.2M gives a 106,619 installment
+							So(loan.Schedules[0].AmountDue, ShouldEqual, billing.NewAmount(106_619))
+							So(loan.Schedules[0].InterestDue, ShouldEqual, billing.NewAmount(12_000))
+							So(loan.Schedules[0].PrincipalDue, ShouldEqual, billing.NewAmount(94_619))
+							So(loan.Schedules[1].AmountDue, ShouldEqual, billing.NewAmount(106_619))
+							So(loan.Schedules[1].InterestDue, ShouldEqual, billing.NewAmount(11_054))
+
+							principal := billing.NewAmount(0)
+							for _, schedule := range loan.Schedules {
+								principal = principal.Add(schedule.PrincipalDue)
+							}
+							So(principal, ShouldEqual, billing.NewAmount(1_200_000))
+						}).Return(nil)
+				},
+			},
+			{
+				testID:   5,
+				testDesc: "success create loan: declining balance",
+				testType: "P",
+				product:  newProduct(billing.InterestModelDecliningBalance, billing.NewAmount(0)),
+				args: args{
+					ctx:              ctx,
+					borrowerID:       borrowerID,
+					principalAmount:  billing.NewAmount(1_200_000),
+					interestRate:     0.12,
+					paymentFrequency: billing.LoanFrequencyMonthly,
+					totalPayments:    12,
+				},
+				mock: func() {
+					mockBorrowerStore.EXPECT().GetBorrowerByIDForUpdate(ctx, borrowerID).Return(borrower, nil)
+					mockLoanStore.EXPECT().GetBorrowerExposure(ctx, borrowerID).Return(exposure(0, 0, 0), nil)
+					mockLoanStore.EXPECT().CreateLoan(ctx, gomock.Any()).
+						Do(func(ctx context.Context, loan *billing.Loan) {
+							So(loan.Schedules, ShouldHaveLength, 12)
+							So(loan.Schedules[0].PrincipalDue, ShouldEqual, billing.NewAmount(100_000))
+							So(loan.Schedules[0].InterestDue, ShouldEqual, billing.NewAmount(12_000))
+							So(loan.Schedules[1].InterestDue, ShouldEqual, billing.NewAmount(11_000))
+							So(loan.Schedules[11].InterestDue, ShouldEqual, billing.NewAmount(1_000))
+							So(loan.Schedules[11].AmountDue, ShouldEqual, billing.NewAmount(101_000))
+						}).Return(nil)
+				},
+			},
+			{
+				testID:   6,
+				testDesc: "failed: interest rate outside the product range",
+				testType: "N",
+				product:  newProduct(interestModel, billing.NewAmount(0)),
+				args: args{
+					ctx:              ctx,
+					borrowerID:       borrowerID,
+					principalAmount:  principalAmount,
+					interestRate:     0.6,
+					paymentFrequency: paymentFrequency,
+					totalPayments:    totalPayments,
+				},
+				mock: func() {},
+			},
+			{
+				testID:   7,
+				testDesc: "success create loan: fee is spread over installments",
+				testType: "P",
+				product:  newProduct(interestModel, billing.NewAmount(100)),
+				args: args{
+					ctx:              ctx,
+					borrowerID:       borrowerID,
+					principalAmount:  billing.NewAmount(1_000),
+					interestRate:     interestRate,
+					paymentFrequency: paymentFrequency,
+					totalPayments:    3,
+				},
+				mock: func() {
+					mockBorrowerStore.EXPECT().GetBorrowerByIDForUpdate(ctx, borrowerID).Return(borrower, nil)
+					mockLoanStore.EXPECT().GetBorrowerExposure(ctx, borrowerID).Return(exposure(0, 0, 0), nil)
+					mockLoanStore.EXPECT().CreateLoan(ctx, gomock.Any()).
+						Do(func(ctx context.Context, loan *billing.Loan) {
+							So(loan.FeeAmount, ShouldEqual, billing.NewAmount(100))
+							So(loan.Schedules[0].FeeDue, ShouldEqual, billing.NewAmount(34))
+							So(loan.Schedules[0].AmountDue, ShouldEqual, billing.NewAmount(401))
+							So(loan.Schedules[2].FeeDue, ShouldEqual, billing.NewAmount(33))
+							So(loan.Schedules[2].AmountDue, ShouldEqual, billing.NewAmount(399))
+
+							for _, schedule := range loan.Schedules {
+								So(
+									schedule.PrincipalDue.Add(schedule.InterestDue).Add(schedule.FeeDue),
+									ShouldEqual,
+									schedule.AmountDue,
+								)
+							}
+						}).Return(nil)
+				},
+			},
+			{
+				testID:   8,
+				testDesc: "failed: unknown borrower",
+				testType: "N",
+				product:  newProduct(interestModel, billing.NewAmount(0)),
+				args: args{
+					ctx:              ctx,
+					borrowerID:       borrowerID,
+					principalAmount:  principalAmount,
+					interestRate:     interestRate,
+					paymentFrequency: paymentFrequency,
+					totalPayments:    totalPayments,
+				},
+				mock: func() {
+					mockBorrowerStore.EXPECT().GetBorrowerByIDForUpdate(ctx, borrowerID).Return(nil, billing.ErrBorrowerNotFound)
+				},
+				expectedErr: billing.ErrBorrowerNotFound,
+			},
+			{
+				testID:   9,
+				testDesc: "failed: borrower has not passed kyc",
+				testType: "N",
+				product:  newProduct(interestModel, billing.NewAmount(0)),
+				args: args{
+					ctx:              ctx,
+					borrowerID:       borrowerID,
+					principalAmount:  principalAmount,
+					interestRate:     interestRate,
+					paymentFrequency: paymentFrequency,
+					totalPayments:    totalPayments,
+				},
+				mock: func() {
+					mockBorrowerStore.EXPECT().GetBorrowerByIDForUpdate(ctx, borrowerID).Return(&billing.Borrower{
+						ID:        borrowerID,
+						KYCStatus: billing.KYCStatusPending,
+					}, nil)
+				},
+				expectedErr: billing.ErrBorrowerNotVerified,
+			},
+			{
+				testID:   10,
+				testDesc: "failed: borrower has a delinquent loan",
+				testType: "N",
+				product:  newProduct(interestModel, billing.NewAmount(0)),
+				args: args{
+					ctx:              ctx,
+					borrowerID:       borrowerID,
+					principalAmount:  principalAmount,
+					interestRate:     interestRate,
+					paymentFrequency: paymentFrequency,
+					totalPayments:    totalPayments,
+				},
+				mock: func() {
+					mockBorrowerStore.EXPECT().GetBorrowerByIDForUpdate(ctx, borrowerID).Return(borrower, nil)
+					mockLoanStore.EXPECT().GetBorrowerExposure(ctx, borrowerID).Return(exposure(1, 1, 1_000_000), nil)
+				},
+				expectedErr: billing.ErrBorrowerDelinquent,
+			},
+			{
+				testID:   11,
+				testDesc: "failed: borrower reached the active loan limit",
+				testType: "N",
+				product:  newProduct(interestModel, billing.NewAmount(0)),
+				args: args{
+					ctx:              ctx,
+					borrowerID:       borrowerID,
+					principalAmount:  principalAmount,
+					interestRate:     interestRate,
+					paymentFrequency: paymentFrequency,
+					totalPayments:    totalPayments,
+				},
+				mock: func() {
+					mockBorrowerStore.EXPECT().GetBorrowerByIDForUpdate(ctx, borrowerID).Return(borrower, nil)
+					mockLoanStore.EXPECT().GetBorrowerExposure(ctx, borrowerID).Return(exposure(2, 0, 2_000_000), nil)
+				},
+				expectedErr: billing.ErrActiveLoanLimitExceeded,
+			},
+			{
+				testID:   12,
+				testDesc: "failed: loan takes the borrower over the outstanding principal limit",
+				testType: "N",
+				product:  newProduct(interestModel, billing.NewAmount(0)),
+				args: args{
+					ctx:              ctx,
+					borrowerID:       borrowerID,
+					principalAmount:  principalAmount,
+					interestRate:     interestRate,
+					paymentFrequency: paymentFrequency,
+					totalPayments:    totalPayments,
+				},
+				mock: func() {
+					mockBorrowerStore.EXPECT().GetBorrowerByIDForUpdate(ctx, borrowerID).Return(borrower, nil)
+					mockLoanStore.EXPECT().GetBorrowerExposure(ctx, borrowerID).Return(exposure(1, 0, 5_000_001), nil)
+				},
+				expectedErr: billing.ErrOutstandingLimitExceeded,
+			},
+			{
+				testID:   13,
+				testDesc: "success create loan up to the outstanding principal limit",
+				testType: "P",
+				product:  newProduct(interestModel, billing.NewAmount(0)),
+				args: args{
+					ctx:              ctx,
+					borrowerID:       borrowerID,
+					principalAmount:  principalAmount,
+					interestRate:     interestRate,
+					paymentFrequency: paymentFrequency,
+					totalPayments:    totalPayments,
+				},
+				mock: func() {
+					mockBorrowerStore.EXPECT().GetBorrowerByIDForUpdate(ctx, borrowerID).Return(borrower, nil)
+					mockLoanStore.EXPECT().GetBorrowerExposure(ctx, borrowerID).Return(exposure(1, 0, 5_000_000), nil)
+					mockLoanStore.EXPECT().CreateLoan(ctx, gomock.Any()).Return(nil)
+				},
+			},
+			{
+				testID:   14,
+				testDesc: "failed: retired product",
+				testType: "N",
+				product: func() *billing.LoanProduct {
+					product := newProduct(interestModel, billing.NewAmount(0))
+					product.Status = billing.LoanProductStatusRetired
+					return product
+				}(),
+				args: args{
+					ctx:              ctx,
+					borrowerID:       borrowerID,
+					principalAmount:  principalAmount,
+					interestRate:     interestRate,
+					paymentFrequency: paymentFrequency,
+					totalPayments:    totalPayments,
+				},
+				mock:        func() {},
+				expectedErr: billing.ErrLoanProductRetired,
+			},
+			{
+				testID:   15,
+				testDesc: "success create loan: terms left out are taken from a single option product",
+				testType: "P",
+				product: func() *billing.LoanProduct {
+					product := newProduct(interestModel, billing.NewAmount(0))
+					product.MinInterestRate = 0.2
+					product.MaxInterestRate = 0.2
+					product.Frequencies = []billing.LoanFrequency{billing.LoanFrequencyMonthly}
+					product.Terms = []int{12}
+					product.GraceDays = 3
+					return product
+				}(),
+				args: args{
+					ctx:             ctx,
+					borrowerID:      borrowerID,
+					principalAmount: principalAmount,
+				},
+				mock: func() {
+					mockBorrowerStore.EXPECT().GetBorrowerByIDForUpdate(ctx, borrowerID).Return(borrower, nil)
+					mockLoanStore.EXPECT().GetBorrowerExposure(ctx, borrowerID).Return(exposure(0, 0, 0), nil)
+					mockLoanStore.EXPECT().CreateLoan(ctx, gomock.Any()).
+						Do(func(ctx context.Context, loan *billing.Loan) {
+							So(*loan.ProductID, ShouldEqual, productID)
+							So(*loan.GraceDays, ShouldEqual, 3)
+							So(loan.InterestRate, ShouldEqual, 0.2)
+							So(loan.PaymentFrequency, ShouldEqual, billing.LoanFrequencyMonthly)
+							So(loan.TotalPayments, ShouldEqual, 12)
+							So(loan.Schedules, ShouldHaveLength, 12)
+						}).Return(nil)
+				},
+			},
+			{
+				testID:   16,
+				testDesc: "failed: frequency left out of a product offering several",
+				testType: "N",
+				product:  newProduct(interestModel, billing.NewAmount(0)),
+				args: args{
+					ctx:             ctx,
+					borrowerID:      borrowerID,
+					principalAmount: principalAmount,
+					interestRate:    interestRate,
+					totalPayments:   totalPayments,
+				},
+				mock: func() {},
+			},
 		}
 
 		for _, tc := range testCases {
 			t.Logf("%d - [%s] : %s", tc.testID, tc.testType, tc.testDesc)
+			mockProductStore.EXPECT().GetProductByID(ctx, productID).Return(tc.product, nil)
 			tc.mock()
 
 			_, err := loanService.CreateLoan(
 				tc.args.ctx,
 				tc.args.borrowerID,
-				tc.args.principalAmount,
-				tc.args.interestRate,
-				tc.args.paymentFrequency,
-				tc.args.totalPayments,
+				productID,
+				billing.LoanApplication{
+					PrincipalAmount:  tc.args.principalAmount,
+					InterestRate:     tc.args.interestRate,
+					PaymentFrequency: tc.args.paymentFrequency,
+					TotalPayments:    tc.args.totalPayments,
+				},
 			)
 
 			if tc.testType == "P" {
 				So(err, ShouldBeNil)
 			} else {
 				So(err, ShouldNotBeNil)
+				if tc.expectedErr != nil {
+					So(err, ShouldEqual, tc.expectedErr)
+				}
 			}
 		}
 	})
@@ -139,22 +556,48 @@ func TestPayLoan(t *testing.T) {
 	Convey("PayLoan", t, FailureHalts, func() {
 		type (
 			args struct {
-				ctx       context.Context
-				loanID    string
-				payAmount billing.Amount
+				ctx               context.Context
+				loanID            string
+				payAmount         billing.Amount
+				channel           string
+				externalReference string
+				idempotencyKey    string
 			}
 		)
 
 		var (
-			ctx       = context.Background()
-			loanID    = "loan-id"
-			payAmount = billing.NewAmount(100)
+			ctx               = context.Background()
+			loanID            = "loan-id"
+			channel           = "bank_transfer"
+			externalReference = "ref-001"
+			idempotencyKey    = "idempotency-key"
+
+			// idempotency key stored by the first request that used it
+			storedKey *billing.IdempotencyKey
 
-			oneHundredAmount  = billing.NewAmount(100)
-			oneThousandAmount = billing.NewAmount(1_000)
+			loanWithStatus = func(status billing.LoanStatus) *billing.Loan {
+				return &billing.Loan{
+					ID:              loanID,
+					PrincipalAmount: billing.NewAmount(1_000),
+					Status:          status,
+				}
+			}
 
-			loan = billing.Loan{
-				ID: loanID,
+			schedules = []billing.LoanSchedule{
+				{
+					ID:         "schedule-1",
+					Seq:        1,
+					AmountDue:  billing.NewAmount(100),
+					PaidAmount: billing.NewAmount(40),
+					Status:     billing.LoanScheduleStatusPartiallyPaid,
+				},
+				{
+					ID:         "schedule-2",
+					Seq:        2,
+					AmountDue:  billing.NewAmount(100),
+					PaidAmount: billing.NewAmount(0),
+					Status:     billing.LoanScheduleStatusUnpaid,
+				},
 			}
 		)
 
@@ -168,89 +611,260 @@ func TestPayLoan(t *testing.T) {
 		}{
 			{
 				testID:   1,
-				testDesc: "success pay loan",
+				testDesc: "success pay loan: partial payment settles oldest schedule first",
 				testType: "P",
 				args: args{
-					ctx:       ctx,
-					loanID:    loanID,
-					payAmount: payAmount,
+					ctx:               ctx,
+					loanID:            loanID,
+					payAmount:         billing.NewAmount(100),
+					channel:           channel,
+					externalReference: externalReference,
 				},
 				mock: func() {
-					mockLoanStore.EXPECT().GetLoanByID(ctx, loanID).Return(&loan, nil)
-					mockLoanStore.EXPECT().GetTotalPending(ctx, loanID).Return(&oneHundredAmount, nil)
-					mockLoanStore.EXPECT().MarkPendingAsPaid(ctx, loanID).Return(nil)
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(loanWithStatus(billing.LoanStatusActive), nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loanID).Return(schedules, nil)
+					mockPaymentStore.EXPECT().CreatePayment(ctx, gomock.Any(), nil).
+						Do(func(ctx context.Context, payment *billing.Payment, _ *billing.IdempotencyKey) {
+							So(payment.LoanID, ShouldEqual, loanID)
+							So(payment.Amount, ShouldEqual, billing.NewAmount(100))
+							So(payment.Channel, ShouldEqual, channel)
+							So(payment.ExternalReference, ShouldEqual, externalReference)
+
+							So(payment.Allocations, ShouldHaveLength, 2)
+							So(payment.Allocations[0].ScheduleID, ShouldEqual, "schedule-1")
+							So(payment.Allocations[0].Amount, ShouldEqual, billing.NewAmount(60))
+							So(payment.Allocations[0].ScheduleStatus, ShouldEqual, billing.LoanScheduleStatusPaid)
+							So(payment.Allocations[1].ScheduleID, ShouldEqual, "schedule-2")
+							So(payment.Allocations[1].Amount, ShouldEqual, billing.NewAmount(40))
+							So(payment.Allocations[1].SchedulePaidAmount, ShouldEqual, billing.NewAmount(40))
+							So(payment.Allocations[1].ScheduleStatus, ShouldEqual, billing.LoanScheduleStatusPartiallyPaid)
+							So(payment.UnappliedAmount.IsZero(), ShouldBeTrue)
+						}).Return(nil)
+					mockLoanStore.EXPECT().IsDelinquent(ctx, loanID).Return(false, nil)
 				},
 			},
 			{
 				testID:   2,
-				testDesc: "failed: loan not found",
-				testType: "N",
+				testDesc: "success pay loan: overpayment settles the loan and is held as credit",
+				testType: "P",
 				args: args{
-					ctx:       ctx,
-					loanID:    loanID,
-					payAmount: payAmount,
+					ctx:               ctx,
+					loanID:            loanID,
+					payAmount:         billing.NewAmount(200),
+					channel:           channel,
+					externalReference: externalReference,
 				},
 				mock: func() {
-					mockLoanStore.EXPECT().GetLoanByID(ctx, loanID).Return(nil, billing.ErrLoanNotFound)
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(loanWithStatus(billing.LoanStatusDelinquent), nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loanID).Return(schedules, nil)
+					mockPaymentStore.EXPECT().CreatePayment(ctx, gomock.Any(), nil).
+						Do(func(ctx context.Context, payment *billing.Payment, _ *billing.IdempotencyKey) {
+							So(payment.Allocations, ShouldHaveLength, 2)
+							So(payment.UnappliedAmount, ShouldEqual, billing.NewAmount(40))
+						}).Return(nil)
+					mockLoanStore.EXPECT().UpdateLoanStatus(ctx, loanID, billing.LoanStatusDelinquent, billing.LoanStatusPaidOff).Return(nil)
 				},
-				expectedErr: billing.ErrLoanNotFound,
 			},
 			{
 				testID:   3,
-				testDesc: "failed: get total pending",
+				testDesc: "failed: loan not found",
 				testType: "N",
 				args: args{
-					ctx:       ctx,
-					loanID:    loanID,
-					payAmount: payAmount,
+					ctx:               ctx,
+					loanID:            loanID,
+					payAmount:         billing.NewAmount(100),
+					channel:           channel,
+					externalReference: externalReference,
 				},
 				mock: func() {
-					mockLoanStore.EXPECT().GetLoanByID(ctx, loanID).Return(&loan, nil)
-					mockLoanStore.EXPECT().GetTotalPending(ctx, loanID).Return(nil, errMock)
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(nil, billing.ErrLoanNotFound)
 				},
-				expectedErr: errMock,
+				expectedErr: billing.ErrLoanNotFound,
 			},
 			{
 				testID:   4,
-				testDesc: "failed: payment amount mismatch",
+				testDesc: "failed: loan is closed",
 				testType: "N",
 				args: args{
-					ctx:       ctx,
-					loanID:    loanID,
-					payAmount: payAmount,
+					ctx:               ctx,
+					loanID:            loanID,
+					payAmount:         billing.NewAmount(100),
+					channel:           channel,
+					externalReference: externalReference,
 				},
 				mock: func() {
-					mockLoanStore.EXPECT().GetLoanByID(ctx, loanID).Return(&loan, nil)
-					mockLoanStore.EXPECT().GetTotalPending(ctx, loanID).Return(&oneThousandAmount, nil)
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(loanWithStatus(billing.LoanStatusPaidOff), nil)
 				},
-				expectedErr: billing.ErrPaymentAmountMismatch,
+				expectedErr: billing.ErrLoanNotPayable,
 			},
 			{
 				testID:   5,
-				testDesc: "failed: mark pending as paid",
+				testDesc: "failed: payment currency differs from loan currency",
 				testType: "N",
 				args: args{
-					ctx:       ctx,
-					loanID:    loanID,
-					payAmount: payAmount,
+					ctx:               ctx,
+					loanID:            loanID,
+					payAmount:         billing.NewAmountIn(100, billing.CurrencyUSD),
+					channel:           channel,
+					externalReference: externalReference,
 				},
 				mock: func() {
-					mockLoanStore.EXPECT().GetLoanByID(ctx, loanID).Return(&loan, nil)
-					mockLoanStore.EXPECT().GetTotalPending(ctx, loanID).Return(&oneHundredAmount, nil)
-					mockLoanStore.EXPECT().MarkPendingAsPaid(ctx, loanID).Return(errMock)
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(loanWithStatus(billing.LoanStatusActive), nil)
+				},
+				expectedErr: billing.NewError(
+					billing.ErrCurrencyMismatch.Error(),
+					"EqualTo error: amounts needs to have same currency",
+					http.StatusUnprocessableEntity,
+				),
+			},
+			{
+				testID:   6,
+				testDesc: "failed: get unsettled schedules",
+				testType: "N",
+				args: args{
+					ctx:               ctx,
+					loanID:            loanID,
+					payAmount:         billing.NewAmount(100),
+					channel:           channel,
+					externalReference: externalReference,
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(loanWithStatus(billing.LoanStatusActive), nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loanID).Return(nil, errMock)
 				},
 				expectedErr: errMock,
 			},
+			{
+				testID:   7,
+				testDesc: "failed: create payment",
+				testType: "N",
+				args: args{
+					ctx:               ctx,
+					loanID:            loanID,
+					payAmount:         billing.NewAmount(100),
+					channel:           channel,
+					externalReference: externalReference,
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(loanWithStatus(billing.LoanStatusActive), nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loanID).Return(schedules, nil)
+					mockPaymentStore.EXPECT().CreatePayment(ctx, gomock.Any(), nil).Return(errMock)
+				},
+				expectedErr: errMock,
+			},
+			{
+				testID:   8,
+				testDesc: "success pay loan: idempotency key is stored with the payment",
+				testType: "P",
+				args: args{
+					ctx:               ctx,
+					loanID:            loanID,
+					payAmount:         billing.NewAmount(100),
+					channel:           channel,
+					externalReference: externalReference,
+					idempotencyKey:    idempotencyKey,
+				},
+				mock: func() {
+					mockPaymentStore.EXPECT().GetIdempotencyKey(ctx, idempotencyKey).Return(nil, billing.ErrIdempotencyKeyNotFound)
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(loanWithStatus(billing.LoanStatusActive), nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loanID).Return(schedules, nil)
+					mockPaymentStore.EXPECT().CreatePayment(ctx, gomock.Any(), gomock.Any()).
+						Do(func(ctx context.Context, payment *billing.Payment, key *billing.IdempotencyKey) {
+							So(key.Key, ShouldEqual, idempotencyKey)
+							So(key.RequestHash, ShouldNotBeEmpty)
+							So(key.Payment, ShouldEqual, payment)
+							storedKey = key
+						}).Return(nil)
+					mockLoanStore.EXPECT().IsDelinquent(ctx, loanID).Return(false, nil)
+				},
+			},
+			{
+				testID:   9,
+				testDesc: "success pay loan: retry with the same idempotency key replays the original payment",
+				testType: "P",
+				args: args{
+					ctx:               ctx,
+					loanID:            loanID,
+					payAmount:         billing.NewAmount(100),
+					channel:           channel,
+					externalReference: externalReference,
+					idempotencyKey:    idempotencyKey,
+				},
+				mock: func() {
+					mockPaymentStore.EXPECT().GetIdempotencyKey(ctx, idempotencyKey).Return(storedKey, nil)
+				},
+			},
+			{
+				testID:   10,
+				testDesc: "success pay loan: concurrent request with the same idempotency key replays its payment",
+				testType: "P",
+				args: args{
+					ctx:               ctx,
+					loanID:            loanID,
+					payAmount:         billing.NewAmount(100),
+					channel:           channel,
+					externalReference: externalReference,
+					idempotencyKey:    idempotencyKey,
+				},
+				mock: func() {
+					gomock.InOrder(
+						mockPaymentStore.EXPECT().GetIdempotencyKey(ctx, idempotencyKey).Return(nil, billing.ErrIdempotencyKeyNotFound),
+						mockPaymentStore.EXPECT().GetIdempotencyKey(ctx, idempotencyKey).Return(storedKey, nil),
+					)
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(loanWithStatus(billing.LoanStatusActive), nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loanID).Return(schedules, nil)
+					mockPaymentStore.EXPECT().CreatePayment(ctx, gomock.Any(), gomock.Any()).Return(billing.ErrIdempotencyKeyConflict)
+				},
+			},
+			{
+				testID:   11,
+				testDesc: "failed: idempotency key reused with a different payload",
+				testType: "N",
+				args: args{
+					ctx:               ctx,
+					loanID:            loanID,
+					payAmount:         billing.NewAmount(150),
+					channel:           channel,
+					externalReference: externalReference,
+					idempotencyKey:    idempotencyKey,
+				},
+				mock: func() {
+					mockPaymentStore.EXPECT().GetIdempotencyKey(ctx, idempotencyKey).Return(storedKey, nil)
+				},
+				expectedErr: billing.ErrIdempotencyKeyMismatch,
+			},
+			{
+				testID:   12,
+				testDesc: "success pay loan: clearing the arrears of a delinquent loan makes it active again",
+				testType: "P",
+				args: args{
+					ctx:               ctx,
+					loanID:            loanID,
+					payAmount:         billing.NewAmount(100),
+					channel:           channel,
+					externalReference: externalReference,
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(loanWithStatus(billing.LoanStatusDelinquent), nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loanID).Return(schedules, nil)
+					mockPaymentStore.EXPECT().CreatePayment(ctx, gomock.Any(), nil).Return(nil)
+					mockLoanStore.EXPECT().IsDelinquent(ctx, loanID).Return(false, nil)
+					mockLoanStore.EXPECT().UpdateLoanStatus(ctx, loanID, billing.LoanStatusDelinquent, billing.LoanStatusActive).Return(nil)
+				},
+			},
 		}
 
 		for _, tc := range testCases {
 			t.Logf("%d - [%s] : %s", tc.testID, tc.testType, tc.testDesc)
 			tc.mock()
 
-			err := loanService.PayLoan(
+			_, err := loanService.PayLoan(
 				tc.args.ctx,
 				tc.args.loanID,
 				tc.args.payAmount,
+				tc.args.channel,
+				tc.args.externalReference,
+				tc.args.idempotencyKey,
 			)
 
 			if tc.testType == "P" {
@@ -262,3 +876,19 @@ func TestPayLoan(t *testing.T) {
 		}
 	})
 }
+
+func TestLoanStatusTransition(t *testing.T) {
+	Convey("LoanStatus.CanTransitionTo", t, func() {
+		So(billing.LoanStatusPendingDisbursement.CanTransitionTo(billing.LoanStatusActive), ShouldBeTrue)
+		So(billing.LoanStatusActive.CanTransitionTo(billing.LoanStatusDelinquent), ShouldBeTrue)
+		So(billing.LoanStatusDelinquent.CanTransitionTo(billing.LoanStatusActive), ShouldBeTrue)
+		So(billing.LoanStatusDelinquent.CanTransitionTo(billing.LoanStatusPaidOff), ShouldBeTrue)
+
+		So(billing.LoanStatusPendingDisbursement.CanTransitionTo(billing.LoanStatusPaidOff), ShouldBeFalse)
+		So(billing.LoanStatusPaidOff.CanTransitionTo(billing.LoanStatusActive), ShouldBeFalse)
+		So(billing.LoanStatusCancelled.CanTransitionTo(billing.LoanStatusActive), ShouldBeFalse)
+
+		So(billing.LoanStatusWrittenOff.IsClosed(), ShouldBeTrue)
+		So(billing.LoanStatusActive.IsClosed(), ShouldBeFalse)
+	})
+}
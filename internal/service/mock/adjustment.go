@@ -0,0 +1,191 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/service/adjustment.go
+
+// Package mock_billing is a generated GoMock package.
+package mock_billing
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	service "github.com/theyudiriski/billing-service/internal/service"
+)
+
+// MockAdjustmentService is a mock of AdjustmentService interface.
+type MockAdjustmentService struct {
+	ctrl     *gomock.Controller
+	recorder *MockAdjustmentServiceMockRecorder
+}
+
+// MockAdjustmentServiceMockRecorder is the mock recorder for MockAdjustmentService.
+type MockAdjustmentServiceMockRecorder struct {
+	mock *MockAdjustmentService
+}
+
+// NewMockAdjustmentService creates a new mock instance.
+func NewMockAdjustmentService(ctrl *gomock.Controller) *MockAdjustmentService {
+	mock := &MockAdjustmentService{ctrl: ctrl}
+	mock.recorder = &MockAdjustmentServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAdjustmentService) EXPECT() *MockAdjustmentServiceMockRecorder {
+	return m.recorder
+}
+
+// ApproveAdjustment mocks base method.
+func (m *MockAdjustmentService) ApproveAdjustment(ctx context.Context, loanID, adjustmentID, operatorID string) (*service.Adjustment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApproveAdjustment", ctx, loanID, adjustmentID, operatorID)
+	ret0, _ := ret[0].(*service.Adjustment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApproveAdjustment indicates an expected call of ApproveAdjustment.
+func (mr *MockAdjustmentServiceMockRecorder) ApproveAdjustment(ctx, loanID, adjustmentID, operatorID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApproveAdjustment", reflect.TypeOf((*MockAdjustmentService)(nil).ApproveAdjustment), ctx, loanID, adjustmentID, operatorID)
+}
+
+// CreateAdjustment mocks base method.
+func (m *MockAdjustmentService) CreateAdjustment(ctx context.Context, loanID, scheduleID string, adjustmentType service.AdjustmentType, amount service.Amount, reasonCode service.AdjustmentReasonCode, operatorID string) (*service.Adjustment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAdjustment", ctx, loanID, scheduleID, adjustmentType, amount, reasonCode, operatorID)
+	ret0, _ := ret[0].(*service.Adjustment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAdjustment indicates an expected call of CreateAdjustment.
+func (mr *MockAdjustmentServiceMockRecorder) CreateAdjustment(ctx, loanID, scheduleID, adjustmentType, amount, reasonCode, operatorID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAdjustment", reflect.TypeOf((*MockAdjustmentService)(nil).CreateAdjustment), ctx, loanID, scheduleID, adjustmentType, amount, reasonCode, operatorID)
+}
+
+// GetAdjustments mocks base method.
+func (m *MockAdjustmentService) GetAdjustments(ctx context.Context, loanID string) ([]service.Adjustment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAdjustments", ctx, loanID)
+	ret0, _ := ret[0].([]service.Adjustment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAdjustments indicates an expected call of GetAdjustments.
+func (mr *MockAdjustmentServiceMockRecorder) GetAdjustments(ctx, loanID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAdjustments", reflect.TypeOf((*MockAdjustmentService)(nil).GetAdjustments), ctx, loanID)
+}
+
+// RejectAdjustment mocks base method.
+func (m *MockAdjustmentService) RejectAdjustment(ctx context.Context, loanID, adjustmentID, operatorID string) (*service.Adjustment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RejectAdjustment", ctx, loanID, adjustmentID, operatorID)
+	ret0, _ := ret[0].(*service.Adjustment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RejectAdjustment indicates an expected call of RejectAdjustment.
+func (mr *MockAdjustmentServiceMockRecorder) RejectAdjustment(ctx, loanID, adjustmentID, operatorID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RejectAdjustment", reflect.TypeOf((*MockAdjustmentService)(nil).RejectAdjustment), ctx, loanID, adjustmentID, operatorID)
+}
+
+// ReverseAdjustment mocks base method.
+func (m *MockAdjustmentService) ReverseAdjustment(ctx context.Context, loanID, adjustmentID string, reasonCode service.AdjustmentReasonCode, operatorID string) (*service.Adjustment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReverseAdjustment", ctx, loanID, adjustmentID, reasonCode, operatorID)
+	ret0, _ := ret[0].(*service.Adjustment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReverseAdjustment indicates an expected call of ReverseAdjustment.
+func (mr *MockAdjustmentServiceMockRecorder) ReverseAdjustment(ctx, loanID, adjustmentID, reasonCode, operatorID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReverseAdjustment", reflect.TypeOf((*MockAdjustmentService)(nil).ReverseAdjustment), ctx, loanID, adjustmentID, reasonCode, operatorID)
+}
+
+// MockAdjustmentStore is a mock of AdjustmentStore interface.
+type MockAdjustmentStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockAdjustmentStoreMockRecorder
+}
+
+// MockAdjustmentStoreMockRecorder is the mock recorder for MockAdjustmentStore.
+type MockAdjustmentStoreMockRecorder struct {
+	mock *MockAdjustmentStore
+}
+
+// NewMockAdjustmentStore creates a new mock instance.
+func NewMockAdjustmentStore(ctrl *gomock.Controller) *MockAdjustmentStore {
+	mock := &MockAdjustmentStore{ctrl: ctrl}
+	mock.recorder = &MockAdjustmentStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAdjustmentStore) EXPECT() *MockAdjustmentStoreMockRecorder {
+	return m.recorder
+}
+
+// ApproveAdjustment mocks base method.
+func (m *MockAdjustmentStore) ApproveAdjustment(ctx context.Context, adjustment *service.Adjustment) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApproveAdjustment", ctx, adjustment)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ApproveAdjustment indicates an expected call of ApproveAdjustment.
+func (mr *MockAdjustmentStoreMockRecorder) ApproveAdjustment(ctx, adjustment interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApproveAdjustment", reflect.TypeOf((*MockAdjustmentStore)(nil).ApproveAdjustment), ctx, adjustment)
+}
+
+// CreateAdjustment mocks base method.
+func (m *MockAdjustmentStore) CreateAdjustment(ctx context.Context, adjustment *service.Adjustment) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAdjustment", ctx, adjustment)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateAdjustment indicates an expected call of CreateAdjustment.
+func (mr *MockAdjustmentStoreMockRecorder) CreateAdjustment(ctx, adjustment interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAdjustment", reflect.TypeOf((*MockAdjustmentStore)(nil).CreateAdjustment), ctx, adjustment)
+}
+
+// ListAdjustmentsByLoanID mocks base method.
+func (m *MockAdjustmentStore) ListAdjustmentsByLoanID(ctx context.Context, loanID string) ([]service.Adjustment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAdjustmentsByLoanID", ctx, loanID)
+	ret0, _ := ret[0].([]service.Adjustment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAdjustmentsByLoanID indicates an expected call of ListAdjustmentsByLoanID.
+func (mr *MockAdjustmentStoreMockRecorder) ListAdjustmentsByLoanID(ctx, loanID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAdjustmentsByLoanID", reflect.TypeOf((*MockAdjustmentStore)(nil).ListAdjustmentsByLoanID), ctx, loanID)
+}
+
+// RejectAdjustment mocks base method.
+func (m *MockAdjustmentStore) RejectAdjustment(ctx context.Context, adjustment *service.Adjustment) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RejectAdjustment", ctx, adjustment)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RejectAdjustment indicates an expected call of RejectAdjustment.
+func (mr *MockAdjustmentStoreMockRecorder) RejectAdjustment(ctx, adjustment interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RejectAdjustment", reflect.TypeOf((*MockAdjustmentStore)(nil).RejectAdjustment), ctx, adjustment)
+}
@@ -0,0 +1,163 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/service/borrower.go
+
+// Package mock_billing is a generated GoMock package.
+package mock_billing
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+	service "github.com/theyudiriski/billing-service/internal/service"
+)
+
+// MockBorrowerService is a mock of BorrowerService interface.
+type MockBorrowerService struct {
+	ctrl     *gomock.Controller
+	recorder *MockBorrowerServiceMockRecorder
+}
+
+// MockBorrowerServiceMockRecorder is the mock recorder for MockBorrowerService.
+type MockBorrowerServiceMockRecorder struct {
+	mock *MockBorrowerService
+}
+
+// NewMockBorrowerService creates a new mock instance.
+func NewMockBorrowerService(ctrl *gomock.Controller) *MockBorrowerService {
+	mock := &MockBorrowerService{ctrl: ctrl}
+	mock.recorder = &MockBorrowerServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBorrowerService) EXPECT() *MockBorrowerServiceMockRecorder {
+	return m.recorder
+}
+
+// CreateBorrower mocks base method.
+func (m *MockBorrowerService) CreateBorrower(ctx context.Context, fullName string, identityType service.IdentityType, identityNumber string, dateOfBirth time.Time, email, phoneNumber, address string) (*service.Borrower, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateBorrower", ctx, fullName, identityType, identityNumber, dateOfBirth, email, phoneNumber, address)
+	ret0, _ := ret[0].(*service.Borrower)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateBorrower indicates an expected call of CreateBorrower.
+func (mr *MockBorrowerServiceMockRecorder) CreateBorrower(ctx, fullName, identityType, identityNumber, dateOfBirth, email, phoneNumber, address interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBorrower", reflect.TypeOf((*MockBorrowerService)(nil).CreateBorrower), ctx, fullName, identityType, identityNumber, dateOfBirth, email, phoneNumber, address)
+}
+
+// GetBorrower mocks base method.
+func (m *MockBorrowerService) GetBorrower(ctx context.Context, borrowerID string) (*service.Borrower, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBorrower", ctx, borrowerID)
+	ret0, _ := ret[0].(*service.Borrower)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBorrower indicates an expected call of GetBorrower.
+func (mr *MockBorrowerServiceMockRecorder) GetBorrower(ctx, borrowerID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBorrower", reflect.TypeOf((*MockBorrowerService)(nil).GetBorrower), ctx, borrowerID)
+}
+
+// UpdateKYCStatus mocks base method.
+func (m *MockBorrowerService) UpdateKYCStatus(ctx context.Context, borrowerID string, status service.KYCStatus) (*service.Borrower, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateKYCStatus", ctx, borrowerID, status)
+	ret0, _ := ret[0].(*service.Borrower)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateKYCStatus indicates an expected call of UpdateKYCStatus.
+func (mr *MockBorrowerServiceMockRecorder) UpdateKYCStatus(ctx, borrowerID, status interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateKYCStatus", reflect.TypeOf((*MockBorrowerService)(nil).UpdateKYCStatus), ctx, borrowerID, status)
+}
+
+// MockBorrowerStore is a mock of BorrowerStore interface.
+type MockBorrowerStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockBorrowerStoreMockRecorder
+}
+
+// MockBorrowerStoreMockRecorder is the mock recorder for MockBorrowerStore.
+type MockBorrowerStoreMockRecorder struct {
+	mock *MockBorrowerStore
+}
+
+// NewMockBorrowerStore creates a new mock instance.
+func NewMockBorrowerStore(ctrl *gomock.Controller) *MockBorrowerStore {
+	mock := &MockBorrowerStore{ctrl: ctrl}
+	mock.recorder = &MockBorrowerStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBorrowerStore) EXPECT() *MockBorrowerStoreMockRecorder {
+	return m.recorder
+}
+
+// CreateBorrower mocks base method.
+func (m *MockBorrowerStore) CreateBorrower(ctx context.Context, borrower *service.Borrower) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateBorrower", ctx, borrower)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateBorrower indicates an expected call of CreateBorrower.
+func (mr *MockBorrowerStoreMockRecorder) CreateBorrower(ctx, borrower interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBorrower", reflect.TypeOf((*MockBorrowerStore)(nil).CreateBorrower), ctx, borrower)
+}
+
+// GetBorrowerByID mocks base method.
+func (m *MockBorrowerStore) GetBorrowerByID(ctx context.Context, borrowerID string) (*service.Borrower, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBorrowerByID", ctx, borrowerID)
+	ret0, _ := ret[0].(*service.Borrower)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBorrowerByID indicates an expected call of GetBorrowerByID.
+func (mr *MockBorrowerStoreMockRecorder) GetBorrowerByID(ctx, borrowerID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBorrowerByID", reflect.TypeOf((*MockBorrowerStore)(nil).GetBorrowerByID), ctx, borrowerID)
+}
+
+// GetBorrowerByIDForUpdate mocks base method.
+func (m *MockBorrowerStore) GetBorrowerByIDForUpdate(ctx context.Context, borrowerID string) (*service.Borrower, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBorrowerByIDForUpdate", ctx, borrowerID)
+	ret0, _ := ret[0].(*service.Borrower)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBorrowerByIDForUpdate indicates an expected call of GetBorrowerByIDForUpdate.
+func (mr *MockBorrowerStoreMockRecorder) GetBorrowerByIDForUpdate(ctx, borrowerID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBorrowerByIDForUpdate", reflect.TypeOf((*MockBorrowerStore)(nil).GetBorrowerByIDForUpdate), ctx, borrowerID)
+}
+
+// UpdateKYCStatus mocks base method.
+func (m *MockBorrowerStore) UpdateKYCStatus(ctx context.Context, borrower *service.Borrower, from service.KYCStatus) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateKYCStatus", ctx, borrower, from)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateKYCStatus indicates an expected call of UpdateKYCStatus.
+func (mr *MockBorrowerStoreMockRecorder) UpdateKYCStatus(ctx, borrower, from interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateKYCStatus", reflect.TypeOf((*MockBorrowerStore)(nil).UpdateKYCStatus), ctx, borrower, from)
+}
@@ -0,0 +1,162 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/service/latefee.go
+
+// Package mock_billing is a generated GoMock package.
+package mock_billing
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+	service "github.com/theyudiriski/billing-service/internal/service"
+)
+
+// MockLateFeeService is a mock of LateFeeService interface.
+type MockLateFeeService struct {
+	ctrl     *gomock.Controller
+	recorder *MockLateFeeServiceMockRecorder
+}
+
+// MockLateFeeServiceMockRecorder is the mock recorder for MockLateFeeService.
+type MockLateFeeServiceMockRecorder struct {
+	mock *MockLateFeeService
+}
+
+// NewMockLateFeeService creates a new mock instance.
+func NewMockLateFeeService(ctrl *gomock.Controller) *MockLateFeeService {
+	mock := &MockLateFeeService{ctrl: ctrl}
+	mock.recorder = &MockLateFeeServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLateFeeService) EXPECT() *MockLateFeeServiceMockRecorder {
+	return m.recorder
+}
+
+// AccrueLateFees mocks base method.
+func (m *MockLateFeeService) AccrueLateFees(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AccrueLateFees", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AccrueLateFees indicates an expected call of AccrueLateFees.
+func (mr *MockLateFeeServiceMockRecorder) AccrueLateFees(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AccrueLateFees", reflect.TypeOf((*MockLateFeeService)(nil).AccrueLateFees), ctx)
+}
+
+// GetLateFees mocks base method.
+func (m *MockLateFeeService) GetLateFees(ctx context.Context, loanID string) ([]service.LateFee, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLateFees", ctx, loanID)
+	ret0, _ := ret[0].([]service.LateFee)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLateFees indicates an expected call of GetLateFees.
+func (mr *MockLateFeeServiceMockRecorder) GetLateFees(ctx, loanID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLateFees", reflect.TypeOf((*MockLateFeeService)(nil).GetLateFees), ctx, loanID)
+}
+
+// WaiveLateFee mocks base method.
+func (m *MockLateFeeService) WaiveLateFee(ctx context.Context, loanID, lateFeeID string) (*service.LateFee, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WaiveLateFee", ctx, loanID, lateFeeID)
+	ret0, _ := ret[0].(*service.LateFee)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WaiveLateFee indicates an expected call of WaiveLateFee.
+func (mr *MockLateFeeServiceMockRecorder) WaiveLateFee(ctx, loanID, lateFeeID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaiveLateFee", reflect.TypeOf((*MockLateFeeService)(nil).WaiveLateFee), ctx, loanID, lateFeeID)
+}
+
+// MockLateFeeStore is a mock of LateFeeStore interface.
+type MockLateFeeStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockLateFeeStoreMockRecorder
+}
+
+// MockLateFeeStoreMockRecorder is the mock recorder for MockLateFeeStore.
+type MockLateFeeStoreMockRecorder struct {
+	mock *MockLateFeeStore
+}
+
+// NewMockLateFeeStore creates a new mock instance.
+func NewMockLateFeeStore(ctrl *gomock.Controller) *MockLateFeeStore {
+	mock := &MockLateFeeStore{ctrl: ctrl}
+	mock.recorder = &MockLateFeeStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLateFeeStore) EXPECT() *MockLateFeeStoreMockRecorder {
+	return m.recorder
+}
+
+// CreateLateFees mocks base method.
+func (m *MockLateFeeStore) CreateLateFees(ctx context.Context, fees []service.LateFee) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateLateFees", ctx, fees)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateLateFees indicates an expected call of CreateLateFees.
+func (mr *MockLateFeeStoreMockRecorder) CreateLateFees(ctx, fees interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateLateFees", reflect.TypeOf((*MockLateFeeStore)(nil).CreateLateFees), ctx, fees)
+}
+
+// ListLateFeesByLoanID mocks base method.
+func (m *MockLateFeeStore) ListLateFeesByLoanID(ctx context.Context, loanID string) ([]service.LateFee, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListLateFeesByLoanID", ctx, loanID)
+	ret0, _ := ret[0].([]service.LateFee)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListLateFeesByLoanID indicates an expected call of ListLateFeesByLoanID.
+func (mr *MockLateFeeStoreMockRecorder) ListLateFeesByLoanID(ctx, loanID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListLateFeesByLoanID", reflect.TypeOf((*MockLateFeeStore)(nil).ListLateFeesByLoanID), ctx, loanID)
+}
+
+// ListOverdueSchedules mocks base method.
+func (m *MockLateFeeStore) ListOverdueSchedules(ctx context.Context, today time.Time, defaultGraceDays int) ([]service.LoanSchedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListOverdueSchedules", ctx, today, defaultGraceDays)
+	ret0, _ := ret[0].([]service.LoanSchedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListOverdueSchedules indicates an expected call of ListOverdueSchedules.
+func (mr *MockLateFeeStoreMockRecorder) ListOverdueSchedules(ctx, today, defaultGraceDays interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListOverdueSchedules", reflect.TypeOf((*MockLateFeeStore)(nil).ListOverdueSchedules), ctx, today, defaultGraceDays)
+}
+
+// WaiveLateFee mocks base method.
+func (m *MockLateFeeStore) WaiveLateFee(ctx context.Context, fee *service.LateFee) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WaiveLateFee", ctx, fee)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WaiveLateFee indicates an expected call of WaiveLateFee.
+func (mr *MockLateFeeStoreMockRecorder) WaiveLateFee(ctx, fee interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaiveLateFee", reflect.TypeOf((*MockLateFeeStore)(nil).WaiveLateFee), ctx, fee)
+}
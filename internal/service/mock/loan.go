@@ -35,19 +35,64 @@ func (m *MockLoanService) EXPECT() *MockLoanServiceMockRecorder {
 	return m.recorder
 }
 
+// CancelLoan mocks base method.
+func (m *MockLoanService) CancelLoan(ctx context.Context, loanID, reason string) (*service.Loan, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelLoan", ctx, loanID, reason)
+	ret0, _ := ret[0].(*service.Loan)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CancelLoan indicates an expected call of CancelLoan.
+func (mr *MockLoanServiceMockRecorder) CancelLoan(ctx, loanID, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelLoan", reflect.TypeOf((*MockLoanService)(nil).CancelLoan), ctx, loanID, reason)
+}
+
 // CreateLoan mocks base method.
-func (m *MockLoanService) CreateLoan(ctx context.Context, borrowerID string, principalAmount service.Amount, interestRate float64, paymentFrequency service.LoanFrequency, totalPayments int) (*service.Loan, error) {
+func (m *MockLoanService) CreateLoan(ctx context.Context, borrowerID, productID string, application service.LoanApplication) (*service.Loan, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateLoan", ctx, borrowerID, principalAmount, interestRate, paymentFrequency, totalPayments)
+	ret := m.ctrl.Call(m, "CreateLoan", ctx, borrowerID, productID, application)
 	ret0, _ := ret[0].(*service.Loan)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateLoan indicates an expected call of CreateLoan.
-func (mr *MockLoanServiceMockRecorder) CreateLoan(ctx, borrowerID, principalAmount, interestRate, paymentFrequency, totalPayments interface{}) *gomock.Call {
+func (mr *MockLoanServiceMockRecorder) CreateLoan(ctx, borrowerID, productID, application interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateLoan", reflect.TypeOf((*MockLoanService)(nil).CreateLoan), ctx, borrowerID, principalAmount, interestRate, paymentFrequency, totalPayments)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateLoan", reflect.TypeOf((*MockLoanService)(nil).CreateLoan), ctx, borrowerID, productID, application)
+}
+
+// DeferInstallments mocks base method.
+func (m *MockLoanService) DeferInstallments(ctx context.Context, loanID string, terms service.LoanDeferralTerms) (*service.LoanDeferral, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeferInstallments", ctx, loanID, terms)
+	ret0, _ := ret[0].(*service.LoanDeferral)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeferInstallments indicates an expected call of DeferInstallments.
+func (mr *MockLoanServiceMockRecorder) DeferInstallments(ctx, loanID, terms interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeferInstallments", reflect.TypeOf((*MockLoanService)(nil).DeferInstallments), ctx, loanID, terms)
+}
+
+// DisburseLoan mocks base method.
+func (m *MockLoanService) DisburseLoan(ctx context.Context, loanID string, disbursement service.LoanDisbursement) (*service.Loan, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisburseLoan", ctx, loanID, disbursement)
+	ret0, _ := ret[0].(*service.Loan)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DisburseLoan indicates an expected call of DisburseLoan.
+func (mr *MockLoanServiceMockRecorder) DisburseLoan(ctx, loanID, disbursement interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisburseLoan", reflect.TypeOf((*MockLoanService)(nil).DisburseLoan), ctx, loanID, disbursement)
 }
 
 // GetOutstanding mocks base method.
@@ -65,6 +110,36 @@ func (mr *MockLoanServiceMockRecorder) GetOutstanding(ctx, loanID interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOutstanding", reflect.TypeOf((*MockLoanService)(nil).GetOutstanding), ctx, loanID)
 }
 
+// GetPayments mocks base method.
+func (m *MockLoanService) GetPayments(ctx context.Context, loanID string) ([]service.Payment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPayments", ctx, loanID)
+	ret0, _ := ret[0].([]service.Payment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPayments indicates an expected call of GetPayments.
+func (mr *MockLoanServiceMockRecorder) GetPayments(ctx, loanID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPayments", reflect.TypeOf((*MockLoanService)(nil).GetPayments), ctx, loanID)
+}
+
+// GetSchedules mocks base method.
+func (m *MockLoanService) GetSchedules(ctx context.Context, loanID string, filter service.LoanScheduleFilter) ([]service.LoanSchedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSchedules", ctx, loanID, filter)
+	ret0, _ := ret[0].([]service.LoanSchedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSchedules indicates an expected call of GetSchedules.
+func (mr *MockLoanServiceMockRecorder) GetSchedules(ctx, loanID, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSchedules", reflect.TypeOf((*MockLoanService)(nil).GetSchedules), ctx, loanID, filter)
+}
+
 // GetTotalPending mocks base method.
 func (m *MockLoanService) GetTotalPending(ctx context.Context, loanID string) (*service.PendingLoan, error) {
 	m.ctrl.T.Helper()
@@ -95,18 +170,64 @@ func (mr *MockLoanServiceMockRecorder) IsDelinquent(ctx, loanID interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsDelinquent", reflect.TypeOf((*MockLoanService)(nil).IsDelinquent), ctx, loanID)
 }
 
+// ListBorrowerLoans mocks base method.
+func (m *MockLoanService) ListBorrowerLoans(ctx context.Context, borrowerID string, filter service.BorrowerLoanFilter) (*service.BorrowerLoans, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListBorrowerLoans", ctx, borrowerID, filter)
+	ret0, _ := ret[0].(*service.BorrowerLoans)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListBorrowerLoans indicates an expected call of ListBorrowerLoans.
+func (mr *MockLoanServiceMockRecorder) ListBorrowerLoans(ctx, borrowerID, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListBorrowerLoans", reflect.TypeOf((*MockLoanService)(nil).ListBorrowerLoans), ctx, borrowerID, filter)
+}
+
 // PayLoan mocks base method.
-func (m *MockLoanService) PayLoan(ctx context.Context, loanID string, payAmount service.Amount) error {
+func (m *MockLoanService) PayLoan(ctx context.Context, loanID string, payAmount service.Amount, channel, externalReference, idempotencyKey string) (*service.Payment, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "PayLoan", ctx, loanID, payAmount)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "PayLoan", ctx, loanID, payAmount, channel, externalReference, idempotencyKey)
+	ret0, _ := ret[0].(*service.Payment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // PayLoan indicates an expected call of PayLoan.
-func (mr *MockLoanServiceMockRecorder) PayLoan(ctx, loanID, payAmount interface{}) *gomock.Call {
+func (mr *MockLoanServiceMockRecorder) PayLoan(ctx, loanID, payAmount, channel, externalReference, idempotencyKey interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PayLoan", reflect.TypeOf((*MockLoanService)(nil).PayLoan), ctx, loanID, payAmount, channel, externalReference, idempotencyKey)
+}
+
+// RestructureLoan mocks base method.
+func (m *MockLoanService) RestructureLoan(ctx context.Context, loanID string, terms service.LoanRestructureTerms) (*service.LoanRestructure, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestructureLoan", ctx, loanID, terms)
+	ret0, _ := ret[0].(*service.LoanRestructure)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestructureLoan indicates an expected call of RestructureLoan.
+func (mr *MockLoanServiceMockRecorder) RestructureLoan(ctx, loanID, terms interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestructureLoan", reflect.TypeOf((*MockLoanService)(nil).RestructureLoan), ctx, loanID, terms)
+}
+
+// WriteOffLoan mocks base method.
+func (m *MockLoanService) WriteOffLoan(ctx context.Context, loanID, reason string) (*service.Loan, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteOffLoan", ctx, loanID, reason)
+	ret0, _ := ret[0].(*service.Loan)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteOffLoan indicates an expected call of WriteOffLoan.
+func (mr *MockLoanServiceMockRecorder) WriteOffLoan(ctx, loanID, reason interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PayLoan", reflect.TypeOf((*MockLoanService)(nil).PayLoan), ctx, loanID, payAmount)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteOffLoan", reflect.TypeOf((*MockLoanService)(nil).WriteOffLoan), ctx, loanID, reason)
 }
 
 // MockLoanStore is a mock of LoanStore interface.
@@ -132,6 +253,20 @@ func (m *MockLoanStore) EXPECT() *MockLoanStoreMockRecorder {
 	return m.recorder
 }
 
+// CancelLoan mocks base method.
+func (m *MockLoanStore) CancelLoan(ctx context.Context, loan *service.Loan, from service.LoanStatus) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelLoan", ctx, loan, from)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelLoan indicates an expected call of CancelLoan.
+func (mr *MockLoanStoreMockRecorder) CancelLoan(ctx, loan, from interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelLoan", reflect.TypeOf((*MockLoanStore)(nil).CancelLoan), ctx, loan, from)
+}
+
 // CreateLoan mocks base method.
 func (m *MockLoanStore) CreateLoan(ctx context.Context, loan *service.Loan) error {
 	m.ctrl.T.Helper()
@@ -146,6 +281,49 @@ func (mr *MockLoanStoreMockRecorder) CreateLoan(ctx, loan interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateLoan", reflect.TypeOf((*MockLoanStore)(nil).CreateLoan), ctx, loan)
 }
 
+// DeferInstallments mocks base method.
+func (m *MockLoanStore) DeferInstallments(ctx context.Context, loan *service.Loan, deferral *service.LoanDeferral) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeferInstallments", ctx, loan, deferral)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeferInstallments indicates an expected call of DeferInstallments.
+func (mr *MockLoanStoreMockRecorder) DeferInstallments(ctx, loan, deferral interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeferInstallments", reflect.TypeOf((*MockLoanStore)(nil).DeferInstallments), ctx, loan, deferral)
+}
+
+// DisburseLoan mocks base method.
+func (m *MockLoanStore) DisburseLoan(ctx context.Context, loan *service.Loan) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisburseLoan", ctx, loan)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisburseLoan indicates an expected call of DisburseLoan.
+func (mr *MockLoanStoreMockRecorder) DisburseLoan(ctx, loan interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisburseLoan", reflect.TypeOf((*MockLoanStore)(nil).DisburseLoan), ctx, loan)
+}
+
+// GetBorrowerExposure mocks base method.
+func (m *MockLoanStore) GetBorrowerExposure(ctx context.Context, borrowerID string) (*service.BorrowerExposure, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBorrowerExposure", ctx, borrowerID)
+	ret0, _ := ret[0].(*service.BorrowerExposure)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBorrowerExposure indicates an expected call of GetBorrowerExposure.
+func (mr *MockLoanStoreMockRecorder) GetBorrowerExposure(ctx, borrowerID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBorrowerExposure", reflect.TypeOf((*MockLoanStore)(nil).GetBorrowerExposure), ctx, borrowerID)
+}
+
 // GetLoanByID mocks base method.
 func (m *MockLoanStore) GetLoanByID(ctx context.Context, loanID string) (*service.Loan, error) {
 	m.ctrl.T.Helper()
@@ -161,11 +339,26 @@ func (mr *MockLoanStoreMockRecorder) GetLoanByID(ctx, loanID interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLoanByID", reflect.TypeOf((*MockLoanStore)(nil).GetLoanByID), ctx, loanID)
 }
 
+// GetLoanByIDForUpdate mocks base method.
+func (m *MockLoanStore) GetLoanByIDForUpdate(ctx context.Context, loanID string) (*service.Loan, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLoanByIDForUpdate", ctx, loanID)
+	ret0, _ := ret[0].(*service.Loan)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLoanByIDForUpdate indicates an expected call of GetLoanByIDForUpdate.
+func (mr *MockLoanStoreMockRecorder) GetLoanByIDForUpdate(ctx, loanID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLoanByIDForUpdate", reflect.TypeOf((*MockLoanStore)(nil).GetLoanByIDForUpdate), ctx, loanID)
+}
+
 // GetOutstanding mocks base method.
-func (m *MockLoanStore) GetOutstanding(ctx context.Context, loanID string) (*service.Amount, error) {
+func (m *MockLoanStore) GetOutstanding(ctx context.Context, loanID string) (*service.AmountBreakdown, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetOutstanding", ctx, loanID)
-	ret0, _ := ret[0].(*service.Amount)
+	ret0, _ := ret[0].(*service.AmountBreakdown)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -177,10 +370,10 @@ func (mr *MockLoanStoreMockRecorder) GetOutstanding(ctx, loanID interface{}) *go
 }
 
 // GetTotalPending mocks base method.
-func (m *MockLoanStore) GetTotalPending(ctx context.Context, loanID string) (*service.Amount, error) {
+func (m *MockLoanStore) GetTotalPending(ctx context.Context, loanID string) (*service.AmountBreakdown, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetTotalPending", ctx, loanID)
-	ret0, _ := ret[0].(*service.Amount)
+	ret0, _ := ret[0].(*service.AmountBreakdown)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -191,6 +384,36 @@ func (mr *MockLoanStoreMockRecorder) GetTotalPending(ctx, loanID interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTotalPending", reflect.TypeOf((*MockLoanStore)(nil).GetTotalPending), ctx, loanID)
 }
 
+// GetUnsettledSchedules mocks base method.
+func (m *MockLoanStore) GetUnsettledSchedules(ctx context.Context, loanID string) ([]service.LoanSchedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUnsettledSchedules", ctx, loanID)
+	ret0, _ := ret[0].([]service.LoanSchedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUnsettledSchedules indicates an expected call of GetUnsettledSchedules.
+func (mr *MockLoanStoreMockRecorder) GetUnsettledSchedules(ctx, loanID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUnsettledSchedules", reflect.TypeOf((*MockLoanStore)(nil).GetUnsettledSchedules), ctx, loanID)
+}
+
+// HasPendingAdjustments mocks base method.
+func (m *MockLoanStore) HasPendingAdjustments(ctx context.Context, loanID string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HasPendingAdjustments", ctx, loanID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HasPendingAdjustments indicates an expected call of HasPendingAdjustments.
+func (mr *MockLoanStoreMockRecorder) HasPendingAdjustments(ctx, loanID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasPendingAdjustments", reflect.TypeOf((*MockLoanStore)(nil).HasPendingAdjustments), ctx, loanID)
+}
+
 // IsDelinquent mocks base method.
 func (m *MockLoanStore) IsDelinquent(ctx context.Context, userID string) (bool, error) {
 	m.ctrl.T.Helper()
@@ -206,16 +429,74 @@ func (mr *MockLoanStoreMockRecorder) IsDelinquent(ctx, userID interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsDelinquent", reflect.TypeOf((*MockLoanStore)(nil).IsDelinquent), ctx, userID)
 }
 
-// MarkPendingAsPaid mocks base method.
-func (m *MockLoanStore) MarkPendingAsPaid(ctx context.Context, loanID string) error {
+// ListLoansByBorrowerID mocks base method.
+func (m *MockLoanStore) ListLoansByBorrowerID(ctx context.Context, borrowerID string, filter service.BorrowerLoanFilter) ([]service.Loan, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListLoansByBorrowerID", ctx, borrowerID, filter)
+	ret0, _ := ret[0].([]service.Loan)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListLoansByBorrowerID indicates an expected call of ListLoansByBorrowerID.
+func (mr *MockLoanStoreMockRecorder) ListLoansByBorrowerID(ctx, borrowerID, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListLoansByBorrowerID", reflect.TypeOf((*MockLoanStore)(nil).ListLoansByBorrowerID), ctx, borrowerID, filter)
+}
+
+// ListSchedules mocks base method.
+func (m *MockLoanStore) ListSchedules(ctx context.Context, loanID string, filter service.LoanScheduleFilter) ([]service.LoanSchedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSchedules", ctx, loanID, filter)
+	ret0, _ := ret[0].([]service.LoanSchedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSchedules indicates an expected call of ListSchedules.
+func (mr *MockLoanStoreMockRecorder) ListSchedules(ctx, loanID, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSchedules", reflect.TypeOf((*MockLoanStore)(nil).ListSchedules), ctx, loanID, filter)
+}
+
+// RestructureLoan mocks base method.
+func (m *MockLoanStore) RestructureLoan(ctx context.Context, loan *service.Loan, restructure *service.LoanRestructure, from service.LoanStatus) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestructureLoan", ctx, loan, restructure, from)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RestructureLoan indicates an expected call of RestructureLoan.
+func (mr *MockLoanStoreMockRecorder) RestructureLoan(ctx, loan, restructure, from interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestructureLoan", reflect.TypeOf((*MockLoanStore)(nil).RestructureLoan), ctx, loan, restructure, from)
+}
+
+// UpdateLoanStatus mocks base method.
+func (m *MockLoanStore) UpdateLoanStatus(ctx context.Context, loanID string, from, to service.LoanStatus) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateLoanStatus", ctx, loanID, from, to)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateLoanStatus indicates an expected call of UpdateLoanStatus.
+func (mr *MockLoanStoreMockRecorder) UpdateLoanStatus(ctx, loanID, from, to interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateLoanStatus", reflect.TypeOf((*MockLoanStore)(nil).UpdateLoanStatus), ctx, loanID, from, to)
+}
+
+// WriteOffLoan mocks base method.
+func (m *MockLoanStore) WriteOffLoan(ctx context.Context, loan *service.Loan, from service.LoanStatus) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "MarkPendingAsPaid", ctx, loanID)
+	ret := m.ctrl.Call(m, "WriteOffLoan", ctx, loan, from)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// MarkPendingAsPaid indicates an expected call of MarkPendingAsPaid.
-func (mr *MockLoanStoreMockRecorder) MarkPendingAsPaid(ctx, loanID interface{}) *gomock.Call {
+// WriteOffLoan indicates an expected call of WriteOffLoan.
+func (mr *MockLoanStoreMockRecorder) WriteOffLoan(ctx, loan, from interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkPendingAsPaid", reflect.TypeOf((*MockLoanStore)(nil).MarkPendingAsPaid), ctx, loanID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteOffLoan", reflect.TypeOf((*MockLoanStore)(nil).WriteOffLoan), ctx, loan, from)
 }
@@ -0,0 +1,80 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/service/payment.go
+
+// Package mock_billing is a generated GoMock package.
+package mock_billing
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	service "github.com/theyudiriski/billing-service/internal/service"
+)
+
+// MockPaymentStore is a mock of PaymentStore interface.
+type MockPaymentStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockPaymentStoreMockRecorder
+}
+
+// MockPaymentStoreMockRecorder is the mock recorder for MockPaymentStore.
+type MockPaymentStoreMockRecorder struct {
+	mock *MockPaymentStore
+}
+
+// NewMockPaymentStore creates a new mock instance.
+func NewMockPaymentStore(ctrl *gomock.Controller) *MockPaymentStore {
+	mock := &MockPaymentStore{ctrl: ctrl}
+	mock.recorder = &MockPaymentStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPaymentStore) EXPECT() *MockPaymentStoreMockRecorder {
+	return m.recorder
+}
+
+// CreatePayment mocks base method.
+func (m *MockPaymentStore) CreatePayment(ctx context.Context, payment *service.Payment, idempotencyKey *service.IdempotencyKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePayment", ctx, payment, idempotencyKey)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreatePayment indicates an expected call of CreatePayment.
+func (mr *MockPaymentStoreMockRecorder) CreatePayment(ctx, payment, idempotencyKey interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePayment", reflect.TypeOf((*MockPaymentStore)(nil).CreatePayment), ctx, payment, idempotencyKey)
+}
+
+// GetIdempotencyKey mocks base method.
+func (m *MockPaymentStore) GetIdempotencyKey(ctx context.Context, key string) (*service.IdempotencyKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetIdempotencyKey", ctx, key)
+	ret0, _ := ret[0].(*service.IdempotencyKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetIdempotencyKey indicates an expected call of GetIdempotencyKey.
+func (mr *MockPaymentStoreMockRecorder) GetIdempotencyKey(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIdempotencyKey", reflect.TypeOf((*MockPaymentStore)(nil).GetIdempotencyKey), ctx, key)
+}
+
+// ListPaymentsByLoanID mocks base method.
+func (m *MockPaymentStore) ListPaymentsByLoanID(ctx context.Context, loanID string) ([]service.Payment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPaymentsByLoanID", ctx, loanID)
+	ret0, _ := ret[0].([]service.Payment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPaymentsByLoanID indicates an expected call of ListPaymentsByLoanID.
+func (mr *MockPaymentStoreMockRecorder) ListPaymentsByLoanID(ctx, loanID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPaymentsByLoanID", reflect.TypeOf((*MockPaymentStore)(nil).ListPaymentsByLoanID), ctx, loanID)
+}
@@ -0,0 +1,133 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/service/payoff.go
+
+// Package mock_billing is a generated GoMock package.
+package mock_billing
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+	service "github.com/theyudiriski/billing-service/internal/service"
+)
+
+// MockPayoffService is a mock of PayoffService interface.
+type MockPayoffService struct {
+	ctrl     *gomock.Controller
+	recorder *MockPayoffServiceMockRecorder
+}
+
+// MockPayoffServiceMockRecorder is the mock recorder for MockPayoffService.
+type MockPayoffServiceMockRecorder struct {
+	mock *MockPayoffService
+}
+
+// NewMockPayoffService creates a new mock instance.
+func NewMockPayoffService(ctrl *gomock.Controller) *MockPayoffService {
+	mock := &MockPayoffService{ctrl: ctrl}
+	mock.recorder = &MockPayoffServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPayoffService) EXPECT() *MockPayoffServiceMockRecorder {
+	return m.recorder
+}
+
+// Payoff mocks base method.
+func (m *MockPayoffService) Payoff(ctx context.Context, loanID, quoteID, channel, externalReference string) (*service.Payment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Payoff", ctx, loanID, quoteID, channel, externalReference)
+	ret0, _ := ret[0].(*service.Payment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Payoff indicates an expected call of Payoff.
+func (mr *MockPayoffServiceMockRecorder) Payoff(ctx, loanID, quoteID, channel, externalReference interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Payoff", reflect.TypeOf((*MockPayoffService)(nil).Payoff), ctx, loanID, quoteID, channel, externalReference)
+}
+
+// QuotePayoff mocks base method.
+func (m *MockPayoffService) QuotePayoff(ctx context.Context, loanID string, asOf time.Time) (*service.PayoffQuote, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QuotePayoff", ctx, loanID, asOf)
+	ret0, _ := ret[0].(*service.PayoffQuote)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QuotePayoff indicates an expected call of QuotePayoff.
+func (mr *MockPayoffServiceMockRecorder) QuotePayoff(ctx, loanID, asOf interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QuotePayoff", reflect.TypeOf((*MockPayoffService)(nil).QuotePayoff), ctx, loanID, asOf)
+}
+
+// MockPayoffStore is a mock of PayoffStore interface.
+type MockPayoffStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockPayoffStoreMockRecorder
+}
+
+// MockPayoffStoreMockRecorder is the mock recorder for MockPayoffStore.
+type MockPayoffStoreMockRecorder struct {
+	mock *MockPayoffStore
+}
+
+// NewMockPayoffStore creates a new mock instance.
+func NewMockPayoffStore(ctrl *gomock.Controller) *MockPayoffStore {
+	mock := &MockPayoffStore{ctrl: ctrl}
+	mock.recorder = &MockPayoffStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPayoffStore) EXPECT() *MockPayoffStoreMockRecorder {
+	return m.recorder
+}
+
+// CreateQuote mocks base method.
+func (m *MockPayoffStore) CreateQuote(ctx context.Context, quote *service.PayoffQuote) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateQuote", ctx, quote)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateQuote indicates an expected call of CreateQuote.
+func (mr *MockPayoffStoreMockRecorder) CreateQuote(ctx, quote interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateQuote", reflect.TypeOf((*MockPayoffStore)(nil).CreateQuote), ctx, quote)
+}
+
+// GetQuoteByID mocks base method.
+func (m *MockPayoffStore) GetQuoteByID(ctx context.Context, quoteID string) (*service.PayoffQuote, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetQuoteByID", ctx, quoteID)
+	ret0, _ := ret[0].(*service.PayoffQuote)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetQuoteByID indicates an expected call of GetQuoteByID.
+func (mr *MockPayoffStoreMockRecorder) GetQuoteByID(ctx, quoteID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetQuoteByID", reflect.TypeOf((*MockPayoffStore)(nil).GetQuoteByID), ctx, quoteID)
+}
+
+// MarkQuotePaid mocks base method.
+func (m *MockPayoffStore) MarkQuotePaid(ctx context.Context, quote *service.PayoffQuote) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkQuotePaid", ctx, quote)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkQuotePaid indicates an expected call of MarkQuotePaid.
+func (mr *MockPayoffStoreMockRecorder) MarkQuotePaid(ctx, quote interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkQuotePaid", reflect.TypeOf((*MockPayoffStore)(nil).MarkQuotePaid), ctx, quote)
+}
@@ -0,0 +1,177 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/service/product.go
+
+// Package mock_billing is a generated GoMock package.
+package mock_billing
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	service "github.com/theyudiriski/billing-service/internal/service"
+)
+
+// MockLoanProductService is a mock of LoanProductService interface.
+type MockLoanProductService struct {
+	ctrl     *gomock.Controller
+	recorder *MockLoanProductServiceMockRecorder
+}
+
+// MockLoanProductServiceMockRecorder is the mock recorder for MockLoanProductService.
+type MockLoanProductServiceMockRecorder struct {
+	mock *MockLoanProductService
+}
+
+// NewMockLoanProductService creates a new mock instance.
+func NewMockLoanProductService(ctrl *gomock.Controller) *MockLoanProductService {
+	mock := &MockLoanProductService{ctrl: ctrl}
+	mock.recorder = &MockLoanProductServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLoanProductService) EXPECT() *MockLoanProductServiceMockRecorder {
+	return m.recorder
+}
+
+// CreateProduct mocks base method.
+func (m *MockLoanProductService) CreateProduct(ctx context.Context, name string, terms service.LoanProductTerms) (*service.LoanProduct, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateProduct", ctx, name, terms)
+	ret0, _ := ret[0].(*service.LoanProduct)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateProduct indicates an expected call of CreateProduct.
+func (mr *MockLoanProductServiceMockRecorder) CreateProduct(ctx, name, terms interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateProduct", reflect.TypeOf((*MockLoanProductService)(nil).CreateProduct), ctx, name, terms)
+}
+
+// GetProduct mocks base method.
+func (m *MockLoanProductService) GetProduct(ctx context.Context, productID string) (*service.LoanProduct, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProduct", ctx, productID)
+	ret0, _ := ret[0].(*service.LoanProduct)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProduct indicates an expected call of GetProduct.
+func (mr *MockLoanProductServiceMockRecorder) GetProduct(ctx, productID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProduct", reflect.TypeOf((*MockLoanProductService)(nil).GetProduct), ctx, productID)
+}
+
+// ListProducts mocks base method.
+func (m *MockLoanProductService) ListProducts(ctx context.Context, status service.LoanProductStatus) ([]service.LoanProduct, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListProducts", ctx, status)
+	ret0, _ := ret[0].([]service.LoanProduct)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListProducts indicates an expected call of ListProducts.
+func (mr *MockLoanProductServiceMockRecorder) ListProducts(ctx, status interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListProducts", reflect.TypeOf((*MockLoanProductService)(nil).ListProducts), ctx, status)
+}
+
+// UpdateProductStatus mocks base method.
+func (m *MockLoanProductService) UpdateProductStatus(ctx context.Context, productID string, status service.LoanProductStatus) (*service.LoanProduct, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateProductStatus", ctx, productID, status)
+	ret0, _ := ret[0].(*service.LoanProduct)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateProductStatus indicates an expected call of UpdateProductStatus.
+func (mr *MockLoanProductServiceMockRecorder) UpdateProductStatus(ctx, productID, status interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProductStatus", reflect.TypeOf((*MockLoanProductService)(nil).UpdateProductStatus), ctx, productID, status)
+}
+
+// MockLoanProductStore is a mock of LoanProductStore interface.
+type MockLoanProductStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockLoanProductStoreMockRecorder
+}
+
+// MockLoanProductStoreMockRecorder is the mock recorder for MockLoanProductStore.
+type MockLoanProductStoreMockRecorder struct {
+	mock *MockLoanProductStore
+}
+
+// NewMockLoanProductStore creates a new mock instance.
+func NewMockLoanProductStore(ctrl *gomock.Controller) *MockLoanProductStore {
+	mock := &MockLoanProductStore{ctrl: ctrl}
+	mock.recorder = &MockLoanProductStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLoanProductStore) EXPECT() *MockLoanProductStoreMockRecorder {
+	return m.recorder
+}
+
+// CreateProduct mocks base method.
+func (m *MockLoanProductStore) CreateProduct(ctx context.Context, product *service.LoanProduct) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateProduct", ctx, product)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateProduct indicates an expected call of CreateProduct.
+func (mr *MockLoanProductStoreMockRecorder) CreateProduct(ctx, product interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateProduct", reflect.TypeOf((*MockLoanProductStore)(nil).CreateProduct), ctx, product)
+}
+
+// GetProductByID mocks base method.
+func (m *MockLoanProductStore) GetProductByID(ctx context.Context, productID string) (*service.LoanProduct, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProductByID", ctx, productID)
+	ret0, _ := ret[0].(*service.LoanProduct)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProductByID indicates an expected call of GetProductByID.
+func (mr *MockLoanProductStoreMockRecorder) GetProductByID(ctx, productID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProductByID", reflect.TypeOf((*MockLoanProductStore)(nil).GetProductByID), ctx, productID)
+}
+
+// ListProducts mocks base method.
+func (m *MockLoanProductStore) ListProducts(ctx context.Context, status service.LoanProductStatus) ([]service.LoanProduct, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListProducts", ctx, status)
+	ret0, _ := ret[0].([]service.LoanProduct)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListProducts indicates an expected call of ListProducts.
+func (mr *MockLoanProductStoreMockRecorder) ListProducts(ctx, status interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListProducts", reflect.TypeOf((*MockLoanProductStore)(nil).ListProducts), ctx, status)
+}
+
+// UpdateProductStatus mocks base method.
+func (m *MockLoanProductStore) UpdateProductStatus(ctx context.Context, product *service.LoanProduct, from service.LoanProductStatus) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateProductStatus", ctx, product, from)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateProductStatus indicates an expected call of UpdateProductStatus.
+func (mr *MockLoanProductStoreMockRecorder) UpdateProductStatus(ctx, product, from interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProductStatus", reflect.TypeOf((*MockLoanProductStore)(nil).UpdateProductStatus), ctx, product, from)
+}
@@ -0,0 +1,83 @@
+package billing
+
+import (
+	"context"
+	"time"
+)
+
+type PaymentStore interface {
+	// CreatePayment applies the payment allocations to the loan schedules and
+	// records the payment in a single transaction. When an idempotency key is
+	// given it is stored along, failing with ErrIdempotencyKeyConflict if the key
+	// is already taken.
+	CreatePayment(ctx context.Context, payment *Payment, idempotencyKey *IdempotencyKey) error
+	ListPaymentsByLoanID(ctx context.Context, loanID string) ([]Payment, error)
+	GetIdempotencyKey(ctx context.Context, key string) (*IdempotencyKey, error)
+}
+
+type Payment struct {
+	ID                string
+	LoanID            string
+	Amount            Amount
+	Channel           string
+	ExternalReference string
+	PaidAt            time.Time
+
+	// installments touched by this payment, oldest due first
+	Allocations []PaymentAllocation
+	// part of the payment left after every installment has been settled,
+	// held as credit for the borrower
+	UnappliedAmount Amount
+}
+
+type PaymentAllocation struct {
+	ScheduleID string
+	Seq        int
+	DueDate    time.Time
+	Amount     Amount
+
+	// schedule state after the allocation
+	SchedulePaidAmount Amount
+	ScheduleStatus     LoanScheduleStatus
+}
+
+// allocatePayment spreads the payment over the unsettled schedules, oldest due
+// first. Any remainder once every schedule is settled is returned as unapplied.
+func allocatePayment(
+	schedules []LoanSchedule,
+	payAmount Amount,
+) ([]PaymentAllocation, Amount) {
+	remaining := payAmount
+	allocations := []PaymentAllocation{}
+
+	for _, schedule := range schedules {
+		if remaining.IsZero() {
+			break
+		}
+
+		balance := schedule.Balance()
+		if balance.Cmp(balance.ZeroLike()) <= 0 {
+			continue
+		}
+
+		allocated := remaining.Min(balance)
+		remaining = remaining.Sub(allocated)
+
+		status := LoanScheduleStatusPartiallyPaid
+		if allocated.Cmp(balance) == 0 {
+			status = LoanScheduleStatusPaid
+		}
+
+		allocations = append(allocations, PaymentAllocation{
+			ScheduleID: schedule.ID,
+			Seq:        schedule.Seq,
+			DueDate:    schedule.DueDate,
+			Amount:     allocated,
+
+			SchedulePaidAmount: schedule.PaidAmount.Add(allocated),
+			ScheduleStatus:     status,
+		})
+	}
+
+	return allocations, remaining
+}
@@ -0,0 +1,505 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+type PayoffService interface {
+	// QuotePayoff prices settling the loan in full on the asOf day, interest not
+	// yet accrued by then is rebated.
+	QuotePayoff(ctx context.Context, loanID string, asOf time.Time) (*PayoffQuote, error)
+	// Payoff pays the quoted amount and closes every remaining schedule of the
+	// loan at once. Paying a quote again returns the payment it was paid with.
+	Payoff(
+		ctx context.Context,
+		loanID string,
+		quoteID string,
+		channel string,
+		externalReference string,
+	) (*Payment, error)
+}
+
+type PayoffStore interface {
+	CreateQuote(ctx context.Context, quote *PayoffQuote) error
+	GetQuoteByID(ctx context.Context, quoteID string) (*PayoffQuote, error)
+	// MarkQuotePaid records the payment the quote was paid with, failing with
+	// ErrPayoffQuoteUsed when it has been paid already.
+	MarkQuotePaid(ctx context.Context, quote *PayoffQuote) error
+}
+
+func NewPayoffService(
+	logger Logger,
+	rules PayoffRules,
+	txManager TxManager,
+	loanStore LoanStore,
+	paymentStore PaymentStore,
+	adjustmentStore AdjustmentStore,
+	payoffStore PayoffStore,
+) PayoffService {
+	return &payoffService{
+		logger:          logger,
+		rules:           rules,
+		txManager:       txManager,
+		loanStore:       loanStore,
+		paymentStore:    paymentStore,
+		adjustmentStore: adjustmentStore,
+		payoffStore:     payoffStore,
+	}
+}
+
+type payoffService struct {
+	logger          Logger
+	rules           PayoffRules
+	txManager       TxManager
+	loanStore       LoanStore
+	paymentStore    PaymentStore
+	adjustmentStore AdjustmentStore
+	payoffStore     PayoffStore
+}
+
+// PayoffRules configures what settling a loan early costs. A zero value
+// rebates all interest not accrued yet.
+type PayoffRules struct {
+	// charged on the principal paid ahead of its due date, never more than the
+	// rebate it comes out of
+	PrepaymentFeeRate float64
+}
+
+func NewPayoffRules(prepaymentFeeRate float64) (PayoffRules, error) {
+	if prepaymentFeeRate < 0 {
+		return PayoffRules{}, errors.New("payoff prepayment fee rate must not be negative")
+	}
+
+	return PayoffRules{
+		PrepaymentFeeRate: prepaymentFeeRate,
+	}, nil
+}
+
+// PayoffQuote is what settles a loan in full on a given day.
+type PayoffQuote struct {
+	ID     string
+	LoanID string
+	// local calendar day the quote is priced for
+	AsOf time.Time
+
+	// principal left to repay, due or not
+	Principal Amount
+	// interest left to pay on installments already due, plus the interest
+	// accrued so far in the current period
+	AccruedInterest Amount
+	Fee             Amount
+	LateFee         Amount
+	// interest of the remaining installments not accrued as of the day
+	Rebate        Amount
+	PrepaymentFee Amount
+	// Principal + AccruedInterest + Fee + LateFee + PrepaymentFee
+	Total Amount
+
+	ValidUntil time.Time
+	CreatedAt  time.Time
+	// payment the quote was paid with, nil until it is
+	PaymentID *string
+}
+
+// payoffSplit prices the payoff of a loan on asOf from its unsettled schedules,
+// the period of the first one starting at start. Along with the quote it
+// returns the interest to write off on each schedule, the rebate net of the
+// prepayment fee.
+func (r PayoffRules) payoffSplit(
+	loan *Loan,
+	schedules []LoanSchedule,
+	start time.Time,
+	asOf time.Time,
+) (*PayoffQuote, []Amount, error) {
+	zero := loan.PrincipalAmount.ZeroLike()
+	quote := &PayoffQuote{
+		LoanID:          loan.ID,
+		AsOf:            asOf,
+		Principal:       zero,
+		AccruedInterest: zero,
+		Fee:             zero,
+		LateFee:         zero,
+		Rebate:          zero,
+		PrepaymentFee:   zero,
+	}
+
+	prepaid := zero
+	rebates := make([]Amount, len(schedules))
+	for i, schedule := range schedules {
+		remaining := schedule.Remaining()
+		quote.Principal = quote.Principal.Add(remaining.Principal)
+		quote.Fee = quote.Fee.Add(remaining.Fee)
+		quote.LateFee = quote.LateFee.Add(remaining.LateFee)
+
+		// each period starts where the one before it ended
+		accrued, err := accruedInterest(schedule, start, remaining.Interest, asOf)
+		if err != nil {
+			return nil, nil, err
+		}
+		start = schedule.DueDate
+		quote.AccruedInterest = quote.AccruedInterest.Add(accrued)
+		rebates[i] = remaining.Interest.Sub(accrued)
+		quote.Rebate = quote.Rebate.Add(rebates[i])
+
+		if LocalDate(schedule.DueDate).After(asOf) {
+			prepaid = prepaid.Add(remaining.Principal)
+		}
+	}
+
+	prepaymentFee, err := prepaid.MulRate(r.PrepaymentFeeRate)
+	if err != nil {
+		return nil, nil, err
+	}
+	quote.PrepaymentFee = prepaymentFee.Min(quote.Rebate)
+
+	// the prepayment fee is kept out of the rebate, earliest schedules first
+	fee := quote.PrepaymentFee
+	for i := range rebates {
+		kept := fee.Min(rebates[i])
+		rebates[i] = rebates[i].Sub(kept)
+		fee = fee.Sub(kept)
+	}
+
+	quote.Total = quote.Principal.
+		Add(quote.AccruedInterest).
+		Add(quote.Fee).
+		Add(quote.LateFee).
+		Add(quote.PrepaymentFee)
+
+	return quote, rebates, nil
+}
+
+// periodStart returns when the period of the first unsettled schedule started,
+// the due date of the latest schedule paid before it, or the start of the loan,
+// when it was disbursed, if none was.
+func (s *payoffService) periodStart(
+	ctx context.Context,
+	loan *Loan,
+	schedules []LoanSchedule,
+) (time.Time, error) {
+	start := loan.StartedAt
+	if len(schedules) == 0 {
+		return start, nil
+	}
+
+	paid, err := s.loanStore.ListSchedules(ctx, loan.ID, LoanScheduleFilter{
+		Statuses: []LoanScheduleStatus{LoanScheduleStatusPaid},
+		DueTo:    &schedules[0].DueDate,
+	})
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to list paid schedules", "error", err)
+		return time.Time{}, err
+	}
+
+	for _, schedule := range paid {
+		if schedule.DueDate.After(start) {
+			start = schedule.DueDate
+		}
+	}
+
+	return start, nil
+}
+
+// accruedInterest returns the part of the remaining interest of a schedule
+// earned by asOf. Interest accrues by the day over the period ending on the due
+// date, a schedule already due has earned all of it.
+func accruedInterest(schedule LoanSchedule, start time.Time, remaining Amount, asOf time.Time) (Amount, error) {
+	start = LocalDate(start)
+	due := LocalDate(schedule.DueDate)
+
+	switch {
+	case !asOf.Before(due):
+		return remaining, nil
+	case !asOf.After(start):
+		return remaining.ZeroLike(), nil
+	}
+
+	elapsed := int64(asOf.Sub(start).Hours() / 24)
+	period := int64(due.Sub(start).Hours() / 24)
+	earned, err := schedule.InterestDue.mulRat(big.NewRat(elapsed, period))
+	if err != nil {
+		return Amount{}, err
+	}
+
+	// what has been paid of the interest counts towards what it has earned
+	paid := schedule.InterestDue.Sub(remaining)
+	if earned.Cmp(paid) <= 0 {
+		return remaining.ZeroLike(), nil
+	}
+	return earned.Sub(paid), nil
+}
+
+func (s *payoffService) QuotePayoff(
+	ctx context.Context,
+	loanID string,
+	asOf time.Time,
+) (*PayoffQuote, error) {
+	today := LocalDate(CurrentLocalTime())
+	if asOf.IsZero() {
+		asOf = today
+	}
+	asOf = LocalDate(asOf)
+
+	if asOf.Before(today) {
+		return nil, NewError(
+			ErrValidationError.Error(),
+			"as_of must not be in the past",
+			http.StatusBadRequest,
+		)
+	}
+
+	// priced from the leader, a lagging follower may miss the latest payment
+	var quote *PayoffQuote
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		quote, err = s.quotePayoff(ctx, loanID, asOf)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return quote, nil
+}
+
+func (s *payoffService) quotePayoff(
+	ctx context.Context,
+	loanID string,
+	asOf time.Time,
+) (*PayoffQuote, error) {
+	loan, err := s.loanStore.GetLoanByID(ctx, loanID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to get loan", "error", err)
+		return nil, err
+	}
+
+	if !loan.Status.IsPayable() {
+		s.logger.WarnContext(ctx, "loan is not payable", "status", loan.Status)
+		return nil, ErrLoanNotPayable
+	}
+
+	schedules, err := s.loanStore.GetUnsettledSchedules(ctx, loanID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to get unsettled schedules", "error", err)
+		return nil, err
+	}
+
+	start, err := s.periodStart(ctx, loan, schedules)
+	if err != nil {
+		return nil, err
+	}
+
+	quote, _, err := s.rules.payoffSplit(loan, schedules, start, asOf)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to price payoff", "error", err)
+		return nil, err
+	}
+	quote.ID = UUID()
+	quote.CreatedAt = CurrentLocalTime()
+	// good for the whole day it is priced for
+	quote.ValidUntil = asOf.AddDate(0, 0, 1)
+
+	if err := s.payoffStore.CreateQuote(ctx, quote); err != nil {
+		s.logger.WarnContext(ctx, "failed to create payoff quote", "error", err)
+		return nil, err
+	}
+
+	return quote, nil
+}
+
+func (s *payoffService) Payoff(
+	ctx context.Context,
+	loanID string,
+	quoteID string,
+	channel string,
+	externalReference string,
+) (*Payment, error) {
+	// the loan and its schedules stay locked from repricing the quote until
+	// every schedule is closed
+	var payment *Payment
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		payment, err = s.payoff(ctx, loanID, quoteID, channel, externalReference)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+func (s *payoffService) payoff(
+	ctx context.Context,
+	loanID string,
+	quoteID string,
+	channel string,
+	externalReference string,
+) (*Payment, error) {
+	loan, err := s.loanStore.GetLoanByIDForUpdate(ctx, loanID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to get loan", "error", err)
+		return nil, err
+	}
+
+	quote, err := s.payoffStore.GetQuoteByID(ctx, quoteID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to get payoff quote", "error", err)
+		return nil, err
+	}
+
+	if quote.LoanID != loan.ID {
+		return nil, ErrPayoffQuoteNotFound
+	}
+
+	// a retried payoff gets the payment of the original one
+	if quote.PaymentID != nil {
+		return s.quotePayment(ctx, quote)
+	}
+
+	if !loan.Status.IsPayable() {
+		s.logger.WarnContext(ctx, "loan is not payable", "status", loan.Status)
+		return nil, ErrLoanNotPayable
+	}
+
+	// a quote only prices the day it is for, the interest of a later day is not
+	// earned yet
+	now := CurrentLocalTime()
+	if now.Before(quote.AsOf) {
+		return nil, ErrPayoffQuoteNotDue
+	}
+	if !now.Before(quote.ValidUntil) {
+		return nil, ErrPayoffQuoteExpired
+	}
+
+	schedules, err := s.loanStore.GetUnsettledSchedules(ctx, loan.ID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to get unsettled schedules", "error", err)
+		return nil, err
+	}
+
+	start, err := s.periodStart(ctx, loan, schedules)
+	if err != nil {
+		return nil, err
+	}
+
+	// anything paid, charged or adjusted since the quote changes its price
+	current, rebates, err := s.rules.payoffSplit(loan, schedules, start, quote.AsOf)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to price payoff", "error", err)
+		return nil, err
+	}
+	if current.Total.Cmp(quote.Total) != 0 || current.Rebate.Cmp(quote.Rebate) != 0 {
+		s.logger.WarnContext(ctx, "payoff quote is stale", "quoted", quote.Total, "current", current.Total)
+		return nil, ErrPayoffQuoteStale
+	}
+
+	// the rebate is written off the schedules it comes from, so the quoted
+	// amount settles every one of them
+	for i := range schedules {
+		if rebates[i].IsZero() {
+			continue
+		}
+
+		if err := s.writeOffInterest(ctx, quote, schedules[i], rebates[i], now); err != nil {
+			return nil, err
+		}
+
+		schedules[i].InterestDue = schedules[i].InterestDue.Sub(rebates[i])
+		schedules[i].AmountDue = schedules[i].AmountDue.Sub(rebates[i])
+	}
+
+	allocations, unapplied := allocatePayment(schedules, quote.Total)
+
+	payment := &Payment{
+		ID:                UUID(),
+		LoanID:            loan.ID,
+		Amount:            quote.Total,
+		Channel:           channel,
+		ExternalReference: externalReference,
+		PaidAt:            now,
+
+		Allocations:     allocations,
+		UnappliedAmount: unapplied,
+	}
+
+	if err := s.paymentStore.CreatePayment(ctx, payment, nil); err != nil {
+		s.logger.WarnContext(ctx, "failed to create payment", "error", err)
+		return nil, err
+	}
+
+	quote.PaymentID = &payment.ID
+	if err := s.payoffStore.MarkQuotePaid(ctx, quote); err != nil {
+		s.logger.WarnContext(ctx, "failed to mark payoff quote paid", "error", err)
+		return nil, err
+	}
+
+	if err := settleLoanIfPaidOff(ctx, s.logger, s.loanStore, loan); err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+// writeOffInterest takes the rebate off the interest of a schedule, recorded
+// as an approved settlement adjustment on behalf of the quote.
+func (s *payoffService) writeOffInterest(
+	ctx context.Context,
+	quote *PayoffQuote,
+	schedule LoanSchedule,
+	rebate Amount,
+	now time.Time,
+) error {
+	operatorID := "payoff:" + quote.ID
+	adjustment := &Adjustment{
+		ID:          UUID(),
+		LoanID:      quote.LoanID,
+		ScheduleID:  schedule.ID,
+		Type:        AdjustmentTypeWriteOffInterest,
+		Amount:      rebate,
+		ReasonCode:  AdjustmentReasonSettlement,
+		Status:      AdjustmentStatusPendingApproval,
+		RequestedBy: operatorID,
+		RequestedAt: now,
+	}
+
+	if err := s.adjustmentStore.CreateAdjustment(ctx, adjustment); err != nil {
+		s.logger.WarnContext(ctx, "failed to create adjustment", "error", err)
+		return err
+	}
+
+	adjustment.Status = AdjustmentStatusApproved
+	adjustment.DecidedBy = &operatorID
+	adjustment.DecidedAt = &now
+
+	if err := s.adjustmentStore.ApproveAdjustment(ctx, adjustment); err != nil {
+		s.logger.WarnContext(ctx, "failed to approve adjustment", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// quotePayment returns the payment a quote was paid with.
+func (s *payoffService) quotePayment(
+	ctx context.Context,
+	quote *PayoffQuote,
+) (*Payment, error) {
+	payments, err := s.paymentStore.ListPaymentsByLoanID(ctx, quote.LoanID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to list payments", "error", err)
+		return nil, err
+	}
+
+	for i := range payments {
+		if payments[i].ID == *quote.PaymentID {
+			return &payments[i], nil
+		}
+	}
+
+	return nil, ErrPayoffQuoteUsed
+}
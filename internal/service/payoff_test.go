@@ -0,0 +1,462 @@
+package billing_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mock_billing "github.com/theyudiriski/billing-service/internal/service/mock"
+
+	billing "github.com/theyudiriski/billing-service/internal/service"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var (
+	mockPayoffStore *mock_billing.MockPayoffStore
+
+	payoffService billing.PayoffService
+)
+
+func providePayoffTest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	mockTxManager = newMockTxManager(ctrl)
+	mockLoanStore = mock_billing.NewMockLoanStore(ctrl)
+	mockPaymentStore = mock_billing.NewMockPaymentStore(ctrl)
+	mockAdjustmentStore = mock_billing.NewMockAdjustmentStore(ctrl)
+	mockPayoffStore = mock_billing.NewMockPayoffStore(ctrl)
+
+	payoffService = billing.NewPayoffService(
+		billing.NewLogger(),
+		billing.PayoffRules{PrepaymentFeeRate: 0.01},
+		mockTxManager,
+		mockLoanStore,
+		mockPaymentStore,
+		mockAdjustmentStore,
+		mockPayoffStore,
+	)
+}
+
+// payoffLoan is a weekly loan started 10 days ago, its first installment is 3
+// days overdue and the second 3 days into its period.
+func payoffLoan(loanID string) (func() *billing.Loan, func() []billing.LoanSchedule) {
+	startedAt := billing.LocalDate(billing.CurrentLocalTime()).AddDate(0, 0, -10)
+	loan := func() *billing.Loan {
+		return &billing.Loan{
+			ID:               loanID,
+			PrincipalAmount:  billing.NewAmount(1_000_000),
+			StartedAt:        startedAt,
+			PaymentFrequency: billing.LoanFrequencyWeekly,
+			TotalPayments:    4,
+			Status:           billing.LoanStatusActive,
+		}
+	}
+
+	schedules := func() []billing.LoanSchedule {
+		schedules := make([]billing.LoanSchedule, 0, 4)
+		for seq := 1; seq <= 4; seq++ {
+			schedules = append(schedules, billing.LoanSchedule{
+				ID:           "schedule-id",
+				LoanID:       loanID,
+				Seq:          seq,
+				DueDate:      billing.LoanFrequencyWeekly.DueDate(startedAt, seq),
+				AmountDue:    billing.NewAmount(257_000),
+				PrincipalDue: billing.NewAmount(250_000),
+				InterestDue:  billing.NewAmount(7_000),
+				FeeDue:       billing.NewAmount(0),
+				LateFeeDue:   billing.NewAmount(0),
+				PaidAmount:   billing.NewAmount(0),
+				Status:       billing.LoanScheduleStatusUnpaid,
+			})
+		}
+		return schedules
+	}
+
+	return loan, schedules
+}
+
+func TestQuotePayoff(t *testing.T) {
+	providePayoffTest(t)
+
+	Convey("QuotePayoff", t, FailureHalts, func() {
+		type (
+			args struct {
+				ctx    context.Context
+				loanID string
+				asOf   time.Time
+			}
+		)
+
+		var (
+			ctx             = context.Background()
+			loanID          = "loan-id"
+			today           = billing.LocalDate(billing.CurrentLocalTime())
+			loan, schedules = payoffLoan(loanID)
+		)
+
+		testCases := []struct {
+			testID      int
+			testDesc    string
+			testType    string
+			args        args
+			mock        func()
+			expectedErr error
+		}{
+			{
+				testID:   1,
+				testDesc: "success interest not accrued yet is rebated, less the prepayment fee",
+				testType: "P",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByID(ctx, loanID).Return(loan(), nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loanID).Return(schedules(), nil)
+					mockLoanStore.EXPECT().ListSchedules(ctx, loanID, gomock.Any()).Return([]billing.LoanSchedule{}, nil)
+					mockPayoffStore.EXPECT().CreateQuote(ctx, gomock.Any()).
+						Do(func(ctx context.Context, quote *billing.PayoffQuote) {
+							So(quote.AsOf.Equal(today), ShouldBeTrue)
+							So(quote.Principal, ShouldEqual, billing.NewAmount(1_000_000))
+							// all of the overdue installment, 3 of 7 days of the next
+							So(quote.AccruedInterest, ShouldEqual, billing.NewAmount(10_000))
+							So(quote.Rebate, ShouldEqual, billing.NewAmount(18_000))
+							So(quote.PrepaymentFee, ShouldEqual, billing.NewAmount(7_500))
+							So(quote.Total, ShouldEqual, billing.NewAmount(1_017_500))
+							So(quote.ValidUntil.Equal(today.AddDate(0, 0, 1)), ShouldBeTrue)
+						}).Return(nil)
+				},
+			},
+			{
+				testID:   2,
+				testDesc: "success partly paid interest counts towards what has accrued",
+				testType: "P",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+				},
+				mock: func() {
+					paid := schedules()
+					paid[1].PaidAmount = billing.NewAmount(5_000)
+					paid[1].Status = billing.LoanScheduleStatusPartiallyPaid
+
+					mockLoanStore.EXPECT().GetLoanByID(ctx, loanID).Return(loan(), nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loanID).Return(paid, nil)
+					mockLoanStore.EXPECT().ListSchedules(ctx, loanID, gomock.Any()).Return([]billing.LoanSchedule{}, nil)
+					mockPayoffStore.EXPECT().CreateQuote(ctx, gomock.Any()).
+						Do(func(ctx context.Context, quote *billing.PayoffQuote) {
+							So(quote.AccruedInterest, ShouldEqual, billing.NewAmount(7_000))
+							So(quote.Rebate, ShouldEqual, billing.NewAmount(16_000))
+							So(quote.Total, ShouldEqual, billing.NewAmount(1_014_500))
+						}).Return(nil)
+				},
+			},
+			{
+				testID:   3,
+				testDesc: "success quoted for the last due date accrues all interest",
+				testType: "P",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+					asOf:   today.AddDate(0, 0, 18),
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByID(ctx, loanID).Return(loan(), nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loanID).Return(schedules(), nil)
+					mockLoanStore.EXPECT().ListSchedules(ctx, loanID, gomock.Any()).Return([]billing.LoanSchedule{}, nil)
+					mockPayoffStore.EXPECT().CreateQuote(ctx, gomock.Any()).
+						Do(func(ctx context.Context, quote *billing.PayoffQuote) {
+							So(quote.AccruedInterest, ShouldEqual, billing.NewAmount(28_000))
+							So(quote.Rebate, ShouldEqual, billing.NewAmount(0))
+							So(quote.PrepaymentFee, ShouldEqual, billing.NewAmount(0))
+							So(quote.Total, ShouldEqual, billing.NewAmount(1_028_000))
+						}).Return(nil)
+				},
+			},
+			{
+				testID:   4,
+				testDesc: "success period starts on the due date of the installment paid before",
+				testType: "P",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+				},
+				mock: func() {
+					paid := schedules()[0]
+					paid.DueDate = today.AddDate(0, 0, -1)
+					paid.PaidAmount = paid.AmountDue
+					paid.Status = billing.LoanScheduleStatusPaid
+
+					mockLoanStore.EXPECT().GetLoanByID(ctx, loanID).Return(loan(), nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loanID).Return(schedules()[1:], nil)
+					mockLoanStore.EXPECT().ListSchedules(ctx, loanID, gomock.Any()).
+						Do(func(ctx context.Context, loanID string, filter billing.LoanScheduleFilter) {
+							So(filter.Statuses, ShouldResemble, []billing.LoanScheduleStatus{billing.LoanScheduleStatusPaid})
+							So(filter.DueTo.Equal(today.AddDate(0, 0, 4)), ShouldBeTrue)
+						}).Return([]billing.LoanSchedule{paid}, nil)
+					mockPayoffStore.EXPECT().CreateQuote(ctx, gomock.Any()).
+						Do(func(ctx context.Context, quote *billing.PayoffQuote) {
+							So(quote.Principal, ShouldEqual, billing.NewAmount(750_000))
+							// 1 of the 5 days since the paid installment was due
+							So(quote.AccruedInterest, ShouldEqual, billing.NewAmount(1_400))
+							So(quote.Rebate, ShouldEqual, billing.NewAmount(19_600))
+							So(quote.Total, ShouldEqual, billing.NewAmount(758_900))
+						}).Return(nil)
+				},
+			},
+			{
+				testID:   5,
+				testDesc: "failed as of a past day",
+				testType: "N",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+					asOf:   today.AddDate(0, 0, -1),
+				},
+				mock: func() {},
+			},
+			{
+				testID:   6,
+				testDesc: "failed loan already paid off",
+				testType: "N",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByID(ctx, loanID).Return(&billing.Loan{
+						ID:     loanID,
+						Status: billing.LoanStatusPaidOff,
+					}, nil)
+				},
+				expectedErr: billing.ErrLoanNotPayable,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Logf("%d - [%s] : %s", tc.testID, tc.testType, tc.testDesc)
+			tc.mock()
+
+			_, err := payoffService.QuotePayoff(
+				tc.args.ctx,
+				tc.args.loanID,
+				tc.args.asOf,
+			)
+
+			if tc.testType == "P" {
+				So(err, ShouldBeNil)
+			} else {
+				So(err, ShouldNotBeNil)
+				if tc.expectedErr != nil {
+					So(err, ShouldEqual, tc.expectedErr)
+				}
+			}
+		}
+	})
+}
+
+func TestPayoff(t *testing.T) {
+	providePayoffTest(t)
+
+	Convey("Payoff", t, FailureHalts, func() {
+		type (
+			args struct {
+				ctx     context.Context
+				loanID  string
+				quoteID string
+			}
+		)
+
+		var (
+			ctx             = context.Background()
+			loanID          = "loan-id"
+			quoteID         = "quote-id"
+			paymentID       = "payment-id"
+			today           = billing.LocalDate(billing.CurrentLocalTime())
+			loan, schedules = payoffLoan(loanID)
+
+			quote = func(total float64, validUntil time.Time) *billing.PayoffQuote {
+				return &billing.PayoffQuote{
+					ID:         quoteID,
+					LoanID:     loanID,
+					AsOf:       today,
+					Rebate:     billing.NewAmount(18_000),
+					Total:      billing.NewAmount(total),
+					ValidUntil: validUntil,
+				}
+			}
+		)
+
+		testCases := []struct {
+			testID      int
+			testDesc    string
+			testType    string
+			args        args
+			mock        func()
+			expectedErr error
+		}{
+			{
+				testID:   1,
+				testDesc: "success rebate written off and every schedule settled",
+				testType: "P",
+				args: args{
+					ctx:     ctx,
+					loanID:  loanID,
+					quoteID: quoteID,
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(loan(), nil)
+					mockPayoffStore.EXPECT().GetQuoteByID(ctx, quoteID).Return(quote(1_017_500, today.AddDate(0, 0, 1)), nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loanID).Return(schedules(), nil)
+					mockLoanStore.EXPECT().ListSchedules(ctx, loanID, gomock.Any()).Return([]billing.LoanSchedule{}, nil)
+					// the prepayment fee keeps the rebate of the 2nd and half of the 3rd
+					mockAdjustmentStore.EXPECT().CreateAdjustment(ctx, gomock.Any()).Return(nil).Times(2)
+					gomock.InOrder(
+						mockAdjustmentStore.EXPECT().ApproveAdjustment(ctx, gomock.Any()).
+							Do(func(ctx context.Context, adjustment *billing.Adjustment) {
+								So(adjustment.Type, ShouldEqual, billing.AdjustmentTypeWriteOffInterest)
+								So(adjustment.ReasonCode, ShouldEqual, billing.AdjustmentReasonSettlement)
+								So(adjustment.Status, ShouldEqual, billing.AdjustmentStatusApproved)
+								So(adjustment.Amount, ShouldEqual, billing.NewAmount(3_500))
+							}).Return(nil),
+						mockAdjustmentStore.EXPECT().ApproveAdjustment(ctx, gomock.Any()).
+							Do(func(ctx context.Context, adjustment *billing.Adjustment) {
+								So(adjustment.Amount, ShouldEqual, billing.NewAmount(7_000))
+							}).Return(nil),
+					)
+					mockPaymentStore.EXPECT().CreatePayment(ctx, gomock.Any(), nil).
+						Do(func(ctx context.Context, payment *billing.Payment, _ *billing.IdempotencyKey) {
+							So(payment.Amount, ShouldEqual, billing.NewAmount(1_017_500))
+							So(payment.UnappliedAmount, ShouldEqual, billing.NewAmount(0))
+							So(payment.Allocations, ShouldHaveLength, 4)
+							for _, allocation := range payment.Allocations {
+								So(allocation.ScheduleStatus, ShouldEqual, billing.LoanScheduleStatusPaid)
+							}
+						}).Return(nil)
+					mockPayoffStore.EXPECT().MarkQuotePaid(ctx, gomock.Any()).Return(nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loanID).Return([]billing.LoanSchedule{}, nil)
+					mockLoanStore.EXPECT().UpdateLoanStatus(ctx, loanID, billing.LoanStatusActive, billing.LoanStatusPaidOff).Return(nil)
+				},
+			},
+			{
+				testID:   2,
+				testDesc: "success quote already paid returns its payment",
+				testType: "P",
+				args: args{
+					ctx:     ctx,
+					loanID:  loanID,
+					quoteID: quoteID,
+				},
+				mock: func() {
+					paid := quote(1_017_500, today.AddDate(0, 0, 1))
+					paid.PaymentID = &paymentID
+
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(&billing.Loan{
+						ID:     loanID,
+						Status: billing.LoanStatusPaidOff,
+					}, nil)
+					mockPayoffStore.EXPECT().GetQuoteByID(ctx, quoteID).Return(paid, nil)
+					mockPaymentStore.EXPECT().ListPaymentsByLoanID(ctx, loanID).Return([]billing.Payment{
+						{ID: paymentID, LoanID: loanID},
+					}, nil)
+				},
+			},
+			{
+				testID:   3,
+				testDesc: "failed quote expired",
+				testType: "N",
+				args: args{
+					ctx:     ctx,
+					loanID:  loanID,
+					quoteID: quoteID,
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(loan(), nil)
+					mockPayoffStore.EXPECT().GetQuoteByID(ctx, quoteID).Return(quote(1_017_500, today), nil)
+				},
+				expectedErr: billing.ErrPayoffQuoteExpired,
+			},
+			{
+				testID:   4,
+				testDesc: "failed loan paid on since the quote",
+				testType: "N",
+				args: args{
+					ctx:     ctx,
+					loanID:  loanID,
+					quoteID: quoteID,
+				},
+				mock: func() {
+					paid := schedules()
+					paid[0].PaidAmount = billing.NewAmount(100_000)
+					paid[0].Status = billing.LoanScheduleStatusPartiallyPaid
+
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(loan(), nil)
+					mockPayoffStore.EXPECT().GetQuoteByID(ctx, quoteID).Return(quote(1_017_500, today.AddDate(0, 0, 1)), nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loanID).Return(paid, nil)
+					mockLoanStore.EXPECT().ListSchedules(ctx, loanID, gomock.Any()).Return([]billing.LoanSchedule{}, nil)
+				},
+				expectedErr: billing.ErrPayoffQuoteStale,
+			},
+			{
+				testID:   5,
+				testDesc: "failed quote of another loan",
+				testType: "N",
+				args: args{
+					ctx:     ctx,
+					loanID:  loanID,
+					quoteID: quoteID,
+				},
+				mock: func() {
+					other := quote(1_017_500, today.AddDate(0, 0, 1))
+					other.LoanID = "other-loan-id"
+
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(loan(), nil)
+					mockPayoffStore.EXPECT().GetQuoteByID(ctx, quoteID).Return(other, nil)
+				},
+				expectedErr: billing.ErrPayoffQuoteNotFound,
+			},
+			{
+				testID:   6,
+				testDesc: "failed quote for a later day",
+				testType: "N",
+				args: args{
+					ctx:     ctx,
+					loanID:  loanID,
+					quoteID: quoteID,
+				},
+				mock: func() {
+					later := quote(1_017_500, today.AddDate(0, 0, 3))
+					later.AsOf = today.AddDate(0, 0, 2)
+
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(loan(), nil)
+					mockPayoffStore.EXPECT().GetQuoteByID(ctx, quoteID).Return(later, nil)
+				},
+				expectedErr: billing.ErrPayoffQuoteNotDue,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Logf("%d - [%s] : %s", tc.testID, tc.testType, tc.testDesc)
+			tc.mock()
+
+			_, err := payoffService.Payoff(
+				tc.args.ctx,
+				tc.args.loanID,
+				tc.args.quoteID,
+				"bank_transfer",
+				"",
+			)
+
+			if tc.testType == "P" {
+				So(err, ShouldBeNil)
+			} else {
+				So(err, ShouldNotBeNil)
+				if tc.expectedErr != nil {
+					So(err, ShouldEqual, tc.expectedErr)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,343 @@
+package billing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+type LoanProductService interface {
+	CreateProduct(ctx context.Context, name string, terms LoanProductTerms) (*LoanProduct, error)
+	GetProduct(ctx context.Context, productID string) (*LoanProduct, error)
+	ListProducts(ctx context.Context, status LoanProductStatus) ([]LoanProduct, error)
+	UpdateProductStatus(ctx context.Context, productID string, status LoanProductStatus) (*LoanProduct, error)
+}
+
+type LoanProductStore interface {
+	CreateProduct(ctx context.Context, product *LoanProduct) error
+	GetProductByID(ctx context.Context, productID string) (*LoanProduct, error)
+	// ListProducts returns the products in the given status, every product when
+	// it is empty, newest first.
+	ListProducts(ctx context.Context, status LoanProductStatus) ([]LoanProduct, error)
+	// UpdateProductStatus moves the product from one status to another, failing
+	// when the product is no longer in the expected status.
+	UpdateProductStatus(ctx context.Context, product *LoanProduct, from LoanProductStatus) error
+}
+
+func NewLoanProductService(
+	logger Logger,
+	txManager TxManager,
+	productStore LoanProductStore,
+) LoanProductService {
+	return &loanProductService{
+		logger:       logger,
+		txManager:    txManager,
+		productStore: productStore,
+	}
+}
+
+type loanProductService struct {
+	logger       Logger
+	txManager    TxManager
+	productStore LoanProductStore
+}
+
+// LoanProduct is a loan offering borrowers can apply for. Its terms never
+// change once created, a product is retired and replaced by a new one instead,
+// so loans made from it keep matching it.
+type LoanProduct struct {
+	ID   string
+	Name string
+	LoanProductTerms
+	Status    LoanProductStatus
+	CreatedAt time.Time
+}
+
+// LoanProductTerms bounds the loans made from a product.
+type LoanProductTerms struct {
+	// inclusive principal range, its currency is the currency of the product
+	MinPrincipal Amount
+	MaxPrincipal Amount
+	// inclusive interest rate range, a fixed rate when both are equal
+	MinInterestRate float64
+	MaxInterestRate float64
+	InterestModel   InterestModel
+	Frequencies     []LoanFrequency
+	// number of payments a loan may be repaid in
+	Terms []int
+	// charged on every loan, a flat amount plus a share of the principal
+	FeeAmount Amount
+	FeeRate   float64
+	// days after a due date before late fees accrue on the installment
+	GraceDays int
+}
+
+type (
+	LoanProductStatus string
+)
+
+var (
+	// open to new loans
+	LoanProductStatusActive LoanProductStatus = "active"
+	// closed to new loans, loans already made from it are unaffected
+	LoanProductStatusRetired LoanProductStatus = "retired"
+
+	LoanProductStatuses = []LoanProductStatus{
+		LoanProductStatusActive,
+		LoanProductStatusRetired,
+	}
+)
+
+func (s LoanProductStatus) IsValid() bool {
+	for _, status := range LoanProductStatuses {
+		if status == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *LoanProductStatus) UnmarshalText(text []byte) error {
+	for _, status := range LoanProductStatuses {
+		if strings.EqualFold(string(status), string(text)) {
+			*s = status
+			return nil
+		}
+	}
+	return NewError(
+		ErrValidationError.Error(),
+		fmt.Sprintf("LoanProductStatus should be one of %v", LoanProductStatuses),
+		http.StatusBadRequest,
+	)
+}
+
+// Currency is the currency loans of the product are made in.
+func (t LoanProductTerms) Currency() string {
+	return t.MinPrincipal.Currency
+}
+
+// Fee returns the fee charged on a loan of the given principal.
+func (t LoanProductTerms) Fee(principalAmount Amount) (Amount, error) {
+	variable, err := principalAmount.MulRate(t.FeeRate)
+	if err != nil {
+		return Amount{}, err
+	}
+	return t.FeeAmount.Add(variable), nil
+}
+
+// validate checks the terms can be offered at all.
+func (t LoanProductTerms) validate() error {
+	invalid := func(message string) error {
+		return NewError(ErrValidationError.Error(), message, http.StatusBadRequest)
+	}
+
+	if _, err := t.MinPrincipal.EqualTo(t.MaxPrincipal); err != nil {
+		return err
+	}
+	if _, err := t.MinPrincipal.EqualTo(t.FeeAmount); err != nil {
+		return err
+	}
+	if t.MinPrincipal.Cmp(t.MinPrincipal.ZeroLike()) <= 0 || t.MinPrincipal.Cmp(t.MaxPrincipal) > 0 {
+		return invalid("principal range must be positive with min not above max")
+	}
+	if t.MinInterestRate <= 0 || t.MinInterestRate > t.MaxInterestRate {
+		return invalid("interest rate range must be positive with min not above max")
+	}
+	if !t.InterestModel.IsValid() {
+		return invalid(fmt.Sprintf("InterestModel should be one of %v", InterestModels))
+	}
+	if len(t.Frequencies) == 0 {
+		return invalid("at least one payment frequency is required")
+	}
+	for _, frequency := range t.Frequencies {
+		if !frequency.IsValid() {
+			return invalid(fmt.Sprintf("LoanFrequency should be one of %v", LoanFrequencies))
+		}
+	}
+	if len(t.Terms) == 0 {
+		return invalid("at least one term is required")
+	}
+	for _, term := range t.Terms {
+		if term <= 0 {
+			return invalid("terms must be greater than 0")
+		}
+	}
+	if t.FeeAmount.Cmp(t.FeeAmount.ZeroLike()) < 0 || t.FeeRate < 0 {
+		return invalid("fees must not be negative")
+	}
+	if t.GraceDays < 0 {
+		return invalid("grace days must not be negative")
+	}
+	return nil
+}
+
+// LoanApplication is what a borrower asks for, zero values are left to the
+// product.
+type LoanApplication struct {
+	PrincipalAmount  Amount
+	InterestRate     float64
+	PaymentFrequency LoanFrequency
+	TotalPayments    int
+}
+
+// resolve fills in what the application left out and checks the rest fits the
+// terms. A field left out takes the product's value when the product allows a
+// single one.
+func (t LoanProductTerms) resolve(application LoanApplication) (LoanApplication, error) {
+	outside := func(format string, args ...any) error {
+		return NewError(
+			ErrLoanOutsideProductTerms.Error(),
+			fmt.Sprintf(format, args...),
+			http.StatusUnprocessableEntity,
+		)
+	}
+
+	principal := application.PrincipalAmount
+	if principal.Currency != t.Currency() {
+		return application, outside("principal must be in %s", t.Currency())
+	}
+	if principal.Cmp(t.MinPrincipal) < 0 || principal.Cmp(t.MaxPrincipal) > 0 {
+		return application, outside("principal must be between %s and %s", t.MinPrincipal, t.MaxPrincipal)
+	}
+
+	switch {
+	case application.InterestRate == 0 && t.MinInterestRate == t.MaxInterestRate:
+		application.InterestRate = t.MinInterestRate
+	case application.InterestRate == 0:
+		return application, outside("interest rate is required, between %v and %v", t.MinInterestRate, t.MaxInterestRate)
+	case application.InterestRate < t.MinInterestRate || application.InterestRate > t.MaxInterestRate:
+		return application, outside("interest rate must be between %v and %v", t.MinInterestRate, t.MaxInterestRate)
+	}
+
+	switch {
+	case application.PaymentFrequency == "" && len(t.Frequencies) == 1:
+		application.PaymentFrequency = t.Frequencies[0]
+	case !slices.Contains(t.Frequencies, application.PaymentFrequency):
+		return application, outside("payment frequency must be one of %v", t.Frequencies)
+	}
+
+	switch {
+	case application.TotalPayments == 0 && len(t.Terms) == 1:
+		application.TotalPayments = t.Terms[0]
+	case !slices.Contains(t.Terms, application.TotalPayments):
+		return application, outside("total payments must be one of %v", t.Terms)
+	}
+
+	return application, nil
+}
+
+func (s *loanProductService) CreateProduct(
+	ctx context.Context,
+	name string,
+	terms LoanProductTerms,
+) (*LoanProduct, error) {
+	if err := terms.validate(); err != nil {
+		return nil, err
+	}
+
+	product := &LoanProduct{
+		ID:               UUID(),
+		Name:             name,
+		LoanProductTerms: terms,
+		Status:           LoanProductStatusActive,
+		CreatedAt:        CurrentLocalTime(),
+	}
+
+	if err := s.productStore.CreateProduct(ctx, product); err != nil {
+		s.logger.WarnContext(ctx, "failed to create loan product", "error", err)
+		return nil, err
+	}
+
+	return product, nil
+}
+
+func (s *loanProductService) GetProduct(
+	ctx context.Context,
+	productID string,
+) (*LoanProduct, error) {
+	product, err := s.productStore.GetProductByID(ctx, productID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to get loan product", "error", err)
+		return nil, err
+	}
+
+	return product, nil
+}
+
+func (s *loanProductService) ListProducts(
+	ctx context.Context,
+	status LoanProductStatus,
+) ([]LoanProduct, error) {
+	if status != "" && !status.IsValid() {
+		return nil, NewError(
+			ErrValidationError.Error(),
+			fmt.Sprintf("LoanProductStatus should be one of %v", LoanProductStatuses),
+			http.StatusBadRequest,
+		)
+	}
+
+	products, err := s.productStore.ListProducts(ctx, status)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to list loan products", "error", err)
+		return nil, err
+	}
+
+	return products, nil
+}
+
+func (s *loanProductService) UpdateProductStatus(
+	ctx context.Context,
+	productID string,
+	status LoanProductStatus,
+) (*LoanProduct, error) {
+	if !status.IsValid() {
+		return nil, NewError(
+			ErrValidationError.Error(),
+			fmt.Sprintf("LoanProductStatus should be one of %v", LoanProductStatuses),
+			http.StatusBadRequest,
+		)
+	}
+
+	// the current status is read from the leader, not a lagging follower
+	var product *LoanProduct
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		product, err = s.updateProductStatus(ctx, productID, status)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return product, nil
+}
+
+func (s *loanProductService) updateProductStatus(
+	ctx context.Context,
+	productID string,
+	status LoanProductStatus,
+) (*LoanProduct, error) {
+	product, err := s.productStore.GetProductByID(ctx, productID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to get loan product", "error", err)
+		return nil, err
+	}
+
+	if product.Status == status {
+		s.logger.WarnContext(ctx, "loan product already in status", "status", status)
+		return nil, ErrInvalidLoanProductStatusTransition
+	}
+
+	from := product.Status
+	product.Status = status
+
+	if err := s.productStore.UpdateProductStatus(ctx, product, from); err != nil {
+		s.logger.WarnContext(ctx, "failed to update loan product status", "error", err)
+		return nil, err
+	}
+
+	return product, nil
+}
@@ -0,0 +1,281 @@
+package billing_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mock_billing "github.com/theyudiriski/billing-service/internal/service/mock"
+
+	billing "github.com/theyudiriski/billing-service/internal/service"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var (
+	productService billing.LoanProductService
+)
+
+func provideProductTest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	mockTxManager = newMockTxManager(ctrl)
+	mockProductStore = mock_billing.NewMockLoanProductStore(ctrl)
+
+	productService = billing.NewLoanProductService(
+		billing.NewLogger(),
+		mockTxManager,
+		mockProductStore,
+	)
+}
+
+func TestCreateProduct(t *testing.T) {
+	provideProductTest(t)
+
+	Convey("CreateProduct", t, FailureHalts, func() {
+		type (
+			args struct {
+				ctx   context.Context
+				name  string
+				terms billing.LoanProductTerms
+			}
+		)
+
+		var (
+			ctx = context.Background()
+
+			terms = func(edit func(terms *billing.LoanProductTerms)) billing.LoanProductTerms {
+				terms := billing.LoanProductTerms{
+					MinPrincipal:    billing.NewAmount(1_000_000),
+					MaxPrincipal:    billing.NewAmount(10_000_000),
+					MinInterestRate: 0.1,
+					MaxInterestRate: 0.1,
+					InterestModel:   billing.InterestModelFlat,
+					Frequencies:     []billing.LoanFrequency{billing.LoanFrequencyWeekly},
+					Terms:           []int{50},
+					FeeAmount:       billing.NewAmount(10_000),
+					FeeRate:         0.01,
+					GraceDays:       3,
+				}
+				edit(&terms)
+				return terms
+			}
+		)
+
+		testCases := []struct {
+			testID      int
+			testDesc    string
+			testType    string
+			args        args
+			mock        func()
+			expectedErr error
+		}{
+			{
+				testID:   1,
+				testDesc: "success create active product",
+				testType: "P",
+				args: args{
+					ctx:   ctx,
+					name:  "Weekly 50",
+					terms: terms(func(*billing.LoanProductTerms) {}),
+				},
+				mock: func() {
+					mockProductStore.EXPECT().CreateProduct(ctx, gomock.Any()).
+						Do(func(ctx context.Context, product *billing.LoanProduct) {
+							So(product.ID, ShouldNotBeEmpty)
+							So(product.Name, ShouldEqual, "Weekly 50")
+							So(product.Status, ShouldEqual, billing.LoanProductStatusActive)
+							fee, err := product.Fee(billing.NewAmount(5_000_000))
+							So(err, ShouldBeNil)
+							So(fee, ShouldEqual, billing.NewAmount(60_000))
+						}).Return(nil)
+				},
+			},
+			{
+				testID:   2,
+				testDesc: "failed create product",
+				testType: "N",
+				args: args{
+					ctx:   ctx,
+					name:  "Weekly 50",
+					terms: terms(func(*billing.LoanProductTerms) {}),
+				},
+				mock: func() {
+					mockProductStore.EXPECT().CreateProduct(ctx, gomock.Any()).Return(errMock)
+				},
+				expectedErr: errMock,
+			},
+			{
+				testID:   3,
+				testDesc: "failed min principal above max principal",
+				testType: "N",
+				args: args{
+					ctx:  ctx,
+					name: "Weekly 50",
+					terms: terms(func(terms *billing.LoanProductTerms) {
+						terms.MinPrincipal = billing.NewAmount(20_000_000)
+					}),
+				},
+				mock: func() {},
+			},
+			{
+				testID:   4,
+				testDesc: "failed unknown interest model",
+				testType: "N",
+				args: args{
+					ctx:  ctx,
+					name: "Weekly 50",
+					terms: terms(func(terms *billing.LoanProductTerms) {
+						terms.InterestModel = billing.InterestModel("compound")
+					}),
+				},
+				mock: func() {},
+			},
+			{
+				testID:   5,
+				testDesc: "failed no terms",
+				testType: "N",
+				args: args{
+					ctx:  ctx,
+					name: "Weekly 50",
+					terms: terms(func(terms *billing.LoanProductTerms) {
+						terms.Terms = nil
+					}),
+				},
+				mock: func() {},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Logf("%d - [%s] : %s", tc.testID, tc.testType, tc.testDesc)
+			tc.mock()
+
+			_, err := productService.CreateProduct(
+				tc.args.ctx,
+				tc.args.name,
+				tc.args.terms,
+			)
+
+			if tc.testType == "P" {
+				So(err, ShouldBeNil)
+			} else {
+				So(err, ShouldNotBeNil)
+				if tc.expectedErr != nil {
+					So(err, ShouldEqual, tc.expectedErr)
+				}
+			}
+		}
+	})
+}
+
+func TestUpdateProductStatus(t *testing.T) {
+	provideProductTest(t)
+
+	Convey("UpdateProductStatus", t, FailureHalts, func() {
+		type (
+			args struct {
+				ctx       context.Context
+				productID string
+				status    billing.LoanProductStatus
+			}
+		)
+
+		var (
+			ctx       = context.Background()
+			productID = "product-id"
+
+			productWithStatus = func(status billing.LoanProductStatus) *billing.LoanProduct {
+				return &billing.LoanProduct{
+					ID:     productID,
+					Status: status,
+				}
+			}
+		)
+
+		testCases := []struct {
+			testID      int
+			testDesc    string
+			testType    string
+			args        args
+			mock        func()
+			expectedErr error
+		}{
+			{
+				testID:   1,
+				testDesc: "success retire active product",
+				testType: "P",
+				args: args{
+					ctx:       ctx,
+					productID: productID,
+					status:    billing.LoanProductStatusRetired,
+				},
+				mock: func() {
+					mockProductStore.EXPECT().GetProductByID(ctx, productID).Return(productWithStatus(billing.LoanProductStatusActive), nil)
+					mockProductStore.EXPECT().UpdateProductStatus(ctx, gomock.Any(), billing.LoanProductStatusActive).
+						Do(func(ctx context.Context, product *billing.LoanProduct, from billing.LoanProductStatus) {
+							So(product.Status, ShouldEqual, billing.LoanProductStatusRetired)
+						}).Return(nil)
+				},
+			},
+			{
+				testID:   2,
+				testDesc: "failed product already retired",
+				testType: "N",
+				args: args{
+					ctx:       ctx,
+					productID: productID,
+					status:    billing.LoanProductStatusRetired,
+				},
+				mock: func() {
+					mockProductStore.EXPECT().GetProductByID(ctx, productID).Return(productWithStatus(billing.LoanProductStatusRetired), nil)
+				},
+				expectedErr: billing.ErrInvalidLoanProductStatusTransition,
+			},
+			{
+				testID:   3,
+				testDesc: "failed unknown product",
+				testType: "N",
+				args: args{
+					ctx:       ctx,
+					productID: productID,
+					status:    billing.LoanProductStatusRetired,
+				},
+				mock: func() {
+					mockProductStore.EXPECT().GetProductByID(ctx, productID).Return(nil, billing.ErrLoanProductNotFound)
+				},
+				expectedErr: billing.ErrLoanProductNotFound,
+			},
+			{
+				testID:   4,
+				testDesc: "failed unknown status",
+				testType: "N",
+				args: args{
+					ctx:       ctx,
+					productID: productID,
+					status:    billing.LoanProductStatus("archived"),
+				},
+				mock: func() {},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Logf("%d - [%s] : %s", tc.testID, tc.testType, tc.testDesc)
+			tc.mock()
+
+			_, err := productService.UpdateProductStatus(
+				tc.args.ctx,
+				tc.args.productID,
+				tc.args.status,
+			)
+
+			if tc.testType == "P" {
+				So(err, ShouldBeNil)
+			} else {
+				So(err, ShouldNotBeNil)
+				if tc.expectedErr != nil {
+					So(err, ShouldEqual, tc.expectedErr)
+				}
+			}
+		}
+	})
+}
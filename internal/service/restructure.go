@@ -0,0 +1,256 @@
+package billing
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// LoanRestructureTerms are what collections agreed with a borrower who fell
+// behind. At least one of the term extension, payment holiday or arrears
+// capitalisation is required.
+type LoanRestructureTerms struct {
+	// installments added on top of the unsettled ones
+	ExtendPayments int
+	// periods without any installment before the new schedule starts
+	HolidayPayments int
+	// turn the overdue interest, fees and late fees into principal spread over
+	// the new schedule, instead of keeping the arrears due on its first
+	// installment
+	CapitaliseArrears bool
+
+	Reason      string
+	RequestedBy string
+	ApprovedBy  string
+}
+
+// LoanRestructure records a restructure of a loan and the schedule version it
+// put in place. The superseded schedules are kept for audit.
+type LoanRestructure struct {
+	ID      string
+	LoanID  string
+	Version int
+	LoanRestructureTerms
+
+	// left to pay on the superseded schedules, and the overdue part of it
+	Outstanding Amount
+	Arrears     Amount
+	// interest, fees and late fees of the arrears added to the principal
+	Capitalised Amount
+	CreatedAt   time.Time
+
+	// installments of the new schedule version
+	Schedules []LoanSchedule
+}
+
+// RestructureLoan supersedes the unsettled schedules of an active or delinquent
+// loan with a new schedule version. What is left to pay is spread evenly over
+// the unsettled installments plus the extension, starting one period after the
+// restructure and any payment holiday; no interest is added for the longer
+// term. A delinquent loan becomes active again. Adjustments pending approval
+// must be decided on first.
+func (s *loanService) RestructureLoan(
+	ctx context.Context,
+	loanID string,
+	terms LoanRestructureTerms,
+) (*LoanRestructure, error) {
+	if err := terms.validate(); err != nil {
+		return nil, err
+	}
+
+	var restructure *LoanRestructure
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		restructure, err = s.restructureLoan(ctx, loanID, terms)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return restructure, nil
+}
+
+func (t LoanRestructureTerms) validate() error {
+	switch {
+	case t.ExtendPayments < 0 || t.HolidayPayments < 0:
+		return NewError(
+			ErrValidationError.Error(),
+			"extended and holiday payments must not be negative",
+			http.StatusBadRequest,
+		)
+	case t.ExtendPayments == 0 && t.HolidayPayments == 0 && !t.CapitaliseArrears:
+		return NewError(
+			ErrValidationError.Error(),
+			"restructure must extend the term, add a payment holiday or capitalise arrears",
+			http.StatusBadRequest,
+		)
+	case t.Reason == "":
+		return NewError(
+			ErrValidationError.Error(),
+			"restructure reason is required",
+			http.StatusBadRequest,
+		)
+	case t.RequestedBy == "" || t.ApprovedBy == "":
+		return NewError(
+			ErrValidationError.Error(),
+			"restructure requester and approver are required",
+			http.StatusBadRequest,
+		)
+	case t.RequestedBy == t.ApprovedBy:
+		return NewError(
+			ErrValidationError.Error(),
+			"restructure must be approved by another operator than the one who requested it",
+			http.StatusBadRequest,
+		)
+	}
+
+	return nil
+}
+
+func (s *loanService) restructureLoan(
+	ctx context.Context,
+	loanID string,
+	terms LoanRestructureTerms,
+) (*LoanRestructure, error) {
+	loan, err := s.loanStore.GetLoanByIDForUpdate(ctx, loanID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to get loan", "error", err)
+		return nil, err
+	}
+
+	if !loan.Status.IsPayable() {
+		s.logger.WarnContext(ctx, "loan is not restructurable", "status", loan.Status)
+		return nil, ErrLoanNotRestructurable
+	}
+
+	unsettled, err := s.loanStore.GetUnsettledSchedules(ctx, loanID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to get unsettled schedules", "error", err)
+		return nil, err
+	}
+
+	if len(unsettled) == 0 {
+		s.logger.WarnContext(ctx, "loan has no installment left to restructure")
+		return nil, ErrLoanNotRestructurable
+	}
+
+	// a pending adjustment is on a schedule about to be superseded, approving it
+	// afterwards would correct a schedule nobody pays anymore
+	pending, err := s.loanStore.HasPendingAdjustments(ctx, loanID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to check pending adjustments", "error", err)
+		return nil, err
+	}
+
+	if pending {
+		return nil, NewError(
+			ErrLoanNotRestructurable.Error(),
+			"loan has adjustments pending approval, approve or reject them first",
+			http.StatusUnprocessableEntity,
+		)
+	}
+
+	now := CurrentLocalTime()
+	restructure := newRestructure(loan, unsettled, terms, now)
+
+	from := loan.Status
+	loan.Status = LoanStatusActive
+	loan.TotalPayments += terms.ExtendPayments
+	loan.EndedAt = restructure.Schedules[len(restructure.Schedules)-1].DueDate
+
+	if err := s.loanStore.RestructureLoan(ctx, loan, restructure, from); err != nil {
+		s.logger.WarnContext(ctx, "failed to restructure loan", "error", err)
+		return nil, err
+	}
+
+	return restructure, nil
+}
+
+// newRestructure builds the restructure of the unsettled schedules of a loan
+// and its new schedule version.
+func newRestructure(
+	loan *Loan,
+	unsettled []LoanSchedule,
+	terms LoanRestructureTerms,
+	now time.Time,
+) *LoanRestructure {
+	zero := loan.PrincipalAmount.ZeroLike()
+	arrears := AmountBreakdown{Principal: zero, Interest: zero, Fee: zero, LateFee: zero}
+	upcoming := arrears
+
+	today := LocalDate(now)
+	version := 0
+	for _, schedule := range unsettled {
+		version = max(version, schedule.Version)
+
+		remaining := schedule.Remaining()
+		// late fees only accrue once overdue
+		arrears.LateFee = arrears.LateFee.Add(remaining.LateFee)
+		remaining.LateFee = zero
+
+		if LocalDate(schedule.DueDate).Before(today) {
+			arrears = arrears.add(remaining)
+		} else {
+			upcoming = upcoming.add(remaining)
+		}
+	}
+
+	restructure := &LoanRestructure{
+		ID:                   UUID(),
+		LoanID:               loan.ID,
+		Version:              version + 1,
+		LoanRestructureTerms: terms,
+		Outstanding:          arrears.Total().Add(upcoming.Total()),
+		Arrears:              arrears.Total(),
+		Capitalised:          zero,
+		CreatedAt:            now,
+	}
+
+	// the arrears are either spread along the rest as principal, or due in full
+	// on the first installment
+	spread := upcoming
+	firstDue := AmountBreakdown{Principal: zero, Interest: zero, Fee: zero, LateFee: zero}
+	if terms.CapitaliseArrears {
+		restructure.Capitalised = arrears.Total().Sub(arrears.Principal)
+		spread.Principal = spread.Principal.Add(arrears.Total())
+	} else {
+		firstDue = arrears
+	}
+
+	totalPayments := len(unsettled) + terms.ExtendPayments
+	principals := spread.Principal.Allocate(totalPayments)
+	interests := spread.Interest.Allocate(totalPayments)
+	fees := spread.Fee.Allocate(totalPayments)
+
+	restructure.Schedules = make([]LoanSchedule, 0, totalPayments)
+	for i := 0; i < totalPayments; i++ {
+		installment := AmountBreakdown{
+			Principal: principals[i],
+			Interest:  interests[i],
+			Fee:       fees[i],
+			LateFee:   zero,
+		}
+		if i == 0 {
+			installment = installment.add(firstDue)
+		}
+
+		restructure.Schedules = append(restructure.Schedules, LoanSchedule{
+			ID:           UUID(),
+			LoanID:       loan.ID,
+			Seq:          unsettled[0].Seq + i,
+			Version:      restructure.Version,
+			AnchoredAt:   today,
+			DueDate:      loan.PaymentFrequency.DueDate(today, terms.HolidayPayments+i+1),
+			AmountDue:    installment.Total(),
+			PrincipalDue: installment.Principal,
+			InterestDue:  installment.Interest,
+			FeeDue:       installment.Fee,
+			LateFeeDue:   installment.LateFee,
+			PaidAmount:   zero,
+			Status:       LoanScheduleStatusUnpaid,
+		})
+	}
+
+	return restructure
+}
@@ -0,0 +1,251 @@
+package billing_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	billing "github.com/theyudiriski/billing-service/internal/service"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRestructureLoan(t *testing.T) {
+	provideLoanTest(t)
+
+	Convey("RestructureLoan", t, FailureHalts, func() {
+		type (
+			args struct {
+				ctx    context.Context
+				loanID string
+				terms  billing.LoanRestructureTerms
+			}
+		)
+
+		var (
+			ctx       = context.Background()
+			loanID    = "loan-id"
+			today     = billing.LocalDate(billing.CurrentLocalTime())
+			startedAt = today.AddDate(0, 0, -17)
+
+			terms = func(extend, holiday int, capitalise bool) billing.LoanRestructureTerms {
+				return billing.LoanRestructureTerms{
+					ExtendPayments:    extend,
+					HolidayPayments:   holiday,
+					CapitaliseArrears: capitalise,
+					Reason:            "borrower lost their job",
+					RequestedBy:       "collector-1",
+					ApprovedBy:        "supervisor-1",
+				}
+			}
+
+			delinquentLoan = func() *billing.Loan {
+				return &billing.Loan{
+					ID:               loanID,
+					PrincipalAmount:  billing.NewAmount(1_000_000),
+					StartedAt:        startedAt,
+					PaymentFrequency: billing.LoanFrequencyWeekly,
+					TotalPayments:    4,
+					Status:           billing.LoanStatusDelinquent,
+				}
+			}
+
+			// the 1st installment is paid, the 2nd is 3 days overdue with a late
+			// fee and partly paid, the last two are still to come
+			unsettled = func() []billing.LoanSchedule {
+				schedules := make([]billing.LoanSchedule, 0, 3)
+				for seq := 2; seq <= 4; seq++ {
+					schedules = append(schedules, billing.LoanSchedule{
+						ID:           "schedule-id",
+						LoanID:       loanID,
+						Seq:          seq,
+						Version:      1,
+						DueDate:      billing.LoanFrequencyWeekly.DueDate(startedAt, seq),
+						AmountDue:    billing.NewAmount(257_000),
+						PrincipalDue: billing.NewAmount(250_000),
+						InterestDue:  billing.NewAmount(7_000),
+						FeeDue:       billing.NewAmount(0),
+						LateFeeDue:   billing.NewAmount(0),
+						PaidAmount:   billing.NewAmount(0),
+						Status:       billing.LoanScheduleStatusUnpaid,
+					})
+				}
+				schedules[0].LateFeeDue = billing.NewAmount(5_000)
+				schedules[0].AmountDue = billing.NewAmount(262_000)
+				schedules[0].PaidAmount = billing.NewAmount(10_000)
+				schedules[0].Status = billing.LoanScheduleStatusPartiallyPaid
+				return schedules
+			}
+		)
+
+		testCases := []struct {
+			testID      int
+			testDesc    string
+			testType    string
+			args        args
+			mock        func()
+			expectedErr error
+		}{
+			{
+				testID:   1,
+				testDesc: "success extended term keeps the arrears due on the first installment",
+				testType: "P",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+					terms:  terms(1, 0, false),
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(delinquentLoan(), nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loanID).Return(unsettled(), nil)
+					mockLoanStore.EXPECT().HasPendingAdjustments(ctx, loanID).Return(false, nil)
+					mockLoanStore.EXPECT().RestructureLoan(ctx, gomock.Any(), gomock.Any(), billing.LoanStatusDelinquent).
+						Do(func(ctx context.Context, loan *billing.Loan, restructure *billing.LoanRestructure, from billing.LoanStatus) {
+							So(loan.Status, ShouldEqual, billing.LoanStatusActive)
+							So(loan.TotalPayments, ShouldEqual, 5)
+
+							So(restructure.Version, ShouldEqual, 2)
+							So(restructure.Outstanding, ShouldEqual, billing.NewAmount(766_000))
+							So(restructure.Arrears, ShouldEqual, billing.NewAmount(252_000))
+							So(restructure.Capitalised, ShouldEqual, billing.NewAmount(0))
+							So(restructure.Schedules, ShouldHaveLength, 4)
+
+							first := restructure.Schedules[0]
+							So(first.Seq, ShouldEqual, 2)
+							So(first.Version, ShouldEqual, 2)
+							So(first.AnchoredAt.Equal(today), ShouldBeTrue)
+							So(first.DueDate.Equal(today.AddDate(0, 0, 7)), ShouldBeTrue)
+							So(first.PrincipalDue, ShouldEqual, billing.NewAmount(375_000))
+							So(first.InterestDue, ShouldEqual, billing.NewAmount(5_500))
+							So(first.AmountDue, ShouldEqual, billing.NewAmount(380_500))
+
+							last := restructure.Schedules[3]
+							So(last.Seq, ShouldEqual, 5)
+							So(last.AmountDue, ShouldEqual, billing.NewAmount(128_500))
+							So(loan.EndedAt.Equal(last.DueDate), ShouldBeTrue)
+						}).Return(nil)
+				},
+			},
+			{
+				testID:   2,
+				testDesc: "success capitalised arrears after a payment holiday",
+				testType: "P",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+					terms:  terms(1, 1, true),
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(delinquentLoan(), nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loanID).Return(unsettled(), nil)
+					mockLoanStore.EXPECT().HasPendingAdjustments(ctx, loanID).Return(false, nil)
+					mockLoanStore.EXPECT().RestructureLoan(ctx, gomock.Any(), gomock.Any(), billing.LoanStatusDelinquent).
+						Do(func(ctx context.Context, loan *billing.Loan, restructure *billing.LoanRestructure, from billing.LoanStatus) {
+							So(restructure.Capitalised, ShouldEqual, billing.NewAmount(2_000))
+							So(restructure.Schedules, ShouldHaveLength, 4)
+							So(restructure.Schedules[0].DueDate.Equal(today.AddDate(0, 0, 14)), ShouldBeTrue)
+							for _, schedule := range restructure.Schedules {
+								So(schedule.PrincipalDue, ShouldEqual, billing.NewAmount(188_000))
+								So(schedule.InterestDue, ShouldEqual, billing.NewAmount(3_500))
+								So(schedule.AmountDue, ShouldEqual, billing.NewAmount(191_500))
+							}
+						}).Return(nil)
+				},
+			},
+			{
+				testID:   3,
+				testDesc: "failed terms change nothing",
+				testType: "N",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+					terms:  terms(0, 0, false),
+				},
+				mock: func() {},
+			},
+			{
+				testID:   4,
+				testDesc: "failed approved by the requester",
+				testType: "N",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+					terms: billing.LoanRestructureTerms{
+						ExtendPayments: 2,
+						Reason:         "borrower lost their job",
+						RequestedBy:    "collector-1",
+						ApprovedBy:     "collector-1",
+					},
+				},
+				mock: func() {},
+			},
+			{
+				testID:   5,
+				testDesc: "failed loan already paid off",
+				testType: "N",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+					terms:  terms(2, 0, false),
+				},
+				mock: func() {
+					loan := delinquentLoan()
+					loan.Status = billing.LoanStatusPaidOff
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(loan, nil)
+				},
+				expectedErr: billing.ErrLoanNotRestructurable,
+			},
+			{
+				testID:   6,
+				testDesc: "failed no installment left",
+				testType: "N",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+					terms:  terms(2, 0, false),
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(delinquentLoan(), nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loanID).Return(nil, nil)
+				},
+				expectedErr: billing.ErrLoanNotRestructurable,
+			},
+			{
+				testID:   7,
+				testDesc: "failed adjustment pending approval",
+				testType: "N",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+					terms:  terms(2, 0, false),
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(delinquentLoan(), nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loanID).Return(unsettled(), nil)
+					mockLoanStore.EXPECT().HasPendingAdjustments(ctx, loanID).Return(true, nil)
+				},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Logf("%d - [%s] : %s", tc.testID, tc.testType, tc.testDesc)
+			tc.mock()
+
+			_, err := loanService.RestructureLoan(
+				tc.args.ctx,
+				tc.args.loanID,
+				tc.args.terms,
+			)
+
+			if tc.testType == "P" {
+				So(err, ShouldBeNil)
+			} else {
+				So(err, ShouldNotBeNil)
+				if tc.expectedErr != nil {
+					So(err, ShouldEqual, tc.expectedErr)
+				}
+			}
+		}
+	})
+}
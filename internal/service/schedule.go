@@ -0,0 +1,99 @@
+package billing
+
+import "time"
+
+// DueDate returns the due date of the n-th installment (starting from 1) of a
+// loan started at start. Monthly based frequencies are anchored to the start
+// date and clamped to the end of shorter months, so a loan started on Jan 31
+// is due on Feb 28 (or 29), Mar 31, Apr 30 and so on.
+func (l LoanFrequency) DueDate(start time.Time, n int) time.Time {
+	switch l {
+	case LoanFrequencyDaily:
+		return start.AddDate(0, 0, n)
+	case LoanFrequencyWeekly:
+		return start.AddDate(0, 0, 7*n)
+	case LoanFrequencyBiWeekly:
+		return start.AddDate(0, 0, 14*n)
+	case LoanFrequencySemiMonthly:
+		// twice a month, on the anniversary day and half a month after it
+		due := addMonthsClamped(start, n/2)
+		if n%2 == 1 {
+			due = due.AddDate(0, 0, 15)
+		}
+		return due
+	case LoanFrequencyMonthly:
+		return addMonthsClamped(start, n)
+	default:
+		return start
+	}
+}
+
+// period returns the number of the first installment of a loan started at
+// start that is due on or after due.
+func (l LoanFrequency) period(start, due time.Time) int {
+	if !l.IsValid() {
+		return 0
+	}
+
+	due = LocalDate(due)
+	n := 1
+	for LocalDate(l.DueDate(start, n)).Before(due) {
+		n++
+	}
+	return n
+}
+
+// PeriodsPerYear returns how many installments of this frequency fit in a year.
+func (l LoanFrequency) PeriodsPerYear() int {
+	switch l {
+	case LoanFrequencyDaily:
+		return 365
+	case LoanFrequencyWeekly:
+		return 52
+	case LoanFrequencyBiWeekly:
+		return 26
+	case LoanFrequencySemiMonthly:
+		return 24
+	default:
+		return 12
+	}
+}
+
+// addMonthsClamped adds months to t, clamping the day to the last day of the
+// resulting month instead of overflowing into the next one like time.AddDate.
+func addMonthsClamped(t time.Time, months int) time.Time {
+	year, month, day := t.Date()
+	firstOfMonth := time.Date(year, month+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+
+	lastDay := firstOfMonth.AddDate(0, 1, -1).Day()
+	if day > lastDay {
+		day = lastDay
+	}
+
+	return firstOfMonth.AddDate(0, 0, day-1)
+}
+
+// buildSchedules generates the installment plan of a loan, one schedule per
+// installment.
+func buildSchedules(loan *Loan, installments []installmentSplit) []LoanSchedule {
+	schedules := make([]LoanSchedule, 0, len(installments))
+	for i, installment := range installments {
+		amountDue := installment.Total()
+		schedules = append(schedules, LoanSchedule{
+			ID:           UUID(),
+			LoanID:       loan.ID,
+			Seq:          i + 1,
+			Version:      1,
+			AnchoredAt:   loan.StartedAt,
+			DueDate:      loan.PaymentFrequency.DueDate(loan.StartedAt, i+1),
+			AmountDue:    amountDue,
+			PrincipalDue: installment.Principal,
+			InterestDue:  installment.Interest,
+			FeeDue:       installment.Fee,
+			LateFeeDue:   amountDue.ZeroLike(),
+			PaidAmount:   amountDue.ZeroLike(),
+			Status:       LoanScheduleStatusUnpaid,
+		})
+	}
+	return schedules
+}
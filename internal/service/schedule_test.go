@@ -0,0 +1,45 @@
+package billing_test
+
+import (
+	"testing"
+	"time"
+
+	billing "github.com/theyudiriski/billing-service/internal/service"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLoanFrequencyDueDate(t *testing.T) {
+	Convey("LoanFrequency.DueDate", t, func() {
+		loc, _ := time.LoadLocation(billing.LocalTimezone)
+		date := func(year int, month time.Month, day int) time.Time {
+			return time.Date(year, month, day, 10, 0, 0, 0, loc)
+		}
+
+		testCases := []struct {
+			testID    int
+			testDesc  string
+			frequency billing.LoanFrequency
+			start     time.Time
+			n         int
+			expected  time.Time
+		}{
+			{1, "daily", billing.LoanFrequencyDaily, date(2024, 1, 30), 3, date(2024, 2, 2)},
+			{2, "weekly", billing.LoanFrequencyWeekly, date(2024, 1, 1), 2, date(2024, 1, 15)},
+			{3, "bi-weekly", billing.LoanFrequencyBiWeekly, date(2024, 1, 1), 2, date(2024, 1, 29)},
+			{4, "semi-monthly first half", billing.LoanFrequencySemiMonthly, date(2024, 1, 10), 1, date(2024, 1, 25)},
+			{5, "semi-monthly anniversary", billing.LoanFrequencySemiMonthly, date(2024, 1, 10), 2, date(2024, 2, 10)},
+			{6, "monthly clamps to end of february on leap year", billing.LoanFrequencyMonthly, date(2024, 1, 31), 1, date(2024, 2, 29)},
+			{7, "monthly clamps to end of february", billing.LoanFrequencyMonthly, date(2023, 1, 31), 1, date(2023, 2, 28)},
+			{8, "monthly keeps anchor day after a short month", billing.LoanFrequencyMonthly, date(2024, 1, 31), 2, date(2024, 3, 31)},
+			{9, "monthly clamps to end of april", billing.LoanFrequencyMonthly, date(2024, 1, 31), 3, date(2024, 4, 30)},
+			{10, "monthly across year end", billing.LoanFrequencyMonthly, date(2024, 11, 15), 3, date(2025, 2, 15)},
+		}
+
+		for _, tc := range testCases {
+			t.Logf("%d : %s", tc.testID, tc.testDesc)
+
+			So(tc.frequency.DueDate(tc.start, tc.n), ShouldEqual, tc.expected)
+		}
+	})
+}
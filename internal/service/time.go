@@ -15,3 +15,9 @@ func LocalTime(in time.Time) time.Time {
 	loc, _ := time.LoadLocation(LocalTimezone)
 	return in.In(loc)
 }
+
+// LocalDate returns the start of the local calendar day of the given time.
+func LocalDate(in time.Time) time.Time {
+	local := LocalTime(in)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+}
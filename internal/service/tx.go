@@ -0,0 +1,30 @@
+package billing
+
+import "context"
+
+// TxManager runs store calls as a single unit of work.
+type TxManager interface {
+	// WithinTx runs fn in a transaction committed when fn returns nil and rolled
+	// back otherwise. Store calls made with the ctx given to fn join the
+	// transaction, as does a nested WithinTx.
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+	// WithinReadOnlyTx runs fn in a read-only transaction on the follower, so
+	// the store reads made in fn see the same snapshot. It runs on the leader
+	// for a ctx marked by WithReadYourWrites, and inside a WithinTx it joins the
+	// outer transaction instead.
+	WithinReadOnlyTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type readYourWritesKey struct{}
+
+// WithReadYourWrites marks ctx so the store reads made with it go to the leader
+// instead of the follower, for callers that must see a write they just made.
+func WithReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readYourWritesKey{}, true)
+}
+
+// IsReadYourWrites reports whether ctx was marked by WithReadYourWrites.
+func IsReadYourWrites(ctx context.Context) bool {
+	readYourWrites, _ := ctx.Value(readYourWritesKey{}).(bool)
+	return readYourWrites
+}
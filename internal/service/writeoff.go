@@ -0,0 +1,87 @@
+package billing
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// LoanWriteOff records why and when a loan was written off, and how much was
+// left unpaid on it.
+type LoanWriteOff struct {
+	Reason       string
+	WrittenOffAt time.Time
+	// what the unsettled schedules still owed, they are kept as they were
+	Amount Amount
+}
+
+// WriteOffLoan closes an active or delinquent loan the borrower is not expected
+// to repay. The loan takes no more payments or late fees.
+func (s *loanService) WriteOffLoan(
+	ctx context.Context,
+	loanID string,
+	reason string,
+) (*Loan, error) {
+	if reason == "" {
+		return nil, NewError(
+			ErrValidationError.Error(),
+			"write-off reason is required",
+			http.StatusBadRequest,
+		)
+	}
+
+	var loan *Loan
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		loan, err = s.writeOffLoan(ctx, loanID, reason)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return loan, nil
+}
+
+func (s *loanService) writeOffLoan(
+	ctx context.Context,
+	loanID string,
+	reason string,
+) (*Loan, error) {
+	loan, err := s.loanStore.GetLoanByIDForUpdate(ctx, loanID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to get loan", "error", err)
+		return nil, err
+	}
+
+	if !loan.Status.CanTransitionTo(LoanStatusWrittenOff) {
+		s.logger.WarnContext(ctx, "invalid loan status transition", "from", loan.Status, "to", LoanStatusWrittenOff)
+		return nil, ErrInvalidLoanStatusTransition
+	}
+
+	unsettled, err := s.loanStore.GetUnsettledSchedules(ctx, loan.ID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to get unsettled schedules", "error", err)
+		return nil, err
+	}
+
+	amount := loan.PrincipalAmount.ZeroLike()
+	for _, schedule := range unsettled {
+		amount = amount.Add(schedule.Balance())
+	}
+
+	from := loan.Status
+	loan.Status = LoanStatusWrittenOff
+	loan.WriteOff = &LoanWriteOff{
+		Reason:       reason,
+		WrittenOffAt: CurrentLocalTime(),
+		Amount:       amount,
+	}
+
+	if err := s.loanStore.WriteOffLoan(ctx, loan, from); err != nil {
+		s.logger.WarnContext(ctx, "failed to write off loan", "error", err)
+		return nil, err
+	}
+
+	return loan, nil
+}
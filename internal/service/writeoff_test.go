@@ -0,0 +1,147 @@
+package billing_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	billing "github.com/theyudiriski/billing-service/internal/service"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWriteOffLoan(t *testing.T) {
+	provideLoanTest(t)
+
+	Convey("WriteOffLoan", t, FailureHalts, func() {
+		type (
+			args struct {
+				ctx    context.Context
+				loanID string
+				reason string
+			}
+		)
+
+		var (
+			ctx    = context.Background()
+			loanID = "loan-id"
+			reason = "borrower unreachable for a year"
+
+			loanWithStatus = func(status billing.LoanStatus) *billing.Loan {
+				return &billing.Loan{
+					ID:              loanID,
+					PrincipalAmount: billing.NewAmount(1_000_000),
+					Status:          status,
+				}
+			}
+
+			unsettled = []billing.LoanSchedule{
+				{
+					LoanID:     loanID,
+					Seq:        3,
+					AmountDue:  billing.NewAmount(262_000),
+					PaidAmount: billing.NewAmount(10_000),
+				},
+				{
+					LoanID:     loanID,
+					Seq:        4,
+					AmountDue:  billing.NewAmount(257_000),
+					PaidAmount: billing.NewAmount(0),
+				},
+			}
+		)
+
+		testCases := []struct {
+			testID      int
+			testDesc    string
+			testType    string
+			args        args
+			mock        func()
+			expectedErr error
+		}{
+			{
+				testID:   1,
+				testDesc: "success write off a delinquent loan with what is left unpaid",
+				testType: "P",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+					reason: reason,
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(loanWithStatus(billing.LoanStatusDelinquent), nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loanID).Return(unsettled, nil)
+					mockLoanStore.EXPECT().WriteOffLoan(ctx, gomock.Any(), billing.LoanStatusDelinquent).
+						Do(func(ctx context.Context, loan *billing.Loan, from billing.LoanStatus) {
+							So(loan.Status, ShouldEqual, billing.LoanStatusWrittenOff)
+							So(loan.WriteOff.Reason, ShouldEqual, reason)
+							So(loan.WriteOff.Amount, ShouldEqual, billing.NewAmount(509_000))
+							So(loan.WriteOff.WrittenOffAt.IsZero(), ShouldBeFalse)
+						}).Return(nil)
+				},
+			},
+			{
+				testID:   2,
+				testDesc: "failed without a reason",
+				testType: "N",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+				},
+				mock: func() {},
+			},
+			{
+				testID:   3,
+				testDesc: "failed loan pending disbursement",
+				testType: "N",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+					reason: reason,
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(loanWithStatus(billing.LoanStatusPendingDisbursement), nil)
+				},
+				expectedErr: billing.ErrInvalidLoanStatusTransition,
+			},
+			{
+				testID:   4,
+				testDesc: "failed write off loan",
+				testType: "N",
+				args: args{
+					ctx:    ctx,
+					loanID: loanID,
+					reason: reason,
+				},
+				mock: func() {
+					mockLoanStore.EXPECT().GetLoanByIDForUpdate(ctx, loanID).Return(loanWithStatus(billing.LoanStatusActive), nil)
+					mockLoanStore.EXPECT().GetUnsettledSchedules(ctx, loanID).Return(unsettled, nil)
+					mockLoanStore.EXPECT().WriteOffLoan(ctx, gomock.Any(), billing.LoanStatusActive).
+						Return(billing.ErrInvalidLoanStatusTransition)
+				},
+				expectedErr: billing.ErrInvalidLoanStatusTransition,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Logf("%d - [%s] : %s", tc.testID, tc.testType, tc.testDesc)
+			tc.mock()
+
+			_, err := loanService.WriteOffLoan(
+				tc.args.ctx,
+				tc.args.loanID,
+				tc.args.reason,
+			)
+
+			if tc.testType == "P" {
+				So(err, ShouldBeNil)
+			} else {
+				So(err, ShouldNotBeNil)
+				if tc.expectedErr != nil {
+					So(err, ShouldEqual, tc.expectedErr)
+				}
+			}
+		}
+	})
+}